@@ -0,0 +1,23 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	er "../libgo/error"
+	lang "../libgo/language"
+)
+
+const errorEnglishDomain = "Sabz.City"
+
+// Declare Errors Details
+var (
+	// FinancialTransaction
+	ErrFinancialTransactionLocked = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Locked",
+		"Other transaction is writing to the user financial transactions chain now! Please try again").Save()
+
+	ErrFinancialTransactionNotLocked = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Not Locked",
+		"Can't save financial transaction without lock the user financial transactions chain first").Save()
+
+	ErrFinancialTransactionChainNotIndexed = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Chain Not Indexed",
+		"Old financial transactions of the user are indexing now and the balance is not known yet! Please try again later").Save()
+)
@@ -0,0 +1,394 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialEscrowStructureID uint64 = 15873960321447880467
+)
+
+var financialEscrowStructure = ganjine.DataStructure{
+	ID:                15873960321447880467,
+	IssueDate:         1792294530,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialEscrow{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Escrow",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store money that held from buyer balance against a pre-sale product until DC confirm delivery.
+Each status change write new version and first version after held one decide escrow outcome.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialEscrow ---Read locale description in financialEscrowStructure---
+type FinancialEscrow struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte     // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte     // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte     `index-hash:"RecordID"`
+	ProductID        [32]byte     `index-hash:"ID"`
+	BuyerID          [32]byte     `index-hash:"ID"`
+	SellerID         [32]byte     // Product owner org that get money on release
+	DCID             [32]byte     // DistributionCenterID that must confirm delivery
+	Amount           price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
+	ExpireTime       etime.Time   `index-hash:"ID[daily]"` // Escrow refund to buyer if not released before this time
+	Status           FinancialEscrowStatus
+}
+
+// SaveNew method set some data and write entire FinancialEscrow record with all indexes!
+func (fe *FinancialEscrow) SaveNew() (err *er.Error) {
+	err = fe.Set()
+	if err != nil {
+		return
+	}
+
+	fe.IndexRecordIDForID()
+	fe.IndexIDForProductID()
+	fe.IndexIDForBuyerID()
+	fe.IndexIDForExpireTimeDaily()
+	return
+}
+
+// Set method set some data and write entire FinancialEscrow record!
+func (fe *FinancialEscrow) Set() (err *er.Error) {
+	fe.RecordStructureID = financialEscrowStructureID
+	fe.RecordSize = fe.syllabLen()
+	fe.WriteTime = etime.Now()
+	fe.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fe.syllabEncoder(),
+	}
+	fe.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fe.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fe *FinancialEscrow) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fe.RecordID,
+		RecordStructureID: financialEscrowStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fe.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fe.RecordStructureID != financialEscrowStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (fe *FinancialEscrow) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fe.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	fe.RecordID = indexRes.IndexValues[0]
+	err = fe.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialEscrowStructureID)
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindRecordIDsByID find all versions RecordIDs of given ID in write order.
+func (fe *FinancialEscrow) FindRecordIDsByID(offset, limit uint64) (RecordIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fe.hashIDForRecordID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	RecordIDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByProductID find IDs by given ProductID
+func (fe *FinancialEscrow) FindIDsByProductID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fe.hashProductIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByBuyerID find IDs by given BuyerID
+func (fe *FinancialEscrow) FindIDsByBuyerID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fe.hashBuyerIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByExpireTimeDaily find IDs by given ExpireTime(round to daily)
+func (fe *FinancialEscrow) FindIDsByExpireTimeDaily(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fe.hashExpireTimeForIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (fe *FinancialEscrow) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fe.hashIDForRecordID(),
+		IndexValue: fe.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fe *FinancialEscrow) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialEscrowStructureID)
+	copy(buf[8:], fe.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForProductID save ID chain for ProductID.
+// Don't call in update to an exiting record!
+func (fe *FinancialEscrow) IndexIDForProductID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fe.hashProductIDForID(),
+		IndexValue: fe.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fe *FinancialEscrow) hashProductIDForID() (hash [32]byte) {
+	const field = "ProductID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialEscrowStructureID)
+	copy(buf[8:], fe.ProductID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForBuyerID save ID chain for BuyerID.
+// Don't call in update to an exiting record!
+func (fe *FinancialEscrow) IndexIDForBuyerID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fe.hashBuyerIDForID(),
+		IndexValue: fe.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fe *FinancialEscrow) hashBuyerIDForID() (hash [32]byte) {
+	const field = "BuyerID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialEscrowStructureID)
+	copy(buf[8:], fe.BuyerID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForExpireTimeDaily save ID chain for ExpireTime daily.
+// Use by timeout job to find expired escrows!
+// Don't call in update to an exiting record!
+func (fe *FinancialEscrow) IndexIDForExpireTimeDaily() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fe.hashExpireTimeForIDDaily(),
+		IndexValue: fe.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fe *FinancialEscrow) hashExpireTimeForIDDaily() (hash [32]byte) {
+	const field = "ExpireTime"
+	var buf = make([]byte, 16+len(field)) // 8+8
+	syllab.SetUInt64(buf, 0, financialEscrowStructureID)
+	syllab.SetInt64(buf, 8, fe.ExpireTime.RoundToDay())
+	copy(buf[16:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fe *FinancialEscrow) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < fe.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fe.RecordID[:], buf[0:])
+	fe.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fe.RecordSize = syllab.GetUInt64(buf, 40)
+	fe.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fe.OwnerAppID[:], buf[56:])
+
+	copy(fe.AppInstanceID[:], buf[88:])
+	copy(fe.UserConnectionID[:], buf[120:])
+	copy(fe.ID[:], buf[152:])
+	copy(fe.ProductID[:], buf[184:])
+	copy(fe.BuyerID[:], buf[216:])
+	copy(fe.SellerID[:], buf[248:])
+	copy(fe.DCID[:], buf[280:])
+	fe.Amount = price.Amount(syllab.GetInt64(buf, 312))
+	fe.ExpireTime = etime.Time(syllab.GetInt64(buf, 320))
+	fe.Status = FinancialEscrowStatus(syllab.GetUInt8(buf, 328))
+	return
+}
+
+func (fe *FinancialEscrow) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fe.syllabLen())
+
+	// copy(buf[0:], fe.RecordID[:])
+	syllab.SetUInt64(buf, 32, fe.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fe.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fe.WriteTime))
+	copy(buf[56:], fe.OwnerAppID[:])
+
+	copy(buf[88:], fe.AppInstanceID[:])
+	copy(buf[120:], fe.UserConnectionID[:])
+	copy(buf[152:], fe.ID[:])
+	copy(buf[184:], fe.ProductID[:])
+	copy(buf[216:], fe.BuyerID[:])
+	copy(buf[248:], fe.SellerID[:])
+	copy(buf[280:], fe.DCID[:])
+	syllab.SetInt64(buf, 312, int64(fe.Amount))
+	syllab.SetInt64(buf, 320, int64(fe.ExpireTime))
+	syllab.SetUInt8(buf, 328, uint8(fe.Status))
+	return
+}
+
+func (fe *FinancialEscrow) syllabStackLen() (ln uint32) {
+	return 329
+}
+
+func (fe *FinancialEscrow) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (fe *FinancialEscrow) syllabLen() (ln uint64) {
+	return uint64(fe.syllabStackLen() + fe.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialEscrowStatus indicate FinancialEscrow record status
+type FinancialEscrowStatus uint8
+
+// FinancialEscrow status
+const (
+	FinancialEscrowUnset    FinancialEscrowStatus = iota
+	FinancialEscrowHeld                           // Amount held from buyer balance
+	FinancialEscrowReleased                       // DC confirm delivery and amount go to seller
+	FinancialEscrowRefunded                       // Cancel or timeout and amount go back to buyer
+)
@@ -0,0 +1,288 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialExchangeStructureID uint64 = 6916475503297413069
+)
+
+var financialExchangeStructure = ganjine.DataStructure{
+	ID:                6916475503297413069,
+	IssueDate:         1792295057,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialExchange{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Exchange",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store a currency exchange of a user with both legs of it and the rate that used.
+Both legs reference to exchange ID.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialExchange ---Read locale description in financialExchangeStructure---
+type FinancialExchange struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID     [32]byte     // Store to remember which app instance set||chanaged this record!
+	UserConnectionID  [32]byte     // Store to remember which user connection set||chanaged this record!
+	ID                [32]byte     `index-hash:"RecordID"`
+	UserID            [32]byte     `index-hash:"ID"`
+	FromCurrency      uint16       // ISO 4217 numeric code
+	FromAmount        price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
+	FromTransactionID [32]byte     // Withdraw leg
+	ToCurrency        uint16       // ISO 4217 numeric code
+	ToAmount          price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
+	ToTransactionID   [32]byte     // Deposit leg
+	Rate              uint64       // ToCurrency amount for FinancialExchangeRateScale of FromCurrency amount
+}
+
+// FinancialExchangeRateScale is fixed point scale of FinancialExchange.Rate
+const FinancialExchangeRateScale = 1000000000
+
+// SaveNew method set some data and write entire FinancialExchange record with all indexes!
+func (fx *FinancialExchange) SaveNew() (err *er.Error) {
+	err = fx.Set()
+	if err != nil {
+		return
+	}
+
+	fx.IndexRecordIDForID()
+	fx.IndexIDForUserID()
+	return
+}
+
+// Set method set some data and write entire FinancialExchange record!
+func (fx *FinancialExchange) Set() (err *er.Error) {
+	fx.RecordStructureID = financialExchangeStructureID
+	fx.RecordSize = fx.syllabLen()
+	fx.WriteTime = etime.Now()
+	fx.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fx.syllabEncoder(),
+	}
+	fx.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fx.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fx *FinancialExchange) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fx.RecordID,
+		RecordStructureID: financialExchangeStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fx.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fx.RecordStructureID != financialExchangeStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (fx *FinancialExchange) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fx.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	fx.RecordID = indexRes.IndexValues[0]
+	err = fx.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialExchangeStructureID)
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindIDsByUserID find IDs by given UserID
+func (fx *FinancialExchange) FindIDsByUserID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fx.hashUserIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (fx *FinancialExchange) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fx.hashIDForRecordID(),
+		IndexValue: fx.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fx *FinancialExchange) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialExchangeStructureID)
+	copy(buf[8:], fx.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForUserID save ID chain for UserID.
+// Don't call in update to an exiting record!
+func (fx *FinancialExchange) IndexIDForUserID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fx.hashUserIDForID(),
+		IndexValue: fx.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fx *FinancialExchange) hashUserIDForID() (hash [32]byte) {
+	const field = "UserID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialExchangeStructureID)
+	copy(buf[8:], fx.UserID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fx *FinancialExchange) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < fx.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fx.RecordID[:], buf[0:])
+	fx.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fx.RecordSize = syllab.GetUInt64(buf, 40)
+	fx.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fx.OwnerAppID[:], buf[56:])
+
+	copy(fx.AppInstanceID[:], buf[88:])
+	copy(fx.UserConnectionID[:], buf[120:])
+	copy(fx.ID[:], buf[152:])
+	copy(fx.UserID[:], buf[184:])
+	fx.FromCurrency = syllab.GetUInt16(buf, 216)
+	fx.FromAmount = price.Amount(syllab.GetInt64(buf, 218))
+	copy(fx.FromTransactionID[:], buf[226:])
+	fx.ToCurrency = syllab.GetUInt16(buf, 258)
+	fx.ToAmount = price.Amount(syllab.GetInt64(buf, 260))
+	copy(fx.ToTransactionID[:], buf[268:])
+	fx.Rate = syllab.GetUInt64(buf, 300)
+	return
+}
+
+func (fx *FinancialExchange) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fx.syllabLen())
+
+	// copy(buf[0:], fx.RecordID[:])
+	syllab.SetUInt64(buf, 32, fx.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fx.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fx.WriteTime))
+	copy(buf[56:], fx.OwnerAppID[:])
+
+	copy(buf[88:], fx.AppInstanceID[:])
+	copy(buf[120:], fx.UserConnectionID[:])
+	copy(buf[152:], fx.ID[:])
+	copy(buf[184:], fx.UserID[:])
+	syllab.SetUInt16(buf, 216, fx.FromCurrency)
+	syllab.SetInt64(buf, 218, int64(fx.FromAmount))
+	copy(buf[226:], fx.FromTransactionID[:])
+	syllab.SetUInt16(buf, 258, fx.ToCurrency)
+	syllab.SetInt64(buf, 260, int64(fx.ToAmount))
+	copy(buf[268:], fx.ToTransactionID[:])
+	syllab.SetUInt64(buf, 300, fx.Rate)
+	return
+}
+
+func (fx *FinancialExchange) syllabStackLen() (ln uint32) {
+	return 308
+}
+
+func (fx *FinancialExchange) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (fx *FinancialExchange) syllabLen() (ln uint64) {
+	return uint64(fx.syllabStackLen() + fx.syllabHeapLen())
+}
@@ -0,0 +1,335 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialFreezeStructureID uint64 = 9214705518306647371
+)
+
+var financialFreezeStructure = ganjine.DataStructure{
+	ID:                9214705518306647371,
+	IssueDate:         1792295516,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialFreeze{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Freeze",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store money that justice department freeze from user balance by a justice case.
+Frozen amount withdraw from user balance by a blocked transaction and deposit back on unfreeze.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialFreeze ---Read locale description in financialFreezeStructure---
+type FinancialFreeze struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte     // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte     // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte     `index-hash:"RecordID"`
+	UserID           [32]byte     `index-hash:"ID"`
+	CaseID           [32]byte     `index-hash:"ID"` // Justice case that order the freeze
+	Currency         uint16       // ISO 4217 numeric code. Zero is society default currency
+	Amount           price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
+	Whole            bool         // Whole account frozen and user can't spend even money that deposit after freeze
+	Status           FinancialFreezeStatus
+}
+
+// SaveNew method set some data and write entire FinancialFreeze record with all indexes!
+func (ff *FinancialFreeze) SaveNew() (err *er.Error) {
+	err = ff.Set()
+	if err != nil {
+		return
+	}
+
+	ff.IndexRecordIDForID()
+	ff.IndexIDForUserID()
+	ff.IndexIDForCaseID()
+	return
+}
+
+// Set method set some data and write entire FinancialFreeze record!
+func (ff *FinancialFreeze) Set() (err *er.Error) {
+	ff.RecordStructureID = financialFreezeStructureID
+	ff.RecordSize = ff.syllabLen()
+	ff.WriteTime = etime.Now()
+	ff.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: ff.syllabEncoder(),
+	}
+	ff.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], ff.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (ff *FinancialFreeze) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          ff.RecordID,
+		RecordStructureID: financialFreezeStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = ff.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if ff.RecordStructureID != financialFreezeStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (ff *FinancialFreeze) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ff.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	ff.RecordID = indexRes.IndexValues[0]
+	err = ff.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialFreezeStructureID)
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindIDsByUserID find IDs by given UserID
+func (ff *FinancialFreeze) FindIDsByUserID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ff.hashUserIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByCaseID find IDs by given CaseID
+func (ff *FinancialFreeze) FindIDsByCaseID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ff.hashCaseIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (ff *FinancialFreeze) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ff.hashIDForRecordID(),
+		IndexValue: ff.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ff *FinancialFreeze) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialFreezeStructureID)
+	copy(buf[8:], ff.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForUserID save ID chain for UserID.
+// Don't call in update to an exiting record!
+func (ff *FinancialFreeze) IndexIDForUserID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ff.hashUserIDForID(),
+		IndexValue: ff.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ff *FinancialFreeze) hashUserIDForID() (hash [32]byte) {
+	const field = "UserID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialFreezeStructureID)
+	copy(buf[8:], ff.UserID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForCaseID save ID chain for CaseID.
+// Don't call in update to an exiting record!
+func (ff *FinancialFreeze) IndexIDForCaseID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ff.hashCaseIDForID(),
+		IndexValue: ff.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ff *FinancialFreeze) hashCaseIDForID() (hash [32]byte) {
+	const field = "CaseID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialFreezeStructureID)
+	copy(buf[8:], ff.CaseID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (ff *FinancialFreeze) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < ff.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(ff.RecordID[:], buf[0:])
+	ff.RecordStructureID = syllab.GetUInt64(buf, 32)
+	ff.RecordSize = syllab.GetUInt64(buf, 40)
+	ff.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(ff.OwnerAppID[:], buf[56:])
+
+	copy(ff.AppInstanceID[:], buf[88:])
+	copy(ff.UserConnectionID[:], buf[120:])
+	copy(ff.ID[:], buf[152:])
+	copy(ff.UserID[:], buf[184:])
+	copy(ff.CaseID[:], buf[216:])
+	ff.Currency = syllab.GetUInt16(buf, 248)
+	ff.Amount = price.Amount(syllab.GetInt64(buf, 250))
+	ff.Whole = buf[258] == 1
+	ff.Status = FinancialFreezeStatus(syllab.GetUInt8(buf, 259))
+	return
+}
+
+func (ff *FinancialFreeze) syllabEncoder() (buf []byte) {
+	buf = make([]byte, ff.syllabLen())
+
+	// copy(buf[0:], ff.RecordID[:])
+	syllab.SetUInt64(buf, 32, ff.RecordStructureID)
+	syllab.SetUInt64(buf, 40, ff.RecordSize)
+	syllab.SetInt64(buf, 48, int64(ff.WriteTime))
+	copy(buf[56:], ff.OwnerAppID[:])
+
+	copy(buf[88:], ff.AppInstanceID[:])
+	copy(buf[120:], ff.UserConnectionID[:])
+	copy(buf[152:], ff.ID[:])
+	copy(buf[184:], ff.UserID[:])
+	copy(buf[216:], ff.CaseID[:])
+	syllab.SetUInt16(buf, 248, ff.Currency)
+	syllab.SetInt64(buf, 250, int64(ff.Amount))
+	if ff.Whole {
+		buf[258] = 1
+	}
+	syllab.SetUInt8(buf, 259, uint8(ff.Status))
+	return
+}
+
+func (ff *FinancialFreeze) syllabStackLen() (ln uint32) {
+	return 260
+}
+
+func (ff *FinancialFreeze) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (ff *FinancialFreeze) syllabLen() (ln uint64) {
+	return uint64(ff.syllabStackLen() + ff.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialFreezeStatus indicate FinancialFreeze record status
+type FinancialFreezeStatus uint8
+
+// FinancialFreeze status
+const (
+	FinancialFreezeUnset    FinancialFreezeStatus = iota
+	FinancialFreezeFrozen                         // Amount withdraw from user balance by a blocked transaction
+	FinancialFreezeUnfrozen                       // Amount deposit back to user balance
+)
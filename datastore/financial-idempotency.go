@@ -0,0 +1,251 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/syllab"
+)
+
+const (
+	financialIdempotencyStructureID uint64 = 7530375525681720030
+)
+
+var financialIdempotencyStructure = ganjine.DataStructure{
+	ID:                7530375525681720030,
+	IssueDate:         1792294147,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialIdempotency{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Idempotency",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store client supplied idempotency key of money-moving requests with result of first request,
+to answer retried requests with same result and not move money twice.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialIdempotency ---Read locale description in financialIdempotencyStructure---
+type FinancialIdempotency struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	ClaimID          [32]byte // Same for all versions of a claim on the key
+	UserID           [32]byte `index-hash:"RecordID[pair,Key]"`
+	Key              [32]byte // Client supplied idempotency key
+	ServiceID        uint32
+	RequestHash      [32]byte // Hash of request payload without the key
+	ResponseID       [32]byte // e.g. FinancialTransaction RecordID
+	Status           FinancialIdempotencyStatus
+	Response         []byte // Syllab encoded response of first request to replay it completely
+}
+
+// SaveNew method set some data and write entire FinancialIdempotency record with all indexes!
+func (fi *FinancialIdempotency) SaveNew() (err *er.Error) {
+	err = fi.Set()
+	if err != nil {
+		return
+	}
+	fi.IndexRecordIDForUserIDKey()
+	return
+}
+
+// Set method set some data and write entire FinancialIdempotency record!
+func (fi *FinancialIdempotency) Set() (err *er.Error) {
+	fi.RecordStructureID = financialIdempotencyStructureID
+	fi.RecordSize = fi.syllabLen()
+	fi.WriteTime = etime.Now()
+	fi.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fi.syllabEncoder(),
+	}
+	fi.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fi.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fi *FinancialIdempotency) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fi.RecordID,
+		RecordStructureID: financialIdempotencyStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fi.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fi.RecordStructureID != financialIdempotencyStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindRecordIDsByUserIDKey find RecordsIDs by given UserID + Key in write order.
+func (fi *FinancialIdempotency) FindRecordIDsByUserIDKey(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fi.hashUserIDKeyForRecordID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForUserIDKey save RecordID chain for UserID + Key
+// Call in each update to the exiting record!
+func (fi *FinancialIdempotency) IndexRecordIDForUserIDKey() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fi.hashUserIDKeyForRecordID(),
+		IndexValue: fi.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fi *FinancialIdempotency) hashUserIDKeyForRecordID() (hash [32]byte) {
+	const field = "UserIDKey"
+	var buf = make([]byte, 72+len(field)) // 8+32+32
+	syllab.SetUInt64(buf, 0, financialIdempotencyStructureID)
+	copy(buf[8:], fi.UserID[:])
+	copy(buf[40:], fi.Key[:])
+	copy(buf[72:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fi *FinancialIdempotency) syllabDecoder(buf []byte) (err *er.Error) {
+	if len(buf) < 317 {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fi.RecordID[:], buf[0:])
+	fi.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fi.RecordSize = syllab.GetUInt64(buf, 40)
+	fi.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fi.OwnerAppID[:], buf[56:])
+
+	copy(fi.AppInstanceID[:], buf[88:])
+	copy(fi.UserConnectionID[:], buf[120:])
+	copy(fi.ClaimID[:], buf[152:])
+	copy(fi.UserID[:], buf[184:])
+	copy(fi.Key[:], buf[216:])
+	fi.ServiceID = syllab.GetUInt32(buf, 248)
+	copy(fi.RequestHash[:], buf[252:])
+	copy(fi.ResponseID[:], buf[284:])
+	fi.Status = FinancialIdempotencyStatus(syllab.GetUInt8(buf, 316))
+	// Records written before response store has just ResponseID.
+	if len(buf) >= 325 {
+		fi.Response = syllab.UnsafeGetByteArray(buf, 317)
+	}
+	return
+}
+
+func (fi *FinancialIdempotency) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fi.syllabLen())
+	var hsi uint32 = fi.syllabStackLen() // Heap start index || Stack size!
+
+	// copy(buf[0:], fi.RecordID[:])
+	syllab.SetUInt64(buf, 32, fi.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fi.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fi.WriteTime))
+	copy(buf[56:], fi.OwnerAppID[:])
+
+	copy(buf[88:], fi.AppInstanceID[:])
+	copy(buf[120:], fi.UserConnectionID[:])
+	copy(buf[152:], fi.ClaimID[:])
+	copy(buf[184:], fi.UserID[:])
+	copy(buf[216:], fi.Key[:])
+	syllab.SetUInt32(buf, 248, fi.ServiceID)
+	copy(buf[252:], fi.RequestHash[:])
+	copy(buf[284:], fi.ResponseID[:])
+	syllab.SetUInt8(buf, 316, uint8(fi.Status))
+	hsi = syllab.SetByteArray(buf, fi.Response, 317, hsi)
+	return
+}
+
+func (fi *FinancialIdempotency) syllabStackLen() (ln uint32) {
+	return 325
+}
+
+func (fi *FinancialIdempotency) syllabHeapLen() (ln uint32) {
+	ln = uint32(len(fi.Response))
+	return
+}
+
+func (fi *FinancialIdempotency) syllabLen() (ln uint64) {
+	return uint64(fi.syllabStackLen() + fi.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialIdempotencyStatus indicate FinancialIdempotency record status
+type FinancialIdempotencyStatus uint8
+
+// FinancialIdempotency status
+const (
+	FinancialIdempotencyUnset      FinancialIdempotencyStatus = iota
+	FinancialIdempotencyInProgress                            // First request with the key is in progress
+	FinancialIdempotencyDone                                  // ResponseID and Response are result of first request
+	FinancialIdempotencyFailed                                // First request failed, so key can use again
+)
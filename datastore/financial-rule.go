@@ -0,0 +1,230 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialRuleStructureID uint64 = 11276350829031746218
+)
+
+var financialRuleStructure = ganjine.DataStructure{
+	ID:                11276350829031746218,
+	IssueDate:         1792294891,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialRule{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Rule",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store spending limits of a user that check before any withdraw from user balance.
+Zero value of each limit means no limit. Last version of the record is active rule.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialRule ---Read locale description in financialRuleStructure---
+type FinancialRule struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID      [32]byte     // Store to remember which app instance set||chanaged this record!
+	UserConnectionID   [32]byte     // Store to remember which user connection set||chanaged this record!
+	UserID             [32]byte     `index-hash:"RecordID"`
+	DailyLimit         price.Amount // Max sum of withdraws in a day
+	TransactionLimit   price.Amount // Max amount of each withdraw
+	DailyCountLimit    uint32       // Max number of withdraws in a day
+	DelegateCoolingOff uint32       // Second that a new delegate connection must wait before it can withdraw
+	OTPStepUp          bool         // Let user pass a breach by send valid OTP
+}
+
+// SaveNew method set some data and write entire FinancialRule record with all indexes!
+func (fr *FinancialRule) SaveNew() (err *er.Error) {
+	err = fr.Set()
+	if err != nil {
+		return
+	}
+
+	fr.IndexRecordIDForUserID()
+	return
+}
+
+// Set method set some data and write entire FinancialRule record!
+func (fr *FinancialRule) Set() (err *er.Error) {
+	fr.RecordStructureID = financialRuleStructureID
+	fr.RecordSize = fr.syllabLen()
+	fr.WriteTime = etime.Now()
+	fr.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fr.syllabEncoder(),
+	}
+	fr.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fr.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fr *FinancialRule) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fr.RecordID,
+		RecordStructureID: financialRuleStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fr.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fr.RecordStructureID != financialRuleStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByUserID method find and read last version of record by given UserID
+func (fr *FinancialRule) GetLastByUserID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fr.hashUserIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	fr.RecordID = indexRes.IndexValues[0]
+	err = fr.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialRuleStructureID)
+	}
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForUserID save RecordID chain for UserID
+// Call in each update to the exiting record!
+func (fr *FinancialRule) IndexRecordIDForUserID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fr.hashUserIDForRecordID(),
+		IndexValue: fr.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fr *FinancialRule) hashUserIDForRecordID() (hash [32]byte) {
+	const field = "UserID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialRuleStructureID)
+	copy(buf[8:], fr.UserID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fr *FinancialRule) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < fr.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fr.RecordID[:], buf[0:])
+	fr.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fr.RecordSize = syllab.GetUInt64(buf, 40)
+	fr.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fr.OwnerAppID[:], buf[56:])
+
+	copy(fr.AppInstanceID[:], buf[88:])
+	copy(fr.UserConnectionID[:], buf[120:])
+	copy(fr.UserID[:], buf[152:])
+	fr.DailyLimit = price.Amount(syllab.GetInt64(buf, 184))
+	fr.TransactionLimit = price.Amount(syllab.GetInt64(buf, 192))
+	fr.DailyCountLimit = syllab.GetUInt32(buf, 200)
+	fr.DelegateCoolingOff = syllab.GetUInt32(buf, 204)
+	fr.OTPStepUp = buf[208] == 1
+	return
+}
+
+func (fr *FinancialRule) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fr.syllabLen())
+
+	// copy(buf[0:], fr.RecordID[:])
+	syllab.SetUInt64(buf, 32, fr.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fr.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fr.WriteTime))
+	copy(buf[56:], fr.OwnerAppID[:])
+
+	copy(buf[88:], fr.AppInstanceID[:])
+	copy(buf[120:], fr.UserConnectionID[:])
+	copy(buf[152:], fr.UserID[:])
+	syllab.SetInt64(buf, 184, int64(fr.DailyLimit))
+	syllab.SetInt64(buf, 192, int64(fr.TransactionLimit))
+	syllab.SetUInt32(buf, 200, fr.DailyCountLimit)
+	syllab.SetUInt32(buf, 204, fr.DelegateCoolingOff)
+	if fr.OTPStepUp {
+		buf[208] = 1
+	}
+	return
+}
+
+func (fr *FinancialRule) syllabStackLen() (ln uint32) {
+	return 209
+}
+
+func (fr *FinancialRule) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (fr *FinancialRule) syllabLen() (ln uint64) {
+	return uint64(fr.syllabStackLen() + fr.syllabHeapLen())
+}
@@ -0,0 +1,367 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialSnapshotStructureID uint64 = 2258711034658301367
+)
+
+var financialSnapshotStructure = ganjine.DataStructure{
+	ID:                2258711034658301367,
+	IssueDate:         1792295394,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialSnapshot{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Snapshot",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store balance of a user in a currency at end of a day or a month that user has any transaction in it,
+with totals of the period transactions by their type. Use to answer past balances without walk the transactions chain.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialSnapshot ---Read locale description in financialSnapshotStructure---
+type FinancialSnapshot struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID     [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID  [32]byte // Store to remember which user connection set||chanaged this record!
+	UserID            [32]byte `index-hash:"RecordID[pair,Currency,Period,Day]"`
+	Currency          uint16   // ISO 4217 numeric code. Zero is society default currency
+	Period            FinancialSnapshotPeriod
+	Day               etime.Time   // First day of the period (round to daily)
+	OpeningBalance    price.Amount // Balance before first transaction of the period
+	ClosingBalance    price.Amount // Balance after last transaction of the period
+	LastTransactionID [32]byte     // Last transaction of the period
+	Transactions      uint32       // Number of transactions in the period
+	Totals            []FinancialSnapshotTotal
+}
+
+// FinancialSnapshotTotal is sum of a FinancialTransactionType transactions in a snapshot period.
+type FinancialSnapshotTotal struct {
+	ReferenceType FinancialTransactionType
+	Amount        price.Amount
+}
+
+// SaveNew method set some data and write entire FinancialSnapshot record with all indexes!
+func (fs *FinancialSnapshot) SaveNew() (err *er.Error) {
+	err = fs.Set()
+	if err != nil {
+		return
+	}
+
+	fs.IndexRecordIDForUserIDDay()
+	fs.ListUserIDForDay()
+	return
+}
+
+// Set method set some data and write entire FinancialSnapshot record!
+func (fs *FinancialSnapshot) Set() (err *er.Error) {
+	fs.RecordStructureID = financialSnapshotStructureID
+	fs.RecordSize = fs.syllabLen()
+	fs.WriteTime = etime.Now()
+	fs.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fs.syllabEncoder(),
+	}
+	fs.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fs.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fs *FinancialSnapshot) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fs.RecordID,
+		RecordStructureID: financialSnapshotStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fs.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fs.RecordStructureID != financialSnapshotStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByUserIDDay method find and read last version of record by given UserID, Currency, Period and Day
+func (fs *FinancialSnapshot) GetLastByUserIDDay() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fs.hashUserIDDayForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	fs.RecordID = indexRes.IndexValues[0]
+	err = fs.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialSnapshotStructureID)
+	}
+	return
+}
+
+// FindUserIDsByDay find UserIDs that has snapshot in given Currency, Period and Day.
+func (fs *FinancialSnapshot) FindUserIDsByDay(offset, limit uint64) (userIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fs.hashDayForUserID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	userIDs = indexRes.IndexValues
+	return
+}
+
+// GetLastSnapshotDay find last day that snapshot job wrote daily snapshots of all users in it.
+// ErrRecordNotFound means job not seed snapshots yet.
+func (fs *FinancialSnapshot) GetLastSnapshotDay() (day etime.Time, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fs.hashSnapshotDay(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	day = etime.Time(syllab.GetInt64(indexRes.IndexValues[0][:], 0))
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForUserIDDay save RecordID chain for UserID + Currency + Period + Day
+// Call in each update to the exiting record!
+func (fs *FinancialSnapshot) IndexRecordIDForUserIDDay() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fs.hashUserIDDayForRecordID(),
+		IndexValue: fs.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fs *FinancialSnapshot) hashUserIDDayForRecordID() (hash [32]byte) {
+	const field = "UserIDDay"
+	var buf = make([]byte, 51+len(field)) // 8+32+2+1+8
+	syllab.SetUInt64(buf, 0, financialSnapshotStructureID)
+	copy(buf[8:], fs.UserID[:])
+	syllab.SetUInt16(buf, 40, fs.Currency)
+	syllab.SetUInt8(buf, 42, uint8(fs.Period))
+	syllab.SetInt64(buf, 43, fs.Day.RoundToDay())
+	copy(buf[51:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexSnapshotDay save given day as last day that snapshot job wrote daily snapshots of all users in it.
+// Call it when job write snapshots of all users of a day.
+func (fs *FinancialSnapshot) IndexSnapshotDay(day etime.Time) {
+	var value [32]byte
+	syllab.SetInt64(value[:], 0, day.RoundToDay())
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fs.hashSnapshotDay(),
+		IndexValue: value,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fs *FinancialSnapshot) hashSnapshotDay() (hash [32]byte) {
+	const field = "SnapshotDay"
+	var buf = make([]byte, 8+len(field)) // 8
+	syllab.SetUInt64(buf, 0, financialSnapshotStructureID)
+	copy(buf[8:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- LIST FIELDS --
+*/
+
+// ListUserIDForDay save UserID chain for Currency + Period + Day
+// Don't call in update to an exiting record!
+func (fs *FinancialSnapshot) ListUserIDForDay() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fs.hashDayForUserID(),
+		IndexValue: fs.UserID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fs *FinancialSnapshot) hashDayForUserID() (hash [32]byte) {
+	const field = "ListDay"
+	var buf = make([]byte, 19+len(field)) // 8+2+1+8
+	syllab.SetUInt64(buf, 0, financialSnapshotStructureID)
+	syllab.SetUInt16(buf, 8, fs.Currency)
+	syllab.SetUInt8(buf, 10, uint8(fs.Period))
+	syllab.SetInt64(buf, 11, fs.Day.RoundToDay())
+	copy(buf[19:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fs *FinancialSnapshot) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < fs.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fs.RecordID[:], buf[0:])
+	fs.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fs.RecordSize = syllab.GetUInt64(buf, 40)
+	fs.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fs.OwnerAppID[:], buf[56:])
+
+	copy(fs.AppInstanceID[:], buf[88:])
+	copy(fs.UserConnectionID[:], buf[120:])
+	copy(fs.UserID[:], buf[152:])
+	fs.Currency = syllab.GetUInt16(buf, 184)
+	fs.Period = FinancialSnapshotPeriod(syllab.GetUInt8(buf, 186))
+	fs.Day = etime.Time(syllab.GetInt64(buf, 187))
+	fs.OpeningBalance = price.Amount(syllab.GetInt64(buf, 195))
+	fs.ClosingBalance = price.Amount(syllab.GetInt64(buf, 203))
+	copy(fs.LastTransactionID[:], buf[211:])
+	fs.Transactions = syllab.GetUInt32(buf, 243)
+
+	var add = syllab.GetUInt32(buf, 247)
+	var ln = syllab.GetUInt32(buf, 251)
+	if uint64(add)+uint64(ln)*9 > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+	fs.Totals = make([]FinancialSnapshotTotal, ln)
+	for i := range fs.Totals {
+		fs.Totals[i].ReferenceType = FinancialTransactionType(syllab.GetUInt8(buf, add))
+		fs.Totals[i].Amount = price.Amount(syllab.GetInt64(buf, add+1))
+		add += 9
+	}
+	return
+}
+
+func (fs *FinancialSnapshot) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fs.syllabLen())
+	var hsi uint32 = fs.syllabStackLen() // Heap start index || Stack size!
+
+	// copy(buf[0:], fs.RecordID[:])
+	syllab.SetUInt64(buf, 32, fs.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fs.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fs.WriteTime))
+	copy(buf[56:], fs.OwnerAppID[:])
+
+	copy(buf[88:], fs.AppInstanceID[:])
+	copy(buf[120:], fs.UserConnectionID[:])
+	copy(buf[152:], fs.UserID[:])
+	syllab.SetUInt16(buf, 184, fs.Currency)
+	syllab.SetUInt8(buf, 186, uint8(fs.Period))
+	syllab.SetInt64(buf, 187, int64(fs.Day))
+	syllab.SetInt64(buf, 195, int64(fs.OpeningBalance))
+	syllab.SetInt64(buf, 203, int64(fs.ClosingBalance))
+	copy(buf[211:], fs.LastTransactionID[:])
+	syllab.SetUInt32(buf, 243, fs.Transactions)
+
+	syllab.SetUInt32(buf, 247, hsi)
+	syllab.SetUInt32(buf, 251, uint32(len(fs.Totals)))
+	for _, total := range fs.Totals {
+		syllab.SetUInt8(buf, hsi, uint8(total.ReferenceType))
+		syllab.SetInt64(buf, hsi+1, int64(total.Amount))
+		hsi += 9
+	}
+	return
+}
+
+func (fs *FinancialSnapshot) syllabStackLen() (ln uint32) {
+	return 255
+}
+
+func (fs *FinancialSnapshot) syllabHeapLen() (ln uint32) {
+	ln = uint32(len(fs.Totals)) * 9
+	return
+}
+
+func (fs *FinancialSnapshot) syllabLen() (ln uint64) {
+	return uint64(fs.syllabStackLen() + fs.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialSnapshotPeriod indicate FinancialSnapshot record period
+type FinancialSnapshotPeriod uint8
+
+// FinancialSnapshot periods
+const (
+	FinancialSnapshotDaily FinancialSnapshotPeriod = iota
+	FinancialSnapshotMonthly
+)
@@ -0,0 +1,458 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialStandingOrderStructureID uint64 = 4127795032860195117
+)
+
+var financialStandingOrderStructure = ganjine.DataStructure{
+	ID:                4127795032860195117,
+	IssueDate:         1792294756,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialStandingOrder{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Standing Order",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store recurring payment order from a user to other user e.g. rent, membership or instalments.
+Each run write new version with run result, so versions are history of order runs.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialStandingOrder ---Read locale description in financialStandingOrderStructure---
+type FinancialStandingOrder struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte     // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte     // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte     `index-hash:"RecordID"`
+	FromUserID       [32]byte     `index-hash:"ID"`
+	ToUserID         [32]byte     `index-hash:"ID"`
+	Amount           price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
+	PeriodDays       uint32
+	ScheduledTime    etime.Time // Time of current period run
+	NextRunTime      etime.Time `index-hash:"ID[daily]"` // ScheduledTime or retry time of current period run
+	EndTime          etime.Time // Zero means no end
+	Retries          uint8      // Failed tries of current period run
+	LastTransferID   [32]byte   // FinancialTransferID of last run
+	LastRunStatus    FinancialStandingOrderRunStatus
+	Status           FinancialStandingOrderStatus
+}
+
+// SaveNew method set some data and write entire FinancialStandingOrder record with all indexes!
+func (fso *FinancialStandingOrder) SaveNew() (err *er.Error) {
+	err = fso.Set()
+	if err != nil {
+		return
+	}
+
+	fso.IndexRecordIDForID()
+	fso.IndexIDForFromUserID()
+	fso.IndexIDForToUserID()
+	fso.IndexIDForNextRunTimeDaily()
+	return
+}
+
+// Set method set some data and write entire FinancialStandingOrder record!
+func (fso *FinancialStandingOrder) Set() (err *er.Error) {
+	fso.RecordStructureID = financialStandingOrderStructureID
+	fso.RecordSize = fso.syllabLen()
+	fso.WriteTime = etime.Now()
+	fso.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fso.syllabEncoder(),
+	}
+	fso.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fso.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fso *FinancialStandingOrder) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fso.RecordID,
+		RecordStructureID: financialStandingOrderStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fso.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fso.RecordStructureID != financialStandingOrderStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (fso *FinancialStandingOrder) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fso.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	fso.RecordID = indexRes.IndexValues[0]
+	err = fso.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialStandingOrderStructureID)
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindRecordIDsByID find all versions RecordIDs of given ID in write order.
+func (fso *FinancialStandingOrder) FindRecordIDsByID(offset, limit uint64) (RecordIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fso.hashIDForRecordID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	RecordIDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByFromUserID find IDs by given FromUserID
+func (fso *FinancialStandingOrder) FindIDsByFromUserID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fso.hashFromUserIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByToUserID find IDs by given ToUserID
+func (fso *FinancialStandingOrder) FindIDsByToUserID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fso.hashToUserIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByNextRunTimeDaily find IDs by given NextRunTime(round to daily)
+// Returned IDs can be duplicate due to index store ID for each run!
+func (fso *FinancialStandingOrder) FindIDsByNextRunTimeDaily(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fso.hashNextRunTimeForIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// GetLastRunDay find first day that scheduler job not finished due orders of it yet.
+func (fso *FinancialStandingOrder) GetLastRunDay() (day etime.Time, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fso.hashRunDay(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	day = etime.Time(syllab.GetInt64(indexRes.IndexValues[0][:], 0))
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (fso *FinancialStandingOrder) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fso.hashIDForRecordID(),
+		IndexValue: fso.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fso *FinancialStandingOrder) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialStandingOrderStructureID)
+	copy(buf[8:], fso.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForFromUserID save ID chain for FromUserID.
+// Don't call in update to an exiting record!
+func (fso *FinancialStandingOrder) IndexIDForFromUserID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fso.hashFromUserIDForID(),
+		IndexValue: fso.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fso *FinancialStandingOrder) hashFromUserIDForID() (hash [32]byte) {
+	const field = "FromUserID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialStandingOrderStructureID)
+	copy(buf[8:], fso.FromUserID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForToUserID save ID chain for ToUserID.
+// Don't call in update to an exiting record!
+func (fso *FinancialStandingOrder) IndexIDForToUserID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fso.hashToUserIDForID(),
+		IndexValue: fso.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fso *FinancialStandingOrder) hashToUserIDForID() (hash [32]byte) {
+	const field = "ToUserID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialStandingOrderStructureID)
+	copy(buf[8:], fso.ToUserID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForNextRunTimeDaily save ID chain for NextRunTime daily.
+// Use by scheduler job to find due orders! Call it when NextRunTime change.
+func (fso *FinancialStandingOrder) IndexIDForNextRunTimeDaily() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fso.hashNextRunTimeForIDDaily(),
+		IndexValue: fso.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fso *FinancialStandingOrder) hashNextRunTimeForIDDaily() (hash [32]byte) {
+	const field = "NextRunTime"
+	var buf = make([]byte, 16+len(field)) // 8+8
+	syllab.SetUInt64(buf, 0, financialStandingOrderStructureID)
+	syllab.SetInt64(buf, 8, fso.NextRunTime.RoundToDay())
+	copy(buf[16:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexRunDay save given day as first day that scheduler job not finished due orders of it yet.
+// Call it when scheduler finish all due orders of a past day.
+func (fso *FinancialStandingOrder) IndexRunDay(day etime.Time) {
+	var value [32]byte
+	syllab.SetInt64(value[:], 0, day.RoundToDay())
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fso.hashRunDay(),
+		IndexValue: value,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fso *FinancialStandingOrder) hashRunDay() (hash [32]byte) {
+	const field = "RunDay"
+	var buf = make([]byte, 8+len(field)) // 8
+	syllab.SetUInt64(buf, 0, financialStandingOrderStructureID)
+	copy(buf[8:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fso *FinancialStandingOrder) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < fso.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fso.RecordID[:], buf[0:])
+	fso.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fso.RecordSize = syllab.GetUInt64(buf, 40)
+	fso.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fso.OwnerAppID[:], buf[56:])
+
+	copy(fso.AppInstanceID[:], buf[88:])
+	copy(fso.UserConnectionID[:], buf[120:])
+	copy(fso.ID[:], buf[152:])
+	copy(fso.FromUserID[:], buf[184:])
+	copy(fso.ToUserID[:], buf[216:])
+	fso.Amount = price.Amount(syllab.GetInt64(buf, 248))
+	fso.PeriodDays = syllab.GetUInt32(buf, 256)
+	fso.ScheduledTime = etime.Time(syllab.GetInt64(buf, 260))
+	fso.NextRunTime = etime.Time(syllab.GetInt64(buf, 268))
+	fso.EndTime = etime.Time(syllab.GetInt64(buf, 276))
+	fso.Retries = syllab.GetUInt8(buf, 284)
+	copy(fso.LastTransferID[:], buf[285:])
+	fso.LastRunStatus = FinancialStandingOrderRunStatus(syllab.GetUInt8(buf, 317))
+	fso.Status = FinancialStandingOrderStatus(syllab.GetUInt8(buf, 318))
+	return
+}
+
+func (fso *FinancialStandingOrder) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fso.syllabLen())
+
+	// copy(buf[0:], fso.RecordID[:])
+	syllab.SetUInt64(buf, 32, fso.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fso.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fso.WriteTime))
+	copy(buf[56:], fso.OwnerAppID[:])
+
+	copy(buf[88:], fso.AppInstanceID[:])
+	copy(buf[120:], fso.UserConnectionID[:])
+	copy(buf[152:], fso.ID[:])
+	copy(buf[184:], fso.FromUserID[:])
+	copy(buf[216:], fso.ToUserID[:])
+	syllab.SetInt64(buf, 248, int64(fso.Amount))
+	syllab.SetUInt32(buf, 256, fso.PeriodDays)
+	syllab.SetInt64(buf, 260, int64(fso.ScheduledTime))
+	syllab.SetInt64(buf, 268, int64(fso.NextRunTime))
+	syllab.SetInt64(buf, 276, int64(fso.EndTime))
+	syllab.SetUInt8(buf, 284, fso.Retries)
+	copy(buf[285:], fso.LastTransferID[:])
+	syllab.SetUInt8(buf, 317, uint8(fso.LastRunStatus))
+	syllab.SetUInt8(buf, 318, uint8(fso.Status))
+	return
+}
+
+func (fso *FinancialStandingOrder) syllabStackLen() (ln uint32) {
+	return 319
+}
+
+func (fso *FinancialStandingOrder) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (fso *FinancialStandingOrder) syllabLen() (ln uint64) {
+	return uint64(fso.syllabStackLen() + fso.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialStandingOrderStatus indicate FinancialStandingOrder record status
+type FinancialStandingOrderStatus uint8
+
+// FinancialStandingOrder status
+const (
+	FinancialStandingOrderUnset FinancialStandingOrderStatus = iota
+	FinancialStandingOrderActive
+	FinancialStandingOrderPaused
+	FinancialStandingOrderCancelled
+	FinancialStandingOrderFinished // EndTime passed
+)
+
+// FinancialStandingOrderRunStatus indicate result of last run of a FinancialStandingOrder
+type FinancialStandingOrderRunStatus uint8
+
+// FinancialStandingOrder run status
+const (
+	FinancialStandingOrderRunUnset   FinancialStandingOrderRunStatus = iota
+	FinancialStandingOrderRunDone                                    // Transfer credited to ToUserID
+	FinancialStandingOrderRunRetry                                   // Transfer failed and will retry later
+	FinancialStandingOrderRunSkipped                                 // All retries failed and period skipped
+)
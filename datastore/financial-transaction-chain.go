@@ -0,0 +1,160 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/price"
+)
+
+const financialTransactionChainPageLimit = 100
+
+// FinancialTransactionChainReport is result of verify a user financial transactions chain.
+type FinancialTransactionChainReport struct {
+	UserID         [32]byte     `json:",string"`
+	Currency       uint16       // Chain currency
+	FirstRecordID  [32]byte     `json:",string"` // First checked record
+	LastRecordID   [32]byte     `json:",string"` // Last healthy record
+	Checked        uint64       // Number of healthy records
+	OpeningBalance price.Amount // Balance before first checked record
+	Balance        price.Amount // Balance of last healthy record
+	BrokenRecordID [32]byte     `json:",string"` // First broken record if any!
+	Problem        FinancialTransactionChainProblem
+}
+
+// VerifyChain walk ft.UserID chain of ft.Currency in the daily UserID index from (ft.WriteTime - days) to ft.WriteTime.
+// It verify RecordID hash and link of each record to previous one and stop on first broken record.
+// err return just on storage problems not on chain problems that report in report.Problem!
+func (ft *FinancialTransaction) VerifyChain(days uint16) (report FinancialTransactionChainReport, err *er.Error) {
+	report.UserID = ft.UserID
+	report.Currency = ft.Currency
+
+	var day = FinancialTransaction{
+		UserID:    ft.UserID,
+		WriteTime: ft.WriteTime - etime.Time(days)*(24*60*60),
+		Currency:  ft.Currency,
+	}
+	var last FinancialTransaction
+	var started bool
+	var previousIDs = make(map[[32]byte]struct{})
+	for i := uint16(0); i <= days; i++ {
+		var offset uint64
+		for {
+			var IDs [][32]byte
+			IDs, err = day.FindRecordIDsByUserIDWriteTime(offset, financialTransactionChainPageLimit)
+			if err.Equal(ganjine.ErrRecordNotFound) {
+				err = nil
+				break
+			}
+			if err != nil {
+				return
+			}
+
+			for _, id := range IDs {
+				var rec = FinancialTransaction{
+					RecordID: id,
+				}
+				err = rec.GetByRecordID()
+				if err.Equal(ganjine.ErrRecordNotFound) || err.Equal(ganjine.ErrMisMatchedStructureID) {
+					err = nil
+					report.BrokenRecordID = id
+					report.Problem = FinancialTransactionChainUnreadable
+					return
+				}
+				if err != nil {
+					return
+				}
+
+				if !started {
+					started = true
+					report.FirstRecordID = rec.RecordID
+					if rec.PreviousTransactionID != [32]byte{} {
+						// Chain start before given period, so previous record is base of verification!
+						last = FinancialTransaction{
+							RecordID: rec.PreviousTransactionID,
+						}
+						err = last.GetByRecordID()
+						if err.Equal(ganjine.ErrRecordNotFound) || err.Equal(ganjine.ErrMisMatchedStructureID) {
+							err = nil
+							report.BrokenRecordID = rec.RecordID
+							report.Problem = FinancialTransactionChainGap
+							return
+						}
+						if err != nil {
+							return
+						}
+					}
+					report.OpeningBalance = last.Balance
+					report.Balance = last.Balance
+				}
+
+				report.Problem = checkFinancialTransactionLink(ft.UserID, ft.Currency, &last, &rec, previousIDs)
+				if report.Problem != FinancialTransactionChainHealthy {
+					report.BrokenRecordID = rec.RecordID
+					return
+				}
+				previousIDs[rec.PreviousTransactionID] = struct{}{}
+				last = rec
+				report.Checked++
+				report.LastRecordID = rec.RecordID
+				report.Balance = rec.Balance
+			}
+
+			if len(IDs) < financialTransactionChainPageLimit {
+				break
+			}
+			offset += financialTransactionChainPageLimit
+		}
+		day.WriteTime += (24 * 60 * 60)
+	}
+	return
+}
+
+// checkFinancialTransactionLink check rec is a valid next record for last in the userID chain of the currency.
+// previousIDs must include PreviousTransactionID of all records before rec in the chain.
+func checkFinancialTransactionLink(userID [32]byte, currency uint16, last, rec *FinancialTransaction, previousIDs map[[32]byte]struct{}) (problem FinancialTransactionChainProblem) {
+	if rec.UserID != userID || rec.Currency != currency {
+		return FinancialTransactionChainBadUser
+	}
+	if rec.RecordID != rec.hashRecord() {
+		return FinancialTransactionChainBadHash
+	}
+	if _, ok := previousIDs[rec.PreviousTransactionID]; ok {
+		return FinancialTransactionChainFork
+	}
+	if rec.PreviousTransactionID != last.RecordID {
+		return FinancialTransactionChainGap
+	}
+	if rec.Balance != last.Balance+rec.Amount {
+		return FinancialTransactionChainBadBalance
+	}
+	return FinancialTransactionChainHealthy
+}
+
+// hashRecord re-encode record and return its hash to compare with RecordID.
+// Records written before multi-currency wallets are shorter, so just RecordSize bytes hash.
+func (ft *FinancialTransaction) hashRecord() (hash [32]byte) {
+	var buf = ft.syllabEncoder()
+	if ft.RecordSize < uint64(len(buf)) {
+		buf = buf[:ft.RecordSize]
+	}
+	return sha512.Sum512_256(buf[32:])
+}
+
+// FinancialTransactionChainProblem indicate first problem find in a user financial transactions chain.
+type FinancialTransactionChainProblem uint8
+
+// FinancialTransactionChain problems
+const (
+	FinancialTransactionChainHealthy    FinancialTransactionChainProblem = iota
+	FinancialTransactionChainUnreadable                                  // Record exist in index but can't read it!
+	FinancialTransactionChainBadUser                                     // Record in chain belong to other user or currency!
+	FinancialTransactionChainBadHash                                     // Record data not match its RecordID!
+	FinancialTransactionChainFork                                        // Two records base on same PreviousTransactionID!
+	FinancialTransactionChainGap                                         // PreviousTransactionID not point to last record in chain!
+	FinancialTransactionChainBadBalance                                  // Balance != previous Balance + Amount
+)
@@ -0,0 +1,313 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"sync"
+	"time"
+
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gs "../libgo/ganjine-services"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/syllab"
+)
+
+/*
+	Each chain position (UserID + last RecordID) has a claims list in pehrest that only accept append.
+	Each writer append a claim and read the list, so all writers in the cluster replay same list and agree on
+	exactly one holder for the position. It is compare-and-set on last RecordID of the user chain.
+	Holder is decided just by claims order, so clock skew between app instances can't pass a live lock to other writer.
+	Holder write just in its lease by its own clock and other writers expire it after they see it hold the lock in
+	two leases by their own clocks.
+	Claim layout: [0:8] claim time in UnixNano just to debug, [8] claim kind, [9:32] random token.
+*/
+
+const (
+	financialTransactionLockLease      = 10 * time.Second // Holder not write in this period lose the lock!
+	financialTransactionLockExpire     = 2 * financialTransactionLockLease
+	financialTransactionLockRetry      = 50 * time.Millisecond
+	financialTransactionLockTimeout    = 3 * time.Second
+	financialTransactionLockClaimsPage = 256
+)
+
+const (
+	financialTransactionLockClaim   uint8 = 1
+	financialTransactionLockRelease uint8 = 2
+	financialTransactionLockExpired uint8 = 3 // Other writer release holder that not release in financialTransactionLockExpire
+)
+
+type financialTransactionLock struct {
+	claim [32]byte
+	time  time.Time // Local time that claim hold or first seen as holder
+}
+
+// financialTransactionLocks store claims that this app instance holds now and holders that this app instance wait for them,
+// key is hashUserIDRecordIDForLock()
+var financialTransactionLocks = struct {
+	sync.Mutex
+	claims  map[[32]byte]financialTransactionLock
+	holders map[[32]byte]financialTransactionLock
+}{
+	claims:  make(map[[32]byte]financialTransactionLock),
+	holders: make(map[[32]byte]financialTransactionLock),
+}
+
+// Lock read last transaction of ft.UserID in ft.Currency and lock the chain on it across the cluster to set in-time transactions!
+// It retry until financialTransactionLockTimeout and return ErrFinancialTransactionLocked if other writer hold the chain.
+// Caller must call UnLock on the new transaction with PreviousTransactionID=ft.RecordID or CancelLock on ft!
+func (ft *FinancialTransaction) Lock() (err *er.Error) {
+	var userID, currency = ft.UserID, ft.Currency
+	var deadline = time.Now().Add(financialTransactionLockTimeout)
+	for {
+		var lastRecordID [32]byte
+		lastRecordID, err = ft.getLastForLock(userID, currency)
+		if err != nil {
+			return
+		}
+
+		var won bool
+		won, err = ft.claimLock()
+		if err != nil {
+			return
+		}
+		if won {
+			// Check chain not moved after we read it e.g. a holder with expired lease wrote after all!
+			var check FinancialTransaction
+			var checkRecordID [32]byte
+			checkRecordID, err = check.getLastForLock(userID, currency)
+			if err != nil {
+				ft.CancelLock()
+				return
+			}
+			if checkRecordID == lastRecordID {
+				return
+			}
+			ft.CancelLock()
+		}
+
+		if time.Now().After(deadline) {
+			err = ErrFinancialTransactionLocked
+			return
+		}
+		time.Sleep(financialTransactionLockRetry)
+	}
+}
+
+// UnLock check lock still held on ft.PreviousTransactionID and save ft as next transaction in ft.UserID chain.
+// After it other services can set new in-time transaction records!
+func (ft *FinancialTransaction) UnLock() (err *er.Error) {
+	var last = FinancialTransaction{
+		UserID:   ft.UserID,
+		RecordID: ft.PreviousTransactionID,
+	}
+	var lockKey = last.hashUserIDRecordIDForLock()
+
+	financialTransactionLocks.Lock()
+	var lock, ok = financialTransactionLocks.claims[lockKey]
+	delete(financialTransactionLocks.claims, lockKey)
+	financialTransactionLocks.Unlock()
+	if !ok {
+		err = ErrFinancialTransactionNotLocked
+		return
+	}
+	if time.Since(lock.time) > financialTransactionLockLease {
+		releaseFinancialTransactionLockClaim(lockKey, lock.claim, financialTransactionLockRelease)
+		err = ErrFinancialTransactionLocked
+		return
+	}
+
+	var claims [][32]byte
+	claims, err = getFinancialTransactionLockClaims(lockKey)
+	if err != nil {
+		return
+	}
+	var holder, held = financialTransactionLockHolder(claims)
+	if !held || holder != lock.claim {
+		err = ErrFinancialTransactionLocked
+		return
+	}
+
+	err = ft.SaveNew()
+	return
+}
+
+// CancelLock release the lock that Lock() got on ft without write any transaction e.g. due to low balance.
+func (ft *FinancialTransaction) CancelLock() {
+	var lockKey = ft.hashUserIDRecordIDForLock()
+
+	financialTransactionLocks.Lock()
+	var lock, ok = financialTransactionLocks.claims[lockKey]
+	delete(financialTransactionLocks.claims, lockKey)
+	financialTransactionLocks.Unlock()
+	if !ok {
+		return
+	}
+
+	releaseFinancialTransactionLockClaim(lockKey, lock.claim, financialTransactionLockRelease)
+}
+
+// releaseFinancialTransactionLockClaim append release of given claim to the claims list of the lock by given kind.
+func releaseFinancialTransactionLockClaim(lockKey, claim [32]byte, kind uint8) {
+	syllab.SetInt64(claim[:], 0, time.Now().UnixNano())
+	claim[8] = kind
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   lockKey,
+		IndexValue: claim,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// Don't need to retry, lease will expire soon!
+	}
+}
+
+// getLastForLock fill ft with last transaction of given userID in given currency or just userID if user has no transaction ever.
+func (ft *FinancialTransaction) getLastForLock(userID [32]byte, currency uint16) (lastRecordID [32]byte, err *er.Error) {
+	*ft = FinancialTransaction{
+		UserID:    userID,
+		WriteTime: etime.Now(),
+		Currency:  currency,
+	}
+	err = ft.GetLastTransactionByUserID()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		// First transaction of the user in the currency! Chain index is complete, so it is not an old inactive chain.
+		*ft = FinancialTransaction{
+			UserID:   userID,
+			Currency: currency,
+		}
+		err = nil
+	}
+	lastRecordID = ft.RecordID
+	return
+}
+
+// claimLock append a claim on ft chain position and report if this app instance is the holder now.
+func (ft *FinancialTransaction) claimLock() (won bool, err *er.Error) {
+	var lockKey = ft.hashUserIDRecordIDForLock()
+	// Lease start before claim append, so holder never write after other writers expire it.
+	var claimTime = time.Now()
+	var claim [32]byte
+	syllab.SetInt64(claim[:], 0, claimTime.UnixNano())
+	claim[8] = financialTransactionLockClaim
+	rand.Read(claim[9:])
+
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   lockKey,
+		IndexValue: claim,
+	}
+	err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		return
+	}
+
+	var claims [][32]byte
+	claims, err = getFinancialTransactionLockClaims(lockKey)
+	if err != nil {
+		return
+	}
+	var holder, held = financialTransactionLockHolder(claims)
+	if !held || holder != claim {
+		// Release lost claim, so it never hold the lock later when it is not waiting for it.
+		releaseFinancialTransactionLockClaim(lockKey, claim, financialTransactionLockRelease)
+		if held {
+			expireFinancialTransactionLockHolder(lockKey, holder)
+		}
+		return
+	}
+
+	won = true
+	financialTransactionLocks.Lock()
+	delete(financialTransactionLocks.holders, lockKey)
+	financialTransactionLocks.claims[lockKey] = financialTransactionLock{claim, claimTime}
+	financialTransactionLocks.Unlock()
+	return
+}
+
+// expireFinancialTransactionLockHolder remember when this app instance see given holder first time and expire it if it still
+// hold the lock after financialTransactionLockExpire e.g. its app instance crashed before release it.
+func expireFinancialTransactionLockHolder(lockKey, holder [32]byte) {
+	var now = time.Now()
+	financialTransactionLocks.Lock()
+	var seen, ok = financialTransactionLocks.holders[lockKey]
+	if !ok || seen.claim != holder {
+		for key, h := range financialTransactionLocks.holders {
+			if now.Sub(h.time) > 2*financialTransactionLockExpire {
+				delete(financialTransactionLocks.holders, key)
+			}
+		}
+		financialTransactionLocks.holders[lockKey] = financialTransactionLock{holder, now}
+		financialTransactionLocks.Unlock()
+		return
+	}
+	var expired = now.Sub(seen.time) > financialTransactionLockExpire
+	if expired {
+		delete(financialTransactionLocks.holders, lockKey)
+	}
+	financialTransactionLocks.Unlock()
+
+	if expired {
+		releaseFinancialTransactionLockClaim(lockKey, holder, financialTransactionLockExpired)
+	}
+}
+
+// getFinancialTransactionLockClaims read all claims of the lock page by page until its tail, so claims after
+// a busy period are never out of replay.
+func getFinancialTransactionLockClaims(lockKey [32]byte) (claims [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: lockKey,
+		Limit:    financialTransactionLockClaimsPage,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	for {
+		indexRes, err = psdk.HashGetValues(indexReq)
+		if err.Equal(ganjine.ErrRecordNotFound) && len(claims) != 0 {
+			// Last page was full and no more claims.
+			return claims, nil
+		}
+		if err != nil {
+			return
+		}
+		claims = append(claims, indexRes.IndexValues...)
+		if len(indexRes.IndexValues) < financialTransactionLockClaimsPage {
+			return
+		}
+		indexReq.Offset += financialTransactionLockClaimsPage
+	}
+}
+
+// financialTransactionLockHolder replay claims in order and return the claim that hold the lock.
+// First claim hold the lock until it release or other writer expire it, then next claim after that hold it.
+// Claims time never use, so all writers agree on the holder even if their clocks are not same.
+func financialTransactionLockHolder(claims [][32]byte) (holder [32]byte, held bool) {
+	for _, claim := range claims {
+		switch claim[8] {
+		case financialTransactionLockClaim:
+			if !held {
+				holder = claim
+				held = true
+			}
+		case financialTransactionLockRelease, financialTransactionLockExpired:
+			if held && bytes.Equal(claim[9:], holder[9:]) {
+				held = false
+			}
+		}
+	}
+	return
+}
+
+func (ft *FinancialTransaction) hashUserIDRecordIDForLock() (hash [32]byte) {
+	const field = "Lock"
+	var buf = make([]byte, 72+len(field)) // 8+32+32
+	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+	copy(buf[8:], ft.UserID[:])
+	copy(buf[40:], ft.RecordID[:])
+	copy(buf[72:], field)
+	return sha512.Sum512_256(buf)
+}
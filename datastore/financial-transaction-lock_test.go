@@ -0,0 +1,94 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"../libgo/syllab"
+)
+
+func makeFinancialTransactionLockClaim(claimTime int64, kind uint8, token byte) (claim [32]byte) {
+	syllab.SetInt64(claim[:], 0, claimTime)
+	claim[8] = kind
+	claim[9] = token
+	return
+}
+
+func TestFinancialTransactionLockHolder(t *testing.T) {
+	var lease = int64(financialTransactionLockLease)
+	var first = makeFinancialTransactionLockClaim(100, financialTransactionLockClaim, 1)
+	var second = makeFinancialTransactionLockClaim(200, financialTransactionLockClaim, 2)
+	var firstRelease = makeFinancialTransactionLockClaim(150, financialTransactionLockRelease, 1)
+	var skewed = makeFinancialTransactionLockClaim(100+10*lease, financialTransactionLockClaim, 3)
+	var secondRelease = makeFinancialTransactionLockClaim(210, financialTransactionLockRelease, 2)
+	var firstLateRelease = makeFinancialTransactionLockClaim(250, financialTransactionLockRelease, 1)
+	var third = makeFinancialTransactionLockClaim(260, financialTransactionLockClaim, 4)
+	var firstExpired = makeFinancialTransactionLockClaim(50, financialTransactionLockExpired, 1)
+	var secondExpired = makeFinancialTransactionLockClaim(270, financialTransactionLockExpired, 2)
+
+	var tests = []struct {
+		name   string
+		claims [][32]byte
+		holder [32]byte
+		held   bool
+	}{
+		{"no claim", nil, [32]byte{}, false},
+		{"first claim win race", [][32]byte{first, second}, first, true},
+		{"released claim pass lock", [][32]byte{first, firstRelease, second}, second, true},
+		{"skewed clock claim not pass live lock", [][32]byte{first, skewed}, first, true},
+		{"skewed clock release time not matter", [][32]byte{skewed, firstRelease}, skewed, true},
+		{"expired claim pass lock", [][32]byte{first, firstExpired, second}, second, true},
+		{"expire of not holder claim not matter", [][32]byte{first, secondExpired, second}, first, true},
+		{"claim before expire not hold", [][32]byte{first, second, firstExpired}, [32]byte{}, false},
+		{"released lost claim never hold", [][32]byte{first, second, secondRelease, firstLateRelease, third}, third, true},
+		{"lock free after lost claim release", [][32]byte{first, second, secondRelease, firstLateRelease}, [32]byte{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var holder, held = financialTransactionLockHolder(tt.claims)
+			if held != tt.held || (held && holder != tt.holder) {
+				t.Errorf("financialTransactionLockHolder() = %v, %v, want %v, %v", holder, held, tt.holder, tt.held)
+			}
+		})
+	}
+}
+
+func TestExpireFinancialTransactionLockHolder_FirstSeen(t *testing.T) {
+	var lockKey, holder = [32]byte{1}, makeFinancialTransactionLockClaim(100, financialTransactionLockClaim, 1)
+	var other = makeFinancialTransactionLockClaim(100, financialTransactionLockClaim, 2)
+	defer func() {
+		financialTransactionLocks.Lock()
+		delete(financialTransactionLocks.holders, lockKey)
+		financialTransactionLocks.Unlock()
+	}()
+
+	var tests = []struct {
+		name     string
+		holder   [32]byte
+		want     [32]byte
+		keepSeen bool
+	}{
+		{"remember new holder", holder, holder, false},
+		{"keep first seen of same holder", holder, holder, true},
+		{"remember next holder", other, other, false},
+	}
+	var firstSeen time.Time
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Holders seen in less than financialTransactionLockExpire never expire, so no claim append.
+			expireFinancialTransactionLockHolder(lockKey, tt.holder)
+			financialTransactionLocks.Lock()
+			var seen = financialTransactionLocks.holders[lockKey]
+			financialTransactionLocks.Unlock()
+			if seen.claim != tt.want {
+				t.Errorf("expireFinancialTransactionLockHolder() holder = %v, want %v", seen.claim, tt.want)
+			}
+			if tt.keepSeen && !seen.time.Equal(firstSeen) {
+				t.Errorf("expireFinancialTransactionLockHolder() first seen = %v, want %v", seen.time, firstSeen)
+			}
+			firstSeen = seen.time
+		})
+	}
+}
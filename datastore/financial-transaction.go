@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"crypto/sha512"
+	"sync/atomic"
 
 	"../libgo/achaemenid"
 	etime "../libgo/earth-time"
@@ -23,6 +24,15 @@ const (
 	financialTransactionStructureID uint64 = 11180411632961596298
 )
 
+// financialTransactionChainsIndexed set to 1 when old transactions of all users chains indexed.
+var financialTransactionChainsIndexed int32
+
+// SetFinancialTransactionChainsIndexed indicate old transactions of all users chains indexed,
+// so no transaction in user chain indexes means user has no transaction ever.
+func SetFinancialTransactionChainsIndexed() {
+	atomic.StoreInt32(&financialTransactionChainsIndexed, 1)
+}
+
 var financialTransactionStructure = ganjine.DataStructure{
 	ID:                11180411632961596298,
 	IssueDate:         1599291620,
@@ -49,33 +59,19 @@ type FinancialTransaction struct {
 	RecordID          [32]byte
 	RecordStructureID uint64
 	RecordSize        uint64
-	WriteTime         etime.Time
+	WriteTime         etime.Time `index-hash:"UserID[daily]"`
 	OwnerAppID        [32]byte
 
 	/* Unique data */
 	AppInstanceID         [32]byte // Store to remember which app instance set||chanaged this record!
 	UserConnectionID      [32]byte // Store to remember which user connection set||chanaged this record!
 	UserID                [32]byte `index-hash:"RecordID[daily]"`
-	ReferenceID           [32]byte // data base on ReferenceType
+	ReferenceID           [32]byte `index-hash:"RecordID"` // data base on ReferenceType
 	ReferenceType         FinancialTransactionType
 	PreviousTransactionID [32]byte     // Last RecordID this transaction base on it!
 	Amount                price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
 	Balance               price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
-}
-
-// Lock read last RecordID and lock userID to set in-time transactions!
-func (ft *FinancialTransaction) Lock() (err *er.Error) {
-	err = ft.GetLastTransactionByUserID()
-	return
-}
-
-// UnLock will unlock userID to let other services set new in-time transaction records!
-func (ft *FinancialTransaction) UnLock() (err *er.Error) {
-	err = ft.SaveNew()
-	if err != nil {
-		return
-	}
-	return
+	Currency              uint16       // ISO 4217 numeric code. Zero is society default currency. Each currency has its own chain!
 }
 
 // SaveNew method set some data and write entire FinancialTransaction record with all indexes!
@@ -85,7 +81,12 @@ func (ft *FinancialTransaction) SaveNew() (err *er.Error) {
 		return
 	}
 
+	ft.IndexRecordIDForUserID()
 	ft.IndexUserIDForRecordIDDaily()
+	ft.IndexUserIDForWriteTimeDaily()
+	if ft.ReferenceID != [32]byte{} {
+		ft.IndexRecordIDForReferenceID()
+	}
 	return
 }
 
@@ -138,9 +139,69 @@ func (ft *FinancialTransaction) GetByRecordID() (err *er.Error) {
 	-- Get Last Methods --
 */
 
-// GetLastTransactionByUserID method find and read last version of record by given UserID.
-// It returns error if can't find any record in last 90 days! longer than this period must do carefully in service logic!
+// GetLastTransactionByUserID method find and read last transaction of given UserID in given Currency by the user chain index.
+// ErrRecordNotFound means user has no transaction in the currency ever. ErrFinancialTransactionChainNotIndexed means
+// user has no transaction after chain index and old transactions not indexed yet, so caller can't know user balance now!
 func (ft *FinancialTransaction) GetLastTransactionByUserID() (err *er.Error) {
+	var userID, currency = ft.UserID, ft.Currency
+	err = ft.getLastByIndexKey(ft.hashUserIDForRecordID())
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		err = ft.getLastByIndexKey(ft.hashUserIDForOldRecordID())
+	}
+	if atomic.LoadInt32(&financialTransactionChainsIndexed) == 1 {
+		return
+	}
+	if err != nil && !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+
+	// Chains not indexed yet, so app instances that not run chain index may write transactions after chain head.
+	// All app instances write daily index, so last of chain head and last daily transaction is the last transaction.
+	var daily = FinancialTransaction{
+		UserID:    userID,
+		WriteTime: etime.Now(),
+		Currency:  currency,
+	}
+	var dailyErr = daily.getLastTransactionByUserIDDaily()
+	if dailyErr.Equal(ganjine.ErrRecordNotFound) {
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = ErrFinancialTransactionChainNotIndexed
+		}
+		return
+	}
+	if dailyErr != nil {
+		return dailyErr
+	}
+	if err != nil || daily.WriteTime > ft.WriteTime || daily.PreviousTransactionID == ft.RecordID {
+		*ft = daily
+	}
+	return nil
+}
+
+// getLastByIndexKey read last transaction that appended to given chain index.
+func (ft *FinancialTransaction) getLastByIndexKey(indexKey [32]byte) (err *er.Error) {
+	var indexRequest = pehrest.HashGetValuesReq{
+		IndexKey: indexKey,
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(&indexRequest)
+	if err != nil {
+		return
+	}
+
+	ft.RecordID = indexRes.IndexValues[0]
+	err = ft.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialTransactionStructureID)
+	}
+	return
+}
+
+// getLastTransactionByUserIDDaily find and read last transaction of given UserID in given Currency by daily index.
+// It returns error if can't find any record in 90 days before ft.WriteTime!
+func (ft *FinancialTransaction) getLastTransactionByUserIDDaily() (err *er.Error) {
 	var indexRequest = pehrest.HashGetValuesReq{
 		IndexKey: ft.hashUserIDForRecordIDDaily(),
 		Offset:   18446744073709551615,
@@ -167,11 +228,43 @@ func (ft *FinancialTransaction) GetLastTransactionByUserID() (err *er.Error) {
 	return
 }
 
+// ClaimChainIndexDate return time that first app instance of the society run with user chain index and save now as it
+// if no app instance claimed it before. Last transaction of each user chain before it must index by IndexOldRecordIDForUserID.
+func (ft *FinancialTransaction) ClaimChainIndexDate() (date etime.Time, err *er.Error) {
+	// First value of the index is the date, so app instances that claim in same time agree on it.
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ft.hashChainIndexDate(),
+		Offset:   0,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		var value [32]byte
+		syllab.SetInt64(value[:], 0, int64(etime.Now()))
+		var indexRequest = pehrest.HashSetValueReq{
+			Type:       gs.RequestTypeBroadcast,
+			IndexKey:   ft.hashChainIndexDate(),
+			IndexValue: value,
+		}
+		err = psdk.HashSetValue(&indexRequest)
+		if err != nil {
+			return
+		}
+		indexRes, err = psdk.HashGetValues(indexReq)
+	}
+	if err != nil {
+		return
+	}
+	date = etime.Time(syllab.GetInt64(indexRes.IndexValues[0][:], 0))
+	return
+}
+
 /*
 	-- Search Methods --
 */
 
-// FindRecordIDsByUserIDWriteTime find RecordsIDs by given UserID + WriteTime(round to daily)
+// FindRecordIDsByUserIDWriteTime find RecordsIDs by given UserID + WriteTime(round to daily) in given Currency
 func (ft *FinancialTransaction) FindRecordIDsByUserIDWriteTime(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
 	var indexReq = &pehrest.HashGetValuesReq{
 		IndexKey: ft.hashUserIDForRecordIDDaily(),
@@ -180,6 +273,42 @@ func (ft *FinancialTransaction) FindRecordIDsByUserIDWriteTime(offset, limit uin
 	}
 	var indexRes *pehrest.HashGetValuesRes
 	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindUserIDsByWriteTimeDaily find UserIDs that have any transaction in given WriteTime(round to daily).
+// Returned UserIDs can be duplicate due to index store UserID for each transaction!
+func (ft *FinancialTransaction) FindUserIDsByWriteTimeDaily(offset, limit uint64) (userIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ft.hashWriteTimeForUserIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	userIDs = indexRes.IndexValues
+	return
+}
+
+// FindRecordIDsByReferenceID find RecordsIDs by given ReferenceID e.g. to find all legs of a transfer.
+func (ft *FinancialTransaction) FindRecordIDsByReferenceID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ft.hashReferenceIDForRecordID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
 	IDs = indexRes.IndexValues
 	return
 }
@@ -188,7 +317,95 @@ func (ft *FinancialTransaction) FindRecordIDsByUserIDWriteTime(offset, limit uin
 	-- PRIMARY INDEXES --
 */
 
+// IndexRecordIDForUserID save RecordID chain for UserID in ft.Currency.
+// Last value is last transaction of the user, so last balance get by one read.
+func (ft *FinancialTransaction) IndexRecordIDForUserID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ft.hashUserIDForRecordID(),
+		IndexValue: ft.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ft *FinancialTransaction) hashUserIDForRecordID() (hash [32]byte) {
+	const field = "UserIDChain"
+	var buf = make([]byte, 42+len(field)) // 8+32+2
+	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+	copy(buf[8:], ft.UserID[:])
+	syllab.SetUInt16(buf, 40, ft.Currency)
+	copy(buf[42:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexOldRecordIDForUserID index last transaction of ft.UserID in ft.Currency in ft.WriteTime day as last old transaction
+// of the user if no later day indexed before. Call it for days before ClaimChainIndexDate() in descending order!
+func (ft *FinancialTransaction) IndexOldRecordIDForUserID() (err *er.Error) {
+	var indexRequest = pehrest.HashGetValuesReq{
+		IndexKey: ft.hashUserIDForOldRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	_, err = psdk.HashGetValues(&indexRequest)
+	if !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+
+	var IDs [][32]byte
+	IDs, err = ft.FindRecordIDsByUserIDWriteTime(18446744073709551615, 1)
+	if err != nil {
+		return
+	}
+	var setRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ft.hashUserIDForOldRecordID(),
+		IndexValue: IDs[0],
+	}
+	err = psdk.HashSetValue(&setRequest)
+	return
+}
+
+func (ft *FinancialTransaction) hashUserIDForOldRecordID() (hash [32]byte) {
+	const field = "UserIDOldChain"
+	var buf = make([]byte, 42+len(field)) // 8+32+2
+	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+	copy(buf[8:], ft.UserID[:])
+	syllab.SetUInt16(buf, 40, ft.Currency)
+	copy(buf[42:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexChainHead append ft as last transaction of ft.UserID in ft.Currency to the user chain index if chain head is not ft
+// e.g. app instances that not run chain index wrote it. Call it in locked situation on last transaction of the user!
+func (ft *FinancialTransaction) IndexChainHead() (err *er.Error) {
+	var head = FinancialTransaction{
+		UserID:   ft.UserID,
+		Currency: ft.Currency,
+	}
+	err = head.getLastByIndexKey(head.hashUserIDForRecordID())
+	if err != nil && !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+	if err == nil && head.RecordID == ft.RecordID {
+		return
+	}
+	ft.IndexRecordIDForUserID()
+	return nil
+}
+
+func (ft *FinancialTransaction) hashChainIndexDate() (hash [32]byte) {
+	const field = "ChainIndexDate"
+	var buf = make([]byte, 8+len(field)) // 8
+	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+	copy(buf[8:], field)
+	return sha512.Sum512_256(buf)
+}
+
 // IndexUserIDForRecordIDDaily index ft.UserID on daily base to retrieve record fast later.
+// Each currency index separately, so this index is the chain of the user in ft.Currency.
 func (ft *FinancialTransaction) IndexUserIDForRecordIDDaily() {
 	var indexRequest = pehrest.HashSetValueReq{
 		Type:       gs.RequestTypeBroadcast,
@@ -203,11 +420,21 @@ func (ft *FinancialTransaction) IndexUserIDForRecordIDDaily() {
 
 func (ft *FinancialTransaction) hashUserIDForRecordIDDaily() (hash [32]byte) {
 	const field = "UserID"
-	var buf = make([]byte, 48+len(field)) // 8+32+8
+	if ft.Currency == 0 {
+		// Default currency keep old key to not lose chains written before multi-currency wallets.
+		var buf = make([]byte, 48+len(field)) // 8+32+8
+		syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+		copy(buf[8:], ft.UserID[:])
+		syllab.SetInt64(buf, 40, ft.WriteTime.RoundToDay())
+		copy(buf[48:], field)
+		return sha512.Sum512_256(buf)
+	}
+	var buf = make([]byte, 50+len(field)) // 8+32+8+2
 	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
 	copy(buf[8:], ft.UserID[:])
 	syllab.SetInt64(buf, 40, ft.WriteTime.RoundToDay())
-	copy(buf[48:], field)
+	syllab.SetUInt16(buf, 48, ft.Currency)
+	copy(buf[50:], field)
 	return sha512.Sum512_256(buf)
 }
 
@@ -215,7 +442,51 @@ func (ft *FinancialTransaction) hashUserIDForRecordIDDaily() (hash [32]byte) {
 	-- SECONDARY INDEXES --
 */
 
-// ??
+// IndexRecordIDForReferenceID save RecordID chain for ReferenceID.
+// Use to find all legs that reference same thing e.g. both legs of a transfer.
+func (ft *FinancialTransaction) IndexRecordIDForReferenceID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ft.hashReferenceIDForRecordID(),
+		IndexValue: ft.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+// IndexUserIDForWriteTimeDaily save UserID chain for WriteTime daily.
+// Use by chain verifier to find users that must check in a day!
+func (ft *FinancialTransaction) IndexUserIDForWriteTimeDaily() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ft.hashWriteTimeForUserIDDaily(),
+		IndexValue: ft.UserID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ft *FinancialTransaction) hashWriteTimeForUserIDDaily() (hash [32]byte) {
+	const field = "WriteTime"
+	var buf = make([]byte, 16+len(field)) // 8+8
+	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+	syllab.SetInt64(buf, 8, ft.WriteTime.RoundToDay())
+	copy(buf[16:], field)
+	return sha512.Sum512_256(buf)
+}
+
+func (ft *FinancialTransaction) hashReferenceIDForRecordID() (hash [32]byte) {
+	const field = "ReferenceID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialTransactionStructureID)
+	copy(buf[8:], ft.ReferenceID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
 
 /*
 	-- LIST FIELDS --
@@ -228,7 +499,7 @@ func (ft *FinancialTransaction) hashUserIDForRecordIDDaily() (hash [32]byte) {
 */
 
 func (ft *FinancialTransaction) syllabDecoder(buf []byte) (err *er.Error) {
-	if uint32(len(buf)) < ft.syllabStackLen() {
+	if len(buf) < 265 {
 		err = syllab.ErrSyllabDecodeSmallSlice
 		return
 	}
@@ -247,6 +518,10 @@ func (ft *FinancialTransaction) syllabDecoder(buf []byte) (err *er.Error) {
 	copy(ft.PreviousTransactionID[:], buf[217:])
 	ft.Amount = price.Amount(syllab.GetInt64(buf, 249))
 	ft.Balance = price.Amount(syllab.GetUInt64(buf, 257))
+	// Records written before multi-currency wallets has no currency and are in default currency.
+	if len(buf) >= 267 {
+		ft.Currency = syllab.GetUInt16(buf, 265)
+	}
 	return
 }
 
@@ -267,11 +542,12 @@ func (ft *FinancialTransaction) syllabEncoder() (buf []byte) {
 	copy(buf[217:], ft.PreviousTransactionID[:])
 	syllab.SetInt64(buf, 249, int64(ft.Amount))
 	syllab.SetInt64(buf, 257, int64(ft.Balance))
+	syllab.SetUInt16(buf, 265, ft.Currency)
 	return
 }
 
 func (ft *FinancialTransaction) syllabStackLen() (ln uint32) {
-	return 265
+	return 267
 }
 
 func (ft *FinancialTransaction) syllabHeapLen() (ln uint32) {
@@ -293,11 +569,18 @@ type FinancialTransactionType uint8
 const (
 	FinancialTransactionUnset FinancialTransactionType = iota
 	FinancialTransactionFailed
-	FinancialTransactionBlocked //  JusticeID
-	FinancialTransactionDonate  // UserID Transferred
+	FinancialTransactionBlocked //  FinancialFreezeID that reference to a justice case
+	FinancialTransactionDonate  // FinancialTransferID
 	FinancialTransactionBankTransfer
 	FinancialTransactionPOSTransfer // ForeignExchangeID
-	FinancialTransactionWebTransfer // ForeignExchangeID
+	FinancialTransactionWebTransfer // FinancialWebPaymentID
 	FinancialTransactionProductAuctionCommission
 	FinancialTransactionProductAuctionPrice // ProductID
+	FinancialTransactionReversal            // FinancialTransferID that its withdraw leg reversed || RecordID of reversed leg
+	FinancialTransactionSocietyTransfer     // FinancialTransferID that settle with other society
+	FinancialTransactionRefund              // RecordID of refunded invoice leg
+	FinancialTransactionEscrowHold          // FinancialEscrowID
+	FinancialTransactionEscrowRelease       // FinancialEscrowID
+	FinancialTransactionEscrowRefund        // FinancialEscrowID
+	FinancialTransactionExchange            // FinancialExchangeID
 )
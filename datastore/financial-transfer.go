@@ -0,0 +1,296 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialTransferStructureID uint64 = 15717832643004591838
+)
+
+var financialTransferStructure = ganjine.DataStructure{
+	ID:                15717832643004591838,
+	IssueDate:         1792293583,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialTransfer{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Transfer",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store state of each two-leg transfer between users. Both FinancialTransaction legs store transfer ID as ReferenceID.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialTransfer ---Read locale description in financialTransferStructure---
+type FinancialTransfer struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time `index-hash:"ID[daily]"`
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte `index-hash:"RecordID"`
+	FromUserID       [32]byte
+	ToUserID         [32]byte
+	Amount           price.Amount // Some number base on currency is Decimal part e.g. 8099 >> 80.99$
+	Status           FinancialTransferStatus
+	FromSocietyID    uint32 // Zero or local society ID for in-society transfers
+	ToSocietyID      uint32 // Zero or local society ID for in-society transfers
+}
+
+// SaveNew method set some data and write entire FinancialTransfer record with all indexes!
+func (ftr *FinancialTransfer) SaveNew() (err *er.Error) {
+	err = ftr.Set()
+	if err != nil {
+		return
+	}
+
+	ftr.IndexRecordIDForID()
+	ftr.IndexIDForWriteTimeDaily()
+	return
+}
+
+// Set method set some data and write entire FinancialTransfer record!
+func (ftr *FinancialTransfer) Set() (err *er.Error) {
+	ftr.RecordStructureID = financialTransferStructureID
+	ftr.RecordSize = ftr.syllabLen()
+	ftr.WriteTime = etime.Now()
+	ftr.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: ftr.syllabEncoder(),
+	}
+	ftr.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], ftr.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (ftr *FinancialTransfer) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          ftr.RecordID,
+		RecordStructureID: financialTransferStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = ftr.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if ftr.RecordStructureID != financialTransferStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (ftr *FinancialTransfer) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ftr.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	ftr.RecordID = indexRes.IndexValues[0]
+	err = ftr.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialTransferStructureID)
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindIDsByWriteTimeDaily find IDs by given WriteTime(round to daily)
+func (ftr *FinancialTransfer) FindIDsByWriteTimeDaily(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ftr.hashWriteTimeForIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (ftr *FinancialTransfer) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ftr.hashIDForRecordID(),
+		IndexValue: ftr.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ftr *FinancialTransfer) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialTransferStructureID)
+	copy(buf[8:], ftr.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForWriteTimeDaily save ID chain for WriteTime daily.
+// Use by recovery job to find half-done transfers!
+// Don't call in update to an exiting record!
+func (ftr *FinancialTransfer) IndexIDForWriteTimeDaily() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ftr.hashWriteTimeForIDDaily(),
+		IndexValue: ftr.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ftr *FinancialTransfer) hashWriteTimeForIDDaily() (hash [32]byte) {
+	const field = "WriteTime"
+	var buf = make([]byte, 16+len(field)) // 8+8
+	syllab.SetUInt64(buf, 0, financialTransferStructureID)
+	syllab.SetInt64(buf, 8, ftr.WriteTime.RoundToDay())
+	copy(buf[16:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (ftr *FinancialTransfer) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < ftr.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(ftr.RecordID[:], buf[0:])
+	ftr.RecordStructureID = syllab.GetUInt64(buf, 32)
+	ftr.RecordSize = syllab.GetUInt64(buf, 40)
+	ftr.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(ftr.OwnerAppID[:], buf[56:])
+
+	copy(ftr.AppInstanceID[:], buf[88:])
+	copy(ftr.UserConnectionID[:], buf[120:])
+	copy(ftr.ID[:], buf[152:])
+	copy(ftr.FromUserID[:], buf[184:])
+	copy(ftr.ToUserID[:], buf[216:])
+	ftr.Amount = price.Amount(syllab.GetInt64(buf, 248))
+	ftr.Status = FinancialTransferStatus(syllab.GetUInt8(buf, 256))
+	ftr.FromSocietyID = syllab.GetUInt32(buf, 257)
+	ftr.ToSocietyID = syllab.GetUInt32(buf, 261)
+	return
+}
+
+func (ftr *FinancialTransfer) syllabEncoder() (buf []byte) {
+	buf = make([]byte, ftr.syllabLen())
+
+	// copy(buf[0:], ftr.RecordID[:])
+	syllab.SetUInt64(buf, 32, ftr.RecordStructureID)
+	syllab.SetUInt64(buf, 40, ftr.RecordSize)
+	syllab.SetInt64(buf, 48, int64(ftr.WriteTime))
+	copy(buf[56:], ftr.OwnerAppID[:])
+
+	copy(buf[88:], ftr.AppInstanceID[:])
+	copy(buf[120:], ftr.UserConnectionID[:])
+	copy(buf[152:], ftr.ID[:])
+	copy(buf[184:], ftr.FromUserID[:])
+	copy(buf[216:], ftr.ToUserID[:])
+	syllab.SetInt64(buf, 248, int64(ftr.Amount))
+	syllab.SetUInt8(buf, 256, uint8(ftr.Status))
+	syllab.SetUInt32(buf, 257, ftr.FromSocietyID)
+	syllab.SetUInt32(buf, 261, ftr.ToSocietyID)
+	return
+}
+
+func (ftr *FinancialTransfer) syllabStackLen() (ln uint32) {
+	return 265
+}
+
+func (ftr *FinancialTransfer) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (ftr *FinancialTransfer) syllabLen() (ln uint64) {
+	return uint64(ftr.syllabStackLen() + ftr.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialTransferStatus indicate FinancialTransfer record status
+type FinancialTransferStatus uint8
+
+// FinancialTransfer status
+const (
+	FinancialTransferUnset      FinancialTransferStatus = iota
+	FinancialTransferRegistered                         // No leg written yet!
+	FinancialTransferDebited                            // FromUserID leg written but ToUserID leg not yet! or wait for other society acknowledgement
+	FinancialTransferCredited                           // Both legs written successfully.
+	FinancialTransferReversed                           // ToUserID leg failed and FromUserID balance restored by reversal leg.
+	FinancialTransferFailed                             // No leg written and never will!
+)
@@ -0,0 +1,245 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	financialWebPaymentStructureID uint64 = 9692082188830823522
+)
+
+var financialWebPaymentStructure = ganjine.DataStructure{
+	ID:                9692082188830823522,
+	IssueDate:         1792293860,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         FinancialWebPayment{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Financial Web Payment",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store each payment that user pay in a web payment gateway to top up a wallet.
+FinancialTransaction record just register after gateway verify the payment.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// FinancialWebPayment ---Read locale description in financialWebPaymentStructure---
+type FinancialWebPayment struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte `index-hash:"RecordID"`
+	UserID           [32]byte // User that wallet top up
+	Amount           price.Amount
+	GatewayID        uint8    // Gateway that payment register in it, same as second byte of PosID
+	GatewayReference [32]byte // Gateway tracking number that fill on verify
+	TransactionID    [32]byte // FinancialTransaction RecordID that register after verify
+	Status           FinancialWebPaymentStatus
+}
+
+// SaveNew method set some data and write entire FinancialWebPayment record with all indexes!
+func (fwp *FinancialWebPayment) SaveNew() (err *er.Error) {
+	err = fwp.Set()
+	if err != nil {
+		return
+	}
+	fwp.IndexRecordIDForID()
+	return
+}
+
+// Set method set some data and write entire FinancialWebPayment record!
+func (fwp *FinancialWebPayment) Set() (err *er.Error) {
+	fwp.RecordStructureID = financialWebPaymentStructureID
+	fwp.RecordSize = fwp.syllabLen()
+	fwp.WriteTime = etime.Now()
+	fwp.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: fwp.syllabEncoder(),
+	}
+	fwp.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], fwp.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (fwp *FinancialWebPayment) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          fwp.RecordID,
+		RecordStructureID: financialWebPaymentStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = fwp.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if fwp.RecordStructureID != financialWebPaymentStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (fwp *FinancialWebPayment) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: fwp.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	fwp.RecordID = indexRes.IndexValues[0]
+	err = fwp.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", financialWebPaymentStructureID)
+	}
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (fwp *FinancialWebPayment) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   fwp.hashIDForRecordID(),
+		IndexValue: fwp.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (fwp *FinancialWebPayment) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, financialWebPaymentStructureID)
+	copy(buf[8:], fwp.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (fwp *FinancialWebPayment) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < fwp.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(fwp.RecordID[:], buf[0:])
+	fwp.RecordStructureID = syllab.GetUInt64(buf, 32)
+	fwp.RecordSize = syllab.GetUInt64(buf, 40)
+	fwp.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(fwp.OwnerAppID[:], buf[56:])
+
+	copy(fwp.AppInstanceID[:], buf[88:])
+	copy(fwp.UserConnectionID[:], buf[120:])
+	copy(fwp.ID[:], buf[152:])
+	copy(fwp.UserID[:], buf[184:])
+	fwp.Amount = price.Amount(syllab.GetInt64(buf, 216))
+	fwp.GatewayID = syllab.GetUInt8(buf, 224)
+	copy(fwp.GatewayReference[:], buf[225:])
+	copy(fwp.TransactionID[:], buf[257:])
+	fwp.Status = FinancialWebPaymentStatus(syllab.GetUInt8(buf, 289))
+	return
+}
+
+func (fwp *FinancialWebPayment) syllabEncoder() (buf []byte) {
+	buf = make([]byte, fwp.syllabLen())
+
+	// copy(buf[0:], fwp.RecordID[:])
+	syllab.SetUInt64(buf, 32, fwp.RecordStructureID)
+	syllab.SetUInt64(buf, 40, fwp.RecordSize)
+	syllab.SetInt64(buf, 48, int64(fwp.WriteTime))
+	copy(buf[56:], fwp.OwnerAppID[:])
+
+	copy(buf[88:], fwp.AppInstanceID[:])
+	copy(buf[120:], fwp.UserConnectionID[:])
+	copy(buf[152:], fwp.ID[:])
+	copy(buf[184:], fwp.UserID[:])
+	syllab.SetInt64(buf, 216, int64(fwp.Amount))
+	syllab.SetUInt8(buf, 224, fwp.GatewayID)
+	copy(buf[225:], fwp.GatewayReference[:])
+	copy(buf[257:], fwp.TransactionID[:])
+	syllab.SetUInt8(buf, 289, uint8(fwp.Status))
+	return
+}
+
+func (fwp *FinancialWebPayment) syllabStackLen() (ln uint32) {
+	return 290
+}
+
+func (fwp *FinancialWebPayment) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (fwp *FinancialWebPayment) syllabLen() (ln uint64) {
+	return uint64(fwp.syllabStackLen() + fwp.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// FinancialWebPaymentStatus indicate FinancialWebPayment record status
+type FinancialWebPaymentStatus uint8
+
+// FinancialWebPayment status
+const (
+	FinancialWebPaymentUnset    FinancialWebPaymentStatus = iota
+	FinancialWebPaymentPending                            // User redirect to gateway and platform wait for callback
+	FinancialWebPaymentVerified                           // Gateway verify payment and FinancialTransaction registered
+	FinancialWebPaymentFailed                             // Gateway reject payment or callback not valid
+)
@@ -7,7 +7,17 @@ import (
 )
 
 func init() {
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialEscrowStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialExchangeStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialFreezeStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialIdempotencyStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialRuleStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialSnapshotStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialStandingOrderStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialTransactionStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialTransferStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&financialWebPaymentStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&invoiceStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&organizationAuthenticationStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&personAuthenticationStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&personNumberStructure)
@@ -15,6 +25,9 @@ func init() {
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&productAuctionStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&productPriceStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&productStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&productStockStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&productStockReorderStructure)
+	ganjine.Cluster.DataStructures.RegisterDataStructure(&productTransitStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&quiddityStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&userAppConnectionStructure)
 	ganjine.Cluster.DataStructures.RegisterDataStructure(&userNameStructure)
@@ -0,0 +1,478 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+const (
+	invoiceStructureID uint64 = 6301836498571202317
+)
+
+var invoiceStructure = ganjine.DataStructure{
+	ID:                6301836498571202317,
+	IssueDate:         1792296169,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         Invoice{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Invoice",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store a sale to a buyer with its products and prices. Each line is a sold product with its own
+buyer debit transaction, so products and their transactions can find from the invoice.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// Invoice ---Read locale description in invoiceStructure---
+type Invoice struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte `index-hash:"RecordID"` // InvoiceID
+	BuyerID          [32]byte `index-hash:"ID[daily]"`
+	SellerID         [32]byte `index-hash:"ID[daily]"` // Empty if buyer register the invoice by itself
+	Price            price.Amount
+	Discount         price.Amount
+	DCCommission     price.Amount
+	SellerCommission price.Amount
+	Tax              price.Amount // Tax included in Price
+	Payable          price.Amount // Price - Discount
+	Status           InvoiceStatus
+	SMSStatus        InvoiceReceiptStatus
+	EmailStatus      InvoiceReceiptStatus
+	SMSID            uint64        // SMS provider message ID to track delivery
+	Lines            []InvoiceLine // DCID of each line index for the invoice ID
+}
+
+// InvoiceLine is a sold product in an invoice.
+type InvoiceLine struct {
+	ProductID        [32]byte
+	QuiddityID       [32]byte
+	ProductAuctionID [32]byte
+	DCID             [32]byte
+	TransactionID    [32]byte // Buyer debit leg of the product
+	Price            price.Amount
+	Discount         price.Amount
+	DCCommission     price.Amount
+	SellerCommission price.Amount
+	Tax              price.Amount
+	Payable          price.Amount
+}
+
+// AddLine append given line to the invoice and add its prices to the invoice totals.
+func (inv *Invoice) AddLine(line InvoiceLine) {
+	inv.Lines = append(inv.Lines, line)
+	inv.Price += line.Price
+	inv.Discount += line.Discount
+	inv.DCCommission += line.DCCommission
+	inv.SellerCommission += line.SellerCommission
+	inv.Tax += line.Tax
+	inv.Payable += line.Payable
+}
+
+// SaveNew method set some data and write entire Invoice record with all indexes!
+func (inv *Invoice) SaveNew() (err *er.Error) {
+	err = inv.Set()
+	if err != nil {
+		return
+	}
+
+	inv.IndexRecordIDForID()
+	inv.IndexIDForBuyerIDDaily()
+	if inv.SellerID != [32]byte{} {
+		inv.IndexIDForSellerIDDaily()
+	}
+	inv.IndexIDForDCID()
+	return
+}
+
+// Set method set some data and write entire Invoice record!
+func (inv *Invoice) Set() (err *er.Error) {
+	inv.RecordStructureID = invoiceStructureID
+	inv.RecordSize = inv.syllabLen()
+	inv.WriteTime = etime.Now()
+	inv.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: inv.syllabEncoder(),
+	}
+	inv.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], inv.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (inv *Invoice) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          inv.RecordID,
+		RecordStructureID: invoiceStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = inv.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if inv.RecordStructureID != invoiceStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID find and read last version of record by given ID
+func (inv *Invoice) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: inv.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	inv.RecordID = indexRes.IndexValues[0]
+	err = inv.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", invoiceStructureID)
+	}
+	return
+}
+
+// FindIDsByBuyerIDDaily find IDs of BuyerID invoices in given WriteTime(round to daily).
+func (inv *Invoice) FindIDsByBuyerIDDaily(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: inv.hashBuyerIDForIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsBySellerIDDaily find IDs of SellerID invoices in given WriteTime(round to daily).
+func (inv *Invoice) FindIDsBySellerIDDaily(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: inv.hashSellerIDForIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByDCID find IDs of invoices that has any line from given dcID.
+func (inv *Invoice) FindIDsByDCID(dcID [32]byte, offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: hashDCIDForInvoiceID(dcID),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (inv *Invoice) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   inv.hashIDForRecordID(),
+		IndexValue: inv.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (inv *Invoice) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, invoiceStructureID)
+	copy(buf[8:], inv.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForBuyerIDDaily save ID chain for BuyerID daily
+func (inv *Invoice) IndexIDForBuyerIDDaily() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   inv.hashBuyerIDForIDDaily(),
+		IndexValue: inv.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (inv *Invoice) hashBuyerIDForIDDaily() (hash [32]byte) {
+	const field = "BuyerID"
+	var buf = make([]byte, 48+len(field)) // 8+32+8
+	syllab.SetUInt64(buf, 0, invoiceStructureID)
+	copy(buf[8:], inv.BuyerID[:])
+	syllab.SetInt64(buf, 40, inv.WriteTime.RoundToDay())
+	copy(buf[48:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForSellerIDDaily save ID chain for SellerID daily
+func (inv *Invoice) IndexIDForSellerIDDaily() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   inv.hashSellerIDForIDDaily(),
+		IndexValue: inv.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (inv *Invoice) hashSellerIDForIDDaily() (hash [32]byte) {
+	const field = "SellerID"
+	var buf = make([]byte, 48+len(field)) // 8+32+8
+	syllab.SetUInt64(buf, 0, invoiceStructureID)
+	copy(buf[8:], inv.SellerID[:])
+	syllab.SetInt64(buf, 40, inv.WriteTime.RoundToDay())
+	copy(buf[48:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForDCID save ID chain for each DCID of invoice lines. Each DC index once even if has many lines.
+func (inv *Invoice) IndexIDForDCID() {
+	var indexed = make(map[[32]byte]bool, len(inv.Lines))
+	for _, line := range inv.Lines {
+		if line.DCID == [32]byte{} || indexed[line.DCID] {
+			continue
+		}
+		indexed[line.DCID] = true
+
+		var indexRequest = pehrest.HashSetValueReq{
+			Type:       gs.RequestTypeBroadcast,
+			IndexKey:   hashDCIDForInvoiceID(line.DCID),
+			IndexValue: inv.ID,
+		}
+		var err = psdk.HashSetValue(&indexRequest)
+		if err != nil {
+			// TODO::: we must retry more due to record wrote successfully!
+		}
+	}
+}
+
+func hashDCIDForInvoiceID(dcID [32]byte) (hash [32]byte) {
+	const field = "DCID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, invoiceStructureID)
+	copy(buf[8:], dcID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+// invoiceLineSyllabLen is fixed size of each line in heap.
+const invoiceLineSyllabLen = 208
+
+func (inv *Invoice) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < inv.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(inv.RecordID[:], buf[0:])
+	inv.RecordStructureID = syllab.GetUInt64(buf, 32)
+	inv.RecordSize = syllab.GetUInt64(buf, 40)
+	inv.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(inv.OwnerAppID[:], buf[56:])
+
+	copy(inv.AppInstanceID[:], buf[88:])
+	copy(inv.UserConnectionID[:], buf[120:])
+	copy(inv.ID[:], buf[152:])
+	copy(inv.BuyerID[:], buf[184:])
+	copy(inv.SellerID[:], buf[216:])
+	inv.Price = price.Amount(syllab.GetInt64(buf, 248))
+	inv.Discount = price.Amount(syllab.GetInt64(buf, 256))
+	inv.DCCommission = price.Amount(syllab.GetInt64(buf, 264))
+	inv.SellerCommission = price.Amount(syllab.GetInt64(buf, 272))
+	inv.Tax = price.Amount(syllab.GetInt64(buf, 280))
+	inv.Payable = price.Amount(syllab.GetInt64(buf, 288))
+	inv.Status = InvoiceStatus(syllab.GetUInt8(buf, 296))
+	inv.SMSStatus = InvoiceReceiptStatus(syllab.GetUInt8(buf, 297))
+	inv.EmailStatus = InvoiceReceiptStatus(syllab.GetUInt8(buf, 298))
+	inv.SMSID = syllab.GetUInt64(buf, 299)
+
+	var add = syllab.GetUInt32(buf, 307)
+	var ln = syllab.GetUInt32(buf, 311)
+	if uint64(add)+uint64(ln)*invoiceLineSyllabLen > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+	inv.Lines = make([]InvoiceLine, ln)
+	for i := range inv.Lines {
+		var line = &inv.Lines[i]
+		copy(line.ProductID[:], buf[add:])
+		copy(line.QuiddityID[:], buf[add+32:])
+		copy(line.ProductAuctionID[:], buf[add+64:])
+		copy(line.DCID[:], buf[add+96:])
+		copy(line.TransactionID[:], buf[add+128:])
+		line.Price = price.Amount(syllab.GetInt64(buf, add+160))
+		line.Discount = price.Amount(syllab.GetInt64(buf, add+168))
+		line.DCCommission = price.Amount(syllab.GetInt64(buf, add+176))
+		line.SellerCommission = price.Amount(syllab.GetInt64(buf, add+184))
+		line.Tax = price.Amount(syllab.GetInt64(buf, add+192))
+		line.Payable = price.Amount(syllab.GetInt64(buf, add+200))
+		add += invoiceLineSyllabLen
+	}
+	return
+}
+
+func (inv *Invoice) syllabEncoder() (buf []byte) {
+	buf = make([]byte, inv.syllabLen())
+	var hsi uint32 = inv.syllabStackLen() // Heap start index || Stack size!
+
+	// copy(buf[0:], inv.RecordID[:])
+	syllab.SetUInt64(buf, 32, inv.RecordStructureID)
+	syllab.SetUInt64(buf, 40, inv.RecordSize)
+	syllab.SetInt64(buf, 48, int64(inv.WriteTime))
+	copy(buf[56:], inv.OwnerAppID[:])
+
+	copy(buf[88:], inv.AppInstanceID[:])
+	copy(buf[120:], inv.UserConnectionID[:])
+	copy(buf[152:], inv.ID[:])
+	copy(buf[184:], inv.BuyerID[:])
+	copy(buf[216:], inv.SellerID[:])
+	syllab.SetInt64(buf, 248, int64(inv.Price))
+	syllab.SetInt64(buf, 256, int64(inv.Discount))
+	syllab.SetInt64(buf, 264, int64(inv.DCCommission))
+	syllab.SetInt64(buf, 272, int64(inv.SellerCommission))
+	syllab.SetInt64(buf, 280, int64(inv.Tax))
+	syllab.SetInt64(buf, 288, int64(inv.Payable))
+	syllab.SetUInt8(buf, 296, uint8(inv.Status))
+	syllab.SetUInt8(buf, 297, uint8(inv.SMSStatus))
+	syllab.SetUInt8(buf, 298, uint8(inv.EmailStatus))
+	syllab.SetUInt64(buf, 299, inv.SMSID)
+
+	syllab.SetUInt32(buf, 307, hsi)
+	syllab.SetUInt32(buf, 311, uint32(len(inv.Lines)))
+	for _, line := range inv.Lines {
+		copy(buf[hsi:], line.ProductID[:])
+		copy(buf[hsi+32:], line.QuiddityID[:])
+		copy(buf[hsi+64:], line.ProductAuctionID[:])
+		copy(buf[hsi+96:], line.DCID[:])
+		copy(buf[hsi+128:], line.TransactionID[:])
+		syllab.SetInt64(buf, hsi+160, int64(line.Price))
+		syllab.SetInt64(buf, hsi+168, int64(line.Discount))
+		syllab.SetInt64(buf, hsi+176, int64(line.DCCommission))
+		syllab.SetInt64(buf, hsi+184, int64(line.SellerCommission))
+		syllab.SetInt64(buf, hsi+192, int64(line.Tax))
+		syllab.SetInt64(buf, hsi+200, int64(line.Payable))
+		hsi += invoiceLineSyllabLen
+	}
+	return
+}
+
+func (inv *Invoice) syllabStackLen() (ln uint32) {
+	return 315
+}
+
+func (inv *Invoice) syllabHeapLen() (ln uint32) {
+	ln = uint32(len(inv.Lines)) * invoiceLineSyllabLen
+	return
+}
+
+func (inv *Invoice) syllabLen() (ln uint64) {
+	return uint64(inv.syllabStackLen() + inv.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// InvoiceStatus indicate Invoice record status
+type InvoiceStatus uint8
+
+// Invoice status
+const (
+	InvoiceUnset InvoiceStatus = iota
+	InvoiceRegistered
+)
+
+// InvoiceReceiptStatus indicate send status of an invoice receipt to the buyer
+type InvoiceReceiptStatus uint8
+
+// Invoice receipt status
+const (
+	InvoiceReceiptUnset InvoiceReceiptStatus = iota // Not send yet
+	InvoiceReceiptSent
+	InvoiceReceiptFailed
+	InvoiceReceiptNoContact // Buyer has no active contact for the receipt
+)
@@ -0,0 +1,223 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/syllab"
+)
+
+const (
+	productStockReorderStructureID uint64 = 5062883705523226437
+)
+
+var productStockReorderStructure = ganjine.DataStructure{
+	ID:                5062883705523226437,
+	IssueDate:         1792295957,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         ProductStockReorder{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Product Stock Reorder",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store reorder level of a quiddity stock in a DC or owned by an org.
+Keep apart from ProductStock so setting it never race with stock counters.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// ProductStockReorder ---Read locale description in productStockReorderStructure---
+type ProductStockReorder struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	QuiddityID       [32]byte `index-hash:"RecordID[pair,ScopeID,Scope]"`
+	ScopeID          [32]byte // DCID or OrgID base on Scope
+	Scope            ProductStockScope
+	ReorderLevel     int64 // Notify scope owner when OnHand drop below this number. Zero means no notify!
+}
+
+// SaveNew method set some data and write entire ProductStockReorder record with all indexes!
+func (psr *ProductStockReorder) SaveNew() (err *er.Error) {
+	err = psr.Set()
+	if err != nil {
+		return
+	}
+
+	psr.IndexRecordIDForQuiddityIDScopeID()
+	return
+}
+
+// Set method set some data and write entire ProductStockReorder record!
+func (psr *ProductStockReorder) Set() (err *er.Error) {
+	psr.RecordStructureID = productStockReorderStructureID
+	psr.RecordSize = psr.syllabLen()
+	psr.WriteTime = etime.Now()
+	psr.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: psr.syllabEncoder(),
+	}
+	psr.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], psr.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (psr *ProductStockReorder) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          psr.RecordID,
+		RecordStructureID: productStockReorderStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = psr.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if psr.RecordStructureID != productStockReorderStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByQuiddityIDScopeID method find and read last version of record by given QuiddityID+Scope+ScopeID
+func (psr *ProductStockReorder) GetLastByQuiddityIDScopeID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: psr.hashQuiddityIDScopeIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	psr.RecordID = indexRes.IndexValues[0]
+	err = psr.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", productStockReorderStructureID)
+	}
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForQuiddityIDScopeID save RecordID chain for QuiddityID+Scope+ScopeID
+// Call in each update to the exiting record!
+func (psr *ProductStockReorder) IndexRecordIDForQuiddityIDScopeID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   psr.hashQuiddityIDScopeIDForRecordID(),
+		IndexValue: psr.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (psr *ProductStockReorder) hashQuiddityIDScopeIDForRecordID() (hash [32]byte) {
+	const field = "QuiddityIDScopeID"
+	var buf = make([]byte, 73+len(field)) // 8+32+32+1
+	syllab.SetUInt64(buf, 0, productStockReorderStructureID)
+	copy(buf[8:], psr.QuiddityID[:])
+	copy(buf[40:], psr.ScopeID[:])
+	syllab.SetUInt8(buf, 72, uint8(psr.Scope))
+	copy(buf[73:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (psr *ProductStockReorder) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < psr.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(psr.RecordID[:], buf[0:])
+	psr.RecordStructureID = syllab.GetUInt64(buf, 32)
+	psr.RecordSize = syllab.GetUInt64(buf, 40)
+	psr.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(psr.OwnerAppID[:], buf[56:])
+
+	copy(psr.AppInstanceID[:], buf[88:])
+	copy(psr.UserConnectionID[:], buf[120:])
+	copy(psr.QuiddityID[:], buf[152:])
+	copy(psr.ScopeID[:], buf[184:])
+	psr.Scope = ProductStockScope(syllab.GetUInt8(buf, 216))
+	psr.ReorderLevel = syllab.GetInt64(buf, 217)
+	return
+}
+
+func (psr *ProductStockReorder) syllabEncoder() (buf []byte) {
+	buf = make([]byte, psr.syllabLen())
+
+	// copy(buf[0:], psr.RecordID[:])
+	syllab.SetUInt64(buf, 32, psr.RecordStructureID)
+	syllab.SetUInt64(buf, 40, psr.RecordSize)
+	syllab.SetInt64(buf, 48, int64(psr.WriteTime))
+	copy(buf[56:], psr.OwnerAppID[:])
+
+	copy(buf[88:], psr.AppInstanceID[:])
+	copy(buf[120:], psr.UserConnectionID[:])
+	copy(buf[152:], psr.QuiddityID[:])
+	copy(buf[184:], psr.ScopeID[:])
+	syllab.SetUInt8(buf, 216, uint8(psr.Scope))
+	syllab.SetInt64(buf, 217, psr.ReorderLevel)
+	return
+}
+
+func (psr *ProductStockReorder) syllabStackLen() (ln uint32) {
+	return 225
+}
+
+func (psr *ProductStockReorder) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (psr *ProductStockReorder) syllabLen() (ln uint64) {
+	return uint64(psr.syllabStackLen() + psr.syllabHeapLen())
+}
@@ -0,0 +1,401 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/syllab"
+)
+
+const (
+	productStockStructureID uint64 = 13548872300185924671
+)
+
+var productStockStructure = ganjine.DataStructure{
+	ID:                13548872300185924671,
+	IssueDate:         1792295957,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         ProductStock{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Product Stock",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store live number of products of a quiddity in a DC or owned by an org.
+Measurable products count by their quantity in quiddity base unit.
+Each product save append a delta to related stocks and records are just snapshots of the deltas sum.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// ProductStock ---Read locale description in productStockStructure---
+type ProductStock struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	QuiddityID       [32]byte `index-hash:"RecordID[pair,ScopeID,Scope]"`
+	ScopeID          [32]byte `index-hash:"QuiddityID[pair,Scope]"` // DCID or OrgID base on Scope
+	Scope            ProductStockScope
+	OnHand           int64
+	InTransit        int64 // Dispatched from the DC and not acknowledged by receiving DC yet
+	PreSale          int64
+	DeltaCount       uint64 // Number of deltas that sum in this snapshot. Stock is this snapshot plus deltas after it
+}
+
+/*
+	Counters of each stock change by a delta list in pehrest that only accept append, so concurrent product saves
+	in the cluster never lose an update. ProductStock records are snapshots of sum of first DeltaCount deltas.
+	Delta layout: [0:8] OnHand, [8:16] InTransit, [16:24] PreSale, [24:32] random token to keep same deltas distinct.
+*/
+
+// MakeProductStockDelta return a delta that add given numbers to the stock counters.
+func MakeProductStockDelta(onHand, inTransit, preSale int64) (delta [32]byte) {
+	syllab.SetInt64(delta[:], 0, onHand)
+	syllab.SetInt64(delta[:], 8, inTransit)
+	syllab.SetInt64(delta[:], 16, preSale)
+	rand.Read(delta[24:])
+	return
+}
+
+// AddDelta add given delta to the stock counters and count it in DeltaCount.
+func (ps *ProductStock) AddDelta(delta [32]byte) {
+	ps.OnHand += syllab.GetInt64(delta[:], 0)
+	ps.InTransit += syllab.GetInt64(delta[:], 8)
+	ps.PreSale += syllab.GetInt64(delta[:], 16)
+	ps.DeltaCount++
+}
+
+// SaveNew method set some data and write entire ProductStock record with all indexes!
+func (ps *ProductStock) SaveNew() (err *er.Error) {
+	err = ps.Set()
+	if err != nil {
+		return
+	}
+
+	ps.IndexRecordIDForQuiddityIDScopeID()
+	ps.ListQuiddityIDForScopeID()
+	ps.ListScopeIDForQuiddityID()
+	return
+}
+
+// Set method set some data and write entire ProductStock record!
+func (ps *ProductStock) Set() (err *er.Error) {
+	ps.RecordStructureID = productStockStructureID
+	ps.RecordSize = ps.syllabLen()
+	ps.WriteTime = etime.Now()
+	ps.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: ps.syllabEncoder(),
+	}
+	ps.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], ps.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (ps *ProductStock) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          ps.RecordID,
+		RecordStructureID: productStockStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = ps.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if ps.RecordStructureID != productStockStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByQuiddityIDScopeID method find and read last version of record by given QuiddityID+Scope+ScopeID
+func (ps *ProductStock) GetLastByQuiddityIDScopeID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ps.hashQuiddityIDScopeIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	ps.RecordID = indexRes.IndexValues[0]
+	err = ps.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", productStockStructureID)
+	}
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindDeltasByQuiddityIDScopeID find deltas of the stock of QuiddityID+Scope+ScopeID in append order
+func (ps *ProductStock) FindDeltasByQuiddityIDScopeID(offset, limit uint64) (deltas [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ps.hashQuiddityIDScopeIDForDelta(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	deltas = indexRes.IndexValues
+	return
+}
+
+// FindQuiddityIDsByScopeID find QuiddityIDs that has stock in given Scope+ScopeID
+func (ps *ProductStock) FindQuiddityIDsByScopeID(offset, limit uint64) (quiddityIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ps.hashScopeIDForQuiddityID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	quiddityIDs = indexRes.IndexValues
+	return
+}
+
+// FindScopeIDsByQuiddityID find ScopeIDs in given Scope that has stock of QuiddityID. It can has duplicate ScopeIDs!
+func (ps *ProductStock) FindScopeIDsByQuiddityID(offset, limit uint64) (scopeIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: ps.hashQuiddityIDForScopeID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	scopeIDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForQuiddityIDScopeID save RecordID chain for QuiddityID+Scope+ScopeID
+// Call in each update to the exiting record!
+func (ps *ProductStock) IndexRecordIDForQuiddityIDScopeID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ps.hashQuiddityIDScopeIDForRecordID(),
+		IndexValue: ps.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ps *ProductStock) hashQuiddityIDScopeIDForRecordID() (hash [32]byte) {
+	// Counter records that wrote before delta chain has no DeltaCount, so snapshots chain in other field to never read them.
+	const field = "QuiddityIDScopeIDSnapshot"
+	var buf = make([]byte, 73+len(field)) // 8+32+32+1
+	syllab.SetUInt64(buf, 0, productStockStructureID)
+	copy(buf[8:], ps.QuiddityID[:])
+	copy(buf[40:], ps.ScopeID[:])
+	syllab.SetUInt8(buf, 72, uint8(ps.Scope))
+	copy(buf[73:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexDeltaForQuiddityIDScopeID append a delta made by MakeProductStockDelta to the stock of QuiddityID+Scope+ScopeID
+// Call in each product save that change the stock!
+func (ps *ProductStock) IndexDeltaForQuiddityIDScopeID(delta [32]byte) (err *er.Error) {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ps.hashQuiddityIDScopeIDForDelta(),
+		IndexValue: delta,
+	}
+	err = psdk.HashSetValue(&indexRequest)
+	return
+}
+
+func (ps *ProductStock) hashQuiddityIDScopeIDForDelta() (hash [32]byte) {
+	const field = "QuiddityIDScopeIDDelta"
+	var buf = make([]byte, 73+len(field)) // 8+32+32+1
+	syllab.SetUInt64(buf, 0, productStockStructureID)
+	copy(buf[8:], ps.QuiddityID[:])
+	copy(buf[40:], ps.ScopeID[:])
+	syllab.SetUInt8(buf, 72, uint8(ps.Scope))
+	copy(buf[73:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- LIST FIELDS --
+*/
+
+// ListQuiddityIDForScopeID save QuiddityID chain for Scope+ScopeID
+// Don't call in update to an exiting record!
+func (ps *ProductStock) ListQuiddityIDForScopeID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ps.hashScopeIDForQuiddityID(),
+		IndexValue: ps.QuiddityID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ps *ProductStock) hashScopeIDForQuiddityID() (hash [32]byte) {
+	const field = "ListScopeID"
+	var buf = make([]byte, 41+len(field)) // 8+32+1
+	syllab.SetUInt64(buf, 0, productStockStructureID)
+	copy(buf[8:], ps.ScopeID[:])
+	syllab.SetUInt8(buf, 40, uint8(ps.Scope))
+	copy(buf[41:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// ListScopeIDForQuiddityID save ScopeID chain for QuiddityID+Scope
+// Don't call in update to an exiting record!
+func (ps *ProductStock) ListScopeIDForQuiddityID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   ps.hashQuiddityIDForScopeID(),
+		IndexValue: ps.ScopeID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (ps *ProductStock) hashQuiddityIDForScopeID() (hash [32]byte) {
+	const field = "ListQuiddityID"
+	var buf = make([]byte, 41+len(field)) // 8+32+1
+	syllab.SetUInt64(buf, 0, productStockStructureID)
+	copy(buf[8:], ps.QuiddityID[:])
+	syllab.SetUInt8(buf, 40, uint8(ps.Scope))
+	copy(buf[41:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (ps *ProductStock) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < ps.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(ps.RecordID[:], buf[0:])
+	ps.RecordStructureID = syllab.GetUInt64(buf, 32)
+	ps.RecordSize = syllab.GetUInt64(buf, 40)
+	ps.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(ps.OwnerAppID[:], buf[56:])
+
+	copy(ps.AppInstanceID[:], buf[88:])
+	copy(ps.UserConnectionID[:], buf[120:])
+	copy(ps.QuiddityID[:], buf[152:])
+	copy(ps.ScopeID[:], buf[184:])
+	ps.Scope = ProductStockScope(syllab.GetUInt8(buf, 216))
+	ps.OnHand = syllab.GetInt64(buf, 217)
+	ps.InTransit = syllab.GetInt64(buf, 225)
+	ps.PreSale = syllab.GetInt64(buf, 233)
+	ps.DeltaCount = syllab.GetUInt64(buf, 241)
+	return
+}
+
+func (ps *ProductStock) syllabEncoder() (buf []byte) {
+	buf = make([]byte, ps.syllabLen())
+
+	// copy(buf[0:], ps.RecordID[:])
+	syllab.SetUInt64(buf, 32, ps.RecordStructureID)
+	syllab.SetUInt64(buf, 40, ps.RecordSize)
+	syllab.SetInt64(buf, 48, int64(ps.WriteTime))
+	copy(buf[56:], ps.OwnerAppID[:])
+
+	copy(buf[88:], ps.AppInstanceID[:])
+	copy(buf[120:], ps.UserConnectionID[:])
+	copy(buf[152:], ps.QuiddityID[:])
+	copy(buf[184:], ps.ScopeID[:])
+	syllab.SetUInt8(buf, 216, uint8(ps.Scope))
+	syllab.SetInt64(buf, 217, ps.OnHand)
+	syllab.SetInt64(buf, 225, ps.InTransit)
+	syllab.SetInt64(buf, 233, ps.PreSale)
+	syllab.SetUInt64(buf, 241, ps.DeltaCount)
+	return
+}
+
+func (ps *ProductStock) syllabStackLen() (ln uint32) {
+	return 249
+}
+
+func (ps *ProductStock) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (ps *ProductStock) syllabLen() (ln uint64) {
+	return uint64(ps.syllabStackLen() + ps.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// ProductStockScope indicate ProductStock ScopeID type
+type ProductStockScope uint8
+
+// ProductStock scopes
+const (
+	ProductStockUnset ProductStockScope = iota
+	ProductStockDC                      // Products that are in the DC
+	ProductStockOrg                     // Products that owned by the org
+)
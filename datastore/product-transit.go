@@ -0,0 +1,347 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package datastore
+
+import (
+	"crypto/sha512"
+
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	gsdk "../libgo/ganjine-sdk"
+	gs "../libgo/ganjine-services"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/pehrest"
+	psdk "../libgo/pehrest-sdk"
+	"../libgo/syllab"
+)
+
+const (
+	productTransitStructureID uint64 = 4715282913870045529
+)
+
+var productTransitStructure = ganjine.DataStructure{
+	ID:                4715282913870045529,
+	IssueDate:         1792295638,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // Other structure name
+	ExpireInFavorOfID: 0,  // Other StructureID! Handy ID or Hash of ExpireInFavorOf!
+	Status:            ganjine.DataStructureStatePreAlpha,
+	Structure:         ProductTransit{},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Product Transit",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `store a product move between two distribution centers.
+Sending DC dispatch the product and receiving DC acknowledge or reject it.`,
+	},
+	TAGS: []string{
+		"",
+	},
+}
+
+// ProductTransit ---Read locale description in productTransitStructure---
+type ProductTransit struct {
+	/* Common header data */
+	RecordID          [32]byte
+	RecordStructureID uint64
+	RecordSize        uint64
+	WriteTime         etime.Time
+	OwnerAppID        [32]byte
+
+	/* Unique data */
+	AppInstanceID    [32]byte // Store to remember which app instance set||chanaged this record!
+	UserConnectionID [32]byte // Store to remember which user connection set||chanaged this record!
+	ID               [32]byte `index-hash:"RecordID"`
+	ProductID        [32]byte `index-hash:"ID"`
+	FromDCID         [32]byte // Sending DistributionCenterID
+	ToDCID           [32]byte `index-hash:"ID"` // Receiving DistributionCenterID
+	Status           ProductTransitStatus
+	ProductStatus    ProductStatus // Product status before dispatch to restore it when receiving DC acknowledge
+}
+
+// SaveNew method set some data and write entire ProductTransit record with all indexes!
+func (pt *ProductTransit) SaveNew() (err *er.Error) {
+	err = pt.Set()
+	if err != nil {
+		return
+	}
+
+	pt.IndexRecordIDForID()
+	pt.IndexIDForProductID()
+	pt.IndexIDForToDCID()
+	return
+}
+
+// Set method set some data and write entire ProductTransit record!
+func (pt *ProductTransit) Set() (err *er.Error) {
+	pt.RecordStructureID = productTransitStructureID
+	pt.RecordSize = pt.syllabLen()
+	pt.WriteTime = etime.Now()
+	pt.OwnerAppID = achaemenid.Server.AppID
+
+	var req = gs.SetRecordReq{
+		Type:   gs.RequestTypeBroadcast,
+		Record: pt.syllabEncoder(),
+	}
+	pt.RecordID = sha512.Sum512_256(req.Record[32:])
+	copy(req.Record[0:], pt.RecordID[:])
+
+	err = gsdk.SetRecord(&req)
+	if err != nil {
+		// TODO::: Handle error situation
+	}
+
+	return
+}
+
+// GetByRecordID method read all existing record data by given RecordID!
+func (pt *ProductTransit) GetByRecordID() (err *er.Error) {
+	var req = gs.GetRecordReq{
+		RecordID:          pt.RecordID,
+		RecordStructureID: productTransitStructureID,
+	}
+	var res *gs.GetRecordRes
+	res, err = gsdk.GetRecord(&req)
+	if err != nil {
+		return
+	}
+
+	err = pt.syllabDecoder(res.Record)
+	if err != nil {
+		return
+	}
+
+	if pt.RecordStructureID != productTransitStructureID {
+		err = ganjine.ErrMisMatchedStructureID
+	}
+	return
+}
+
+// GetLastByID method find and read last version of record by given ID
+func (pt *ProductTransit) GetLastByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: pt.hashIDForRecordID(),
+		Offset:   18446744073709551615,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	pt.RecordID = indexRes.IndexValues[0]
+	err = pt.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", productTransitStructureID)
+	}
+	return
+}
+
+// GetLastByProductID method find and read last version of last transit of given ProductID
+func (pt *ProductTransit) GetLastByProductID() (err *er.Error) {
+	var IDs [][32]byte
+	IDs, err = pt.FindIDsByProductID(18446744073709551615, 1)
+	if err != nil {
+		return
+	}
+
+	pt.ID = IDs[0]
+	err = pt.GetLastByID()
+	return
+}
+
+/*
+	-- Search Methods --
+*/
+
+// FindIDsByProductID find IDs by given ProductID
+func (pt *ProductTransit) FindIDsByProductID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: pt.hashProductIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+// FindIDsByToDCID find IDs by given ToDCID
+func (pt *ProductTransit) FindIDsByToDCID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: pt.hashToDCIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
+/*
+	-- PRIMARY INDEXES --
+*/
+
+// IndexRecordIDForID save RecordID chain for ID
+// Call in each update to the exiting record!
+func (pt *ProductTransit) IndexRecordIDForID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   pt.hashIDForRecordID(),
+		IndexValue: pt.RecordID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (pt *ProductTransit) hashIDForRecordID() (hash [32]byte) {
+	const field = "ID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, productTransitStructureID)
+	copy(buf[8:], pt.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- SECONDARY INDEXES --
+*/
+
+// IndexIDForProductID save ID chain for ProductID.
+// Don't call in update to an exiting record!
+func (pt *ProductTransit) IndexIDForProductID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   pt.hashProductIDForID(),
+		IndexValue: pt.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (pt *ProductTransit) hashProductIDForID() (hash [32]byte) {
+	const field = "ProductID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, productTransitStructureID)
+	copy(buf[8:], pt.ProductID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+// IndexIDForToDCID save ID chain for ToDCID.
+// Use by receiving DC to find incoming products!
+// Don't call in update to an exiting record!
+func (pt *ProductTransit) IndexIDForToDCID() {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   pt.hashToDCIDForID(),
+		IndexValue: pt.ID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+func (pt *ProductTransit) hashToDCIDForID() (hash [32]byte) {
+	const field = "ToDCID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, productTransitStructureID)
+	copy(buf[8:], pt.ToDCID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
+/*
+	-- Syllab Encoder & Decoder --
+*/
+
+func (pt *ProductTransit) syllabDecoder(buf []byte) (err *er.Error) {
+	if len(buf) < 281 {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(pt.RecordID[:], buf[0:])
+	pt.RecordStructureID = syllab.GetUInt64(buf, 32)
+	pt.RecordSize = syllab.GetUInt64(buf, 40)
+	pt.WriteTime = etime.Time(syllab.GetInt64(buf, 48))
+	copy(pt.OwnerAppID[:], buf[56:])
+
+	copy(pt.AppInstanceID[:], buf[88:])
+	copy(pt.UserConnectionID[:], buf[120:])
+	copy(pt.ID[:], buf[152:])
+	copy(pt.ProductID[:], buf[184:])
+	copy(pt.FromDCID[:], buf[216:])
+	copy(pt.ToDCID[:], buf[248:])
+	pt.Status = ProductTransitStatus(syllab.GetUInt8(buf, 280))
+	// Records written before product status has no status to restore.
+	if len(buf) > 281 {
+		pt.ProductStatus = ProductStatus(syllab.GetUInt8(buf, 281))
+	}
+	return
+}
+
+func (pt *ProductTransit) syllabEncoder() (buf []byte) {
+	buf = make([]byte, pt.syllabLen())
+
+	// copy(buf[0:], pt.RecordID[:])
+	syllab.SetUInt64(buf, 32, pt.RecordStructureID)
+	syllab.SetUInt64(buf, 40, pt.RecordSize)
+	syllab.SetInt64(buf, 48, int64(pt.WriteTime))
+	copy(buf[56:], pt.OwnerAppID[:])
+
+	copy(buf[88:], pt.AppInstanceID[:])
+	copy(buf[120:], pt.UserConnectionID[:])
+	copy(buf[152:], pt.ID[:])
+	copy(buf[184:], pt.ProductID[:])
+	copy(buf[216:], pt.FromDCID[:])
+	copy(buf[248:], pt.ToDCID[:])
+	syllab.SetUInt8(buf, 280, uint8(pt.Status))
+	syllab.SetUInt8(buf, 281, uint8(pt.ProductStatus))
+	return
+}
+
+func (pt *ProductTransit) syllabStackLen() (ln uint32) {
+	return 282
+}
+
+func (pt *ProductTransit) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (pt *ProductTransit) syllabLen() (ln uint64) {
+	return uint64(pt.syllabStackLen() + pt.syllabHeapLen())
+}
+
+/*
+	-- Record types --
+*/
+
+// ProductTransitStatus indicate ProductTransit record status
+type ProductTransitStatus uint8
+
+// ProductTransit status
+const (
+	ProductTransitUnset      ProductTransitStatus = iota
+	ProductTransitDispatched                      // Sending DC dispatch the product and it is in transit
+	ProductTransitReceived                        // Receiving DC acknowledge the product
+	ProductTransitRejected                        // Receiving DC reject the product and it back to sending DC stock
+)
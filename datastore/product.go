@@ -59,10 +59,11 @@ type Product struct {
 	QuiddityID       [32]byte `index-hash:"ID[daily],ID[pair,DCID,daily],ID[pair,DCID,temp],DCID[temp]"`
 
 	SellerID         [32]byte `index-hash:"ID[daily]"` // OrdererID, who places the order usually use for prescription(drug order) or sales agent!
-	ProductionID     [32]byte // It can also upper ID that this product split from it!
+	ProductionID     [32]byte // It can also upper ID that this product split from it! or product that it merged to it in void version!
 	DCID             [32]byte `index-hash:"QuiddityID"` // DistributionCenterID
 	ProductAuctionID [32]byte `index-hash:"ID"`         // can be 0 for just change owner without any auction or price but very rare situation!
 	Status           ProductStatus
+	Quantity         uint64 // Amount of quiddity base unit in the product e.g. gram. Zero means product is not measurable!
 }
 
 // SaveNew method set some data and write entire Product record with all indexes!
@@ -153,6 +154,26 @@ func (p *Product) GetLastByID() (err *er.Error) {
 	return
 }
 
+/*
+	-- Search Methods --
+*/
+
+// FindRecordIDsByID find all versions RecordIDs of given ID in write order.
+func (p *Product) FindRecordIDsByID(offset, limit uint64) (RecordIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: p.hashIDForRecordID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	RecordIDs = indexRes.IndexValues
+	return
+}
+
 /*
 	-- PRIMARY INDEXES --
 */
@@ -230,6 +251,22 @@ func (p *Product) hashQuiddityIDForIDDaily() (hash [32]byte) {
 	return sha512.Sum512_256(buf)
 }
 
+// FindIDsByQuiddityIDDaily find IDs of QuiddityID products that made in given WriteTime(round to daily).
+func (p *Product) FindIDsByQuiddityIDDaily(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: p.hashQuiddityIDForIDDaily(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
 // IndexIDForQuiddityIDDCIDDaily save ID chain for QuiddityID+DCID
 func (p *Product) IndexIDForQuiddityIDDCIDDaily() {
 	var indexRequest = pehrest.HashSetValueReq{
@@ -277,6 +314,45 @@ func (p *Product) hashSellerIDForIDDaily() (hash [32]byte) {
 	return sha512.Sum512_256(buf)
 }
 
+// IndexParentIDForID save parent product IDs chain of a merged product ID.
+// ProductionID of a merged product can just refer to one parent, so call it for each merged parent!
+func (p *Product) IndexParentIDForID(parentID [32]byte) {
+	var indexRequest = pehrest.HashSetValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   p.hashIDForParentID(),
+		IndexValue: parentID,
+	}
+	var err = psdk.HashSetValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+// FindParentIDsByID find parent product IDs that merged to given product ID.
+func (p *Product) FindParentIDsByID(offset, limit uint64) (parentIDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: p.hashIDForParentID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	parentIDs = indexRes.IndexValues
+	return
+}
+
+func (p *Product) hashIDForParentID() (hash [32]byte) {
+	const field = "ParentID"
+	var buf = make([]byte, 40+len(field)) // 8+32
+	syllab.SetUInt64(buf, 0, productStructureID)
+	copy(buf[8:], p.ID[:])
+	copy(buf[40:], field)
+	return sha512.Sum512_256(buf)
+}
+
 // IndexIDForProductAuctionID save ID chain for ProductAuctionID
 // Use to indiacate product sell by specific auction. it is better to remove this record each month or year!
 // Don't call in update to an exiting record!
@@ -346,6 +422,36 @@ func (p *Product) TempIndexIDForQuiddityIDDCID() {
 	}
 }
 
+// DeleteTempIndexIDForQuiddityIDDCID delete ID from temporary QuiddityID+DCID chain.
+// Call when product leave the DC stock e.g. dispatch to other DC!
+func (p *Product) DeleteTempIndexIDForQuiddityIDDCID() {
+	var indexRequest = pehrest.HashDeleteKeyValueReq{
+		Type:       gs.RequestTypeBroadcast,
+		IndexKey:   p.hashQuiddityIDDCIDForID(),
+		IndexValue: p.ID,
+	}
+	var err = psdk.HashDeleteKeyValue(&indexRequest)
+	if err != nil {
+		// TODO::: we must retry more due to record wrote successfully!
+	}
+}
+
+// FindIDsByQuiddityIDDCID find IDs of products of the quiddity that are in the DC stock now.
+func (p *Product) FindIDsByQuiddityIDDCID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: p.hashQuiddityIDDCIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	IDs = indexRes.IndexValues
+	return
+}
+
 func (p *Product) hashQuiddityIDDCIDForID() (hash [32]byte) {
 	const field = "TempQuiddityIDDCID"
 	var buf = make([]byte, 72+len(field)) // 8+32+32
@@ -386,7 +492,7 @@ func (p *Product) hashQuiddityIDForDCID() (hash [32]byte) {
 */
 
 func (p *Product) syllabDecoder(buf []byte) (err *er.Error) {
-	if uint32(len(buf)) < p.syllabStackLen() {
+	if len(buf) < 377 {
 		err = syllab.ErrSyllabDecodeSmallSlice
 		return
 	}
@@ -408,6 +514,10 @@ func (p *Product) syllabDecoder(buf []byte) (err *er.Error) {
 	copy(p.DCID[:], buf[312:])
 	copy(p.ProductAuctionID[:], buf[344:])
 	p.Status = ProductStatus(syllab.GetUInt8(buf, 376))
+	// Records written before product quantity are not measurable.
+	if len(buf) >= 385 {
+		p.Quantity = syllab.GetUInt64(buf, 377)
+	}
 	return
 }
 
@@ -431,11 +541,12 @@ func (p *Product) syllabEncoder() (buf []byte) {
 	copy(buf[312:], p.DCID[:])
 	copy(buf[344:], p.ProductAuctionID[:])
 	syllab.SetUInt8(buf, 376, uint8(p.Status))
+	syllab.SetUInt64(buf, 377, p.Quantity)
 	return
 }
 
 func (p *Product) syllabStackLen() (ln uint32) {
-	return 377
+	return 385
 }
 
 func (p *Product) syllabHeapLen() (ln uint32) {
@@ -461,7 +572,8 @@ const (
 	ProductChangeQuiddity // Split to small size product! || Split from upper size product!
 	ProductChangeOwner
 	ProductVoid
-	ProductPreSale // use in budget analysis and also can be trade!
+	ProductPreSale   // use in budget analysis and also can be trade!
+	ProductInTransit // Dispatched from DCID and wait to acknowledge by receiving DC. Read ProductTransit for more info!
 
 	// 0x0 for non expire record, 0x1 for sell to first above SuggestPrice||buy first below it!
 
@@ -64,6 +64,7 @@ type Quiddity struct {
 	URI      string `index-hash:"ID"` // Locale name in the Computer world!!	https://en.quidditypedia.org/quiddity/Uniform_Resource_Identifier && https://en.quidditypedia.org/quiddity/Uniform_Resource_Name && https://en.quidditypedia.org/quiddity/Electronic_Product_Code
 	Title    string `index-text:"ID"` // Locale name in the Human world!!		It can be not unique in all quiddity content.
 	Status   QuiddityStatus
+	Unit     QuiddityUnit // Base unit of measurable products quantity. Same in all languages of the quiddity!
 }
 
 // SaveNew method set some data and write entire Quiddity record with all indexes!
@@ -351,7 +352,7 @@ func (q *Quiddity) hashIDForLanguage() (hash [32]byte) {
 */
 
 func (q *Quiddity) syllabDecoder(buf []byte) (err *er.Error) {
-	if uint32(len(buf)) < q.syllabStackLen() {
+	if len(buf) < 237 {
 		err = syllab.ErrSyllabDecodeSmallSlice
 		return
 	}
@@ -371,6 +372,10 @@ func (q *Quiddity) syllabDecoder(buf []byte) (err *er.Error) {
 	q.URI = syllab.UnsafeGetString(buf, 220)
 	q.Title = syllab.UnsafeGetString(buf, 228)
 	q.Status = QuiddityStatus(syllab.GetUInt8(buf, 236))
+	// Records written before quiddity unit has its heap right after status and has no base unit.
+	if syllab.GetUInt32(buf, 220) >= 238 {
+		q.Unit = QuiddityUnit(syllab.GetUInt8(buf, 237))
+	}
 	return
 }
 
@@ -393,11 +398,12 @@ func (q *Quiddity) syllabEncoder() (buf []byte) {
 	hsi = syllab.SetString(buf, q.URI, 220, hsi)
 	hsi = syllab.SetString(buf, q.Title, 228, hsi)
 	syllab.SetUInt8(buf, 236, uint8(q.Status))
+	syllab.SetUInt8(buf, 237, uint8(q.Unit))
 	return
 }
 
 func (q *Quiddity) syllabStackLen() (ln uint32) {
-	return 237
+	return 238
 }
 
 func (q *Quiddity) syllabHeapLen() (ln uint32) {
@@ -424,3 +430,14 @@ const (
 	QuiddityStatusSuggestion
 	QuiddityStatusBlocked
 )
+
+// QuiddityUnit indicate base unit of a quiddity that products quantity count by it.
+type QuiddityUnit uint8
+
+// Quiddity units
+const (
+	QuiddityUnitUnset QuiddityUnit = iota // Products of the quiddity are not measurable
+	QuiddityUnitGram
+	QuiddityUnitMillilitre
+	QuiddityUnitMillimetre
+)
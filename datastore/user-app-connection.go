@@ -89,6 +89,7 @@ type UserAppConnection struct {
 	/* Security data */
 	PeerPublicKey [32]byte
 	AccessControl authorization.AccessControl
+	ExpireTime    etime.Time // Zero means connection never expire
 
 	// Metrics data
 	LastUsage             etime.Time // Last use of this connection
@@ -194,6 +195,27 @@ func (uac *UserAppConnection) GetLastByID() (err *er.Error) {
 	return
 }
 
+// GetFirstByID find and read first version of record by given ID e.g. to know when connection made
+func (uac *UserAppConnection) GetFirstByID() (err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: uac.hashIDForRecordID(),
+		Offset:   0,
+		Limit:    1,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+
+	uac.RecordID = indexRes.IndexValues[0]
+	err = uac.GetByRecordID()
+	if err.Equal(ganjine.ErrMisMatchedStructureID) {
+		log.Warn("Platform collapsed!! HASH Collision Occurred on", userAppConnectionStructureID)
+	}
+	return
+}
+
 // GetLastByUserIDThingID find and read last version of record by given UserID+ThingID
 func (uac *UserAppConnection) GetLastByUserIDThingID() (err *er.Error) {
 	var indexReq = &pehrest.HashGetValuesReq{
@@ -264,6 +286,21 @@ func (uac *UserAppConnection) FindIDsByGottenDelegate(offset, limit uint64) (IDs
 	return indexRes.IndexValues, nil
 }
 
+// FindIDsByUserIDDelegateUserID return IDs of connections that UserID gave to DelegateUserID.
+func (uac *UserAppConnection) FindIDsByUserIDDelegateUserID(offset, limit uint64) (IDs [][32]byte, err *er.Error) {
+	var indexReq = &pehrest.HashGetValuesReq{
+		IndexKey: uac.hashUserIDDelegateUserIDForID(),
+		Offset:   offset,
+		Limit:    limit,
+	}
+	var indexRes *pehrest.HashGetValuesRes
+	indexRes, err = psdk.HashGetValues(indexReq)
+	if err != nil {
+		return
+	}
+	return indexRes.IndexValues, nil
+}
+
 /*
 	-- PRIMARY INDEX --
 */
@@ -538,7 +575,8 @@ func (uac *UserAppConnection) hashUserIDForDelegateUserID() (hash [32]byte) {
 */
 
 func (uac *UserAppConnection) syllabDecoder(buf []byte) (err *er.Error) {
-	if uint32(len(buf)) < uac.syllabStackLen() {
+	// Records written before connection expire time has 428 stack size, so must accept them too.
+	if uint32(len(buf)) < 428+uac.AccessControl.SyllabStackLen() {
 		err = syllab.ErrSyllabDecodeSmallSlice
 		return
 	}
@@ -579,6 +617,10 @@ func (uac *UserAppConnection) syllabDecoder(buf []byte) (err *er.Error) {
 	uac.PacketsReceived = syllab.GetUInt64(buf, 404+uac.AccessControl.SyllabStackLen())
 	uac.FailedPacketsReceived = syllab.GetUInt64(buf, 412+uac.AccessControl.SyllabStackLen())
 	uac.FailedServiceCall = syllab.GetUInt64(buf, 420+uac.AccessControl.SyllabStackLen())
+	// Records written before connection expire time has description heap right after FailedServiceCall.
+	if syllab.GetUInt32(buf, 153) >= uac.syllabStackLen() {
+		uac.ExpireTime = etime.Time(syllab.GetInt64(buf, 428+uac.AccessControl.SyllabStackLen()))
+	}
 	return
 }
 
@@ -622,11 +664,12 @@ func (uac *UserAppConnection) syllabEncoder() (buf []byte) {
 	syllab.SetUInt64(buf, 404+uac.AccessControl.SyllabStackLen(), uac.PacketsReceived)
 	syllab.SetUInt64(buf, 412+uac.AccessControl.SyllabStackLen(), uac.FailedPacketsReceived)
 	syllab.SetUInt64(buf, 420+uac.AccessControl.SyllabStackLen(), uac.FailedServiceCall)
+	syllab.SetInt64(buf, 428+uac.AccessControl.SyllabStackLen(), int64(uac.ExpireTime))
 	return
 }
 
 func (uac *UserAppConnection) syllabStackLen() (ln uint32) {
-	return 428 + uac.AccessControl.SyllabStackLen()
+	return 436 + uac.AccessControl.SyllabStackLen()
 }
 
 func (uac *UserAppConnection) syllabHeapLen() (ln uint32) {
@@ -490,7 +490,6 @@ invoicePage.checkoutInvoice = async function () {
     const RegisterProductInvoiceReq = {
         "UserID": this.newInvoiceList.BuyerUserID,
         "UserOTP": Number(personOTPInput.value),
-        "UserTransactionID": localStorage.getItem('LastTransferPaymentIDInInvoicePage') || "",
         "SenderDCID": "cfiiAF6pxrG15E50WcmqWGOJj7eutxifP04LOwPIEEg", // localStorage.getItem('LastSenderDCIDInInvoicePage'),
         // "ReceiverDCID": localStorage.getItem('LastReceiverDCIDInInvoicePage'),
         "Language": users.active.ContentPreferences.Language.ID,
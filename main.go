@@ -80,6 +80,15 @@ func init() {
 		},
 	}
 
+	// Set local society settings that platform services need.
+	err = ps.InitSociety(ps.SocietyManifest{
+		TimeZone:         "Asia/Tehran",
+		CurrencyDecimals: 0, // IRR has no minor unit in use
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize server
 	server.Init()
 
@@ -122,6 +131,8 @@ func init() {
 	gs.Init(&server, &cluster)
 	// Initialize datastore
 	datastore.Init(&server, &cluster)
+	// Start platform background jobs e.g. finish half-done financial transfers
+	ps.StartJobs()
 
 	// Register some other services for Achaemenid
 	server.Connections.GetConnByID = getConnectionsByID
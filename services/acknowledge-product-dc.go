@@ -0,0 +1,271 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var acknowledgeProductDcService = achaemenid.Service{
+	ID:                2230961057,
+	IssueDate:         1792295638,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Acknowledge Product Dc",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `receiving DC acknowledge or reject a product that dispatched to it by UpdateProductDc service.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: AcknowledgeProductDcSRPC,
+	HTTPHandler: AcknowledgeProductDcHTTP,
+}
+
+// AcknowledgeProductDcSRPC is sRPC handler of AcknowledgeProductDc service.
+func AcknowledgeProductDcSRPC(st *achaemenid.Stream) {
+	var req = &acknowledgeProductDcReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *acknowledgeProductDcRes
+	res, st.Err = acknowledgeProductDc(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// AcknowledgeProductDcHTTP is HTTP handler of AcknowledgeProductDc service.
+func AcknowledgeProductDcHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &acknowledgeProductDcReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *acknowledgeProductDcRes
+	res, st.Err = acknowledgeProductDc(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type acknowledgeProductDcReq struct {
+	ProductID [32]byte `json:",string"`
+	Reject    bool     `json:",optional"` // Reject the product and back it to sending DC stock
+}
+
+type acknowledgeProductDcRes struct {
+}
+
+func acknowledgeProductDc(st *achaemenid.Stream, req *acknowledgeProductDcReq) (res *acknowledgeProductDcRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var pt = datastore.ProductTransit{
+		ProductID: req.ProductID,
+	}
+	err = pt.GetLastByProductID()
+	if err != nil {
+		return
+	}
+	// Just receiving DC can acknowledge the product.
+	if pt.ToDCID != st.Connection.UserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+	if pt.Status != datastore.ProductTransitDispatched {
+		err = ErrProductNotInTransit
+		return
+	}
+
+	var p = datastore.Product{
+		ID: req.ProductID,
+	}
+	err = p.GetLastByID()
+	if err != nil {
+		return
+	}
+	if p.Status != datastore.ProductInTransit {
+		err = ErrProductNotInTransit
+		return
+	}
+
+	pt.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	pt.UserConnectionID = st.Connection.ID
+	if req.Reject {
+		pt.Status = datastore.ProductTransitRejected
+	} else {
+		pt.Status = datastore.ProductTransitReceived
+	}
+	err = pt.Set()
+	if err != nil {
+		return
+	}
+	pt.IndexRecordIDForID()
+
+	var before = p
+	p.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	p.UserConnectionID = st.Connection.ID
+	var inStock = acknowledgeProductTransit(&p, &pt, req.Reject)
+	err = p.Set()
+	if err != nil {
+		return
+	}
+	p.IndexRecordIDForID()
+	if inStock {
+		p.TempIndexIDForQuiddityIDDCID()
+	}
+	if !req.Reject {
+		p.IndexIDForQuiddityIDDCIDDaily()
+		p.ListQuiddityIDForDCIDDaily()
+		if inStock {
+			p.TempIndexDCIDForQuiddityID()
+		}
+	}
+	moveProductStock(&before, &p)
+
+	res = &acknowledgeProductDcRes{}
+	return
+}
+
+// acknowledgeProductTransit set status and DC of the in transit product when receiving DC acknowledge or reject it and
+// report if the product back to a DC stock. Product keep its status before dispatch e.g. pre-sale or sold by an auction,
+// so a sold product that moved to a DC never count as the DC stock.
+func acknowledgeProductTransit(p *datastore.Product, pt *datastore.ProductTransit, reject bool) (inStock bool) {
+	p.Status = pt.ProductStatus
+	var bucket = getProductStockBucket(p)
+	inStock = bucket != productStockNone
+	if bucket == productStockOnHand {
+		p.Status = datastore.ProductChangeDC
+	}
+	if !reject {
+		p.DCID = pt.ToDCID
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *acknowledgeProductDcReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ProductID[:], buf[0:])
+	req.Reject = buf[32] == 1
+	return
+}
+
+func (req *acknowledgeProductDcReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ProductID[:])
+	if req.Reject {
+		buf[32] = 1
+	}
+	return
+}
+
+func (req *acknowledgeProductDcReq) syllabStackLen() (ln uint32) {
+	return 33
+}
+
+func (req *acknowledgeProductDcReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *acknowledgeProductDcReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *acknowledgeProductDcReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *acknowledgeProductDcReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *acknowledgeProductDcReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *acknowledgeProductDcRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *acknowledgeProductDcRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *acknowledgeProductDcRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *acknowledgeProductDcRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *acknowledgeProductDcRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *acknowledgeProductDcRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *acknowledgeProductDcRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *acknowledgeProductDcRes) jsonLen() (ln int) {
+	return
+}
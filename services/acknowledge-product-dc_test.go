@@ -0,0 +1,56 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../datastore"
+)
+
+func TestAcknowledgeProductTransit(t *testing.T) {
+	var fromDC, toDC = [32]byte{1}, [32]byte{2}
+	var tests = []struct {
+		name          string
+		productStatus datastore.ProductStatus // Product status before dispatch
+		auctionID     [32]byte
+		reject        bool
+		wantStatus    datastore.ProductStatus
+		wantDC        [32]byte
+		wantInStock   bool
+	}{
+		{"acknowledge stock", datastore.ProductCreated, [32]byte{}, false, datastore.ProductChangeDC, toDC, true},
+		{"reject stock", datastore.ProductChangeDC, [32]byte{}, true, datastore.ProductChangeDC, fromDC, true},
+		{"acknowledge transit written before product status", datastore.ProductNotSet, [32]byte{}, false, datastore.ProductChangeDC, toDC, true},
+		{"acknowledge pre-sale", datastore.ProductPreSale, [32]byte{}, false, datastore.ProductPreSale, toDC, true},
+		{"reject pre-sale", datastore.ProductPreSale, [32]byte{}, true, datastore.ProductPreSale, fromDC, true},
+		{"acknowledge owner changed without auction", datastore.ProductChangeOwner, [32]byte{}, false, datastore.ProductChangeDC, toDC, true},
+		{"acknowledge sold", datastore.ProductChangeOwner, [32]byte{3}, false, datastore.ProductChangeOwner, toDC, false},
+		{"reject sold", datastore.ProductChangeOwner, [32]byte{3}, true, datastore.ProductChangeOwner, fromDC, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p = datastore.Product{
+				DCID:             fromDC,
+				ProductAuctionID: tt.auctionID,
+				Status:           datastore.ProductInTransit,
+			}
+			var pt = datastore.ProductTransit{
+				FromDCID:      fromDC,
+				ToDCID:        toDC,
+				Status:        datastore.ProductTransitDispatched,
+				ProductStatus: tt.productStatus,
+			}
+			var inStock = acknowledgeProductTransit(&p, &pt, tt.reject)
+			if p.Status != tt.wantStatus || p.DCID != tt.wantDC || inStock != tt.wantInStock {
+				t.Errorf("acknowledgeProductTransit() = %v, %v, %v, want %v, %v, %v", p.Status, p.DCID, inStock, tt.wantStatus, tt.wantDC, tt.wantInStock)
+			}
+			if inStock && getProductStockBucket(&p) == productStockNone {
+				t.Errorf("acknowledgeProductTransit() product in stock but not count as stock")
+			}
+			if !inStock && getProductStockBucket(&p) != productStockNone {
+				t.Errorf("acknowledgeProductTransit() sold product count as stock")
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var cancelFinancialEscrowService = achaemenid.Service{
+	ID:                1427698301,
+	IssueDate:         1792294530,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Cancel Financial Escrow",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `cancel held escrow and refund amount to the buyer. Buyer, seller or product DC can cancel not released escrow.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction", "Product",
+	},
+
+	SRPCHandler: CancelFinancialEscrowSRPC,
+	HTTPHandler: CancelFinancialEscrowHTTP,
+}
+
+// CancelFinancialEscrowSRPC is sRPC handler of CancelFinancialEscrow service.
+func CancelFinancialEscrowSRPC(st *achaemenid.Stream) {
+	var req = &cancelFinancialEscrowReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *cancelFinancialEscrowRes
+	res, st.Err = cancelFinancialEscrow(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// CancelFinancialEscrowHTTP is HTTP handler of CancelFinancialEscrow service.
+func CancelFinancialEscrowHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &cancelFinancialEscrowReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *cancelFinancialEscrowRes
+	res, st.Err = cancelFinancialEscrow(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type cancelFinancialEscrowReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type cancelFinancialEscrowRes struct {
+}
+
+func cancelFinancialEscrow(st *achaemenid.Stream, req *cancelFinancialEscrowReq) (res *cancelFinancialEscrowRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var fe = datastore.FinancialEscrow{
+		ID: req.ID,
+	}
+	err = fe.GetLastByID()
+	if err != nil {
+		return
+	}
+	if st.Connection.UserID != fe.BuyerID && st.Connection.UserID != fe.SellerID && st.Connection.UserID != fe.DCID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var decision datastore.FinancialEscrow
+	decision, err = decideFinancialEscrow(&fe, datastore.FinancialEscrowRefunded)
+	if err != nil {
+		return
+	}
+	if decision.Status != datastore.FinancialEscrowRefunded {
+		err = ErrFinancialEscrowDecided
+		return
+	}
+	err = settleFinancialEscrow(&decision)
+	if err != nil {
+		return
+	}
+
+	res = &cancelFinancialEscrowRes{}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *cancelFinancialEscrowReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *cancelFinancialEscrowReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *cancelFinancialEscrowReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *cancelFinancialEscrowReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *cancelFinancialEscrowReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *cancelFinancialEscrowReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *cancelFinancialEscrowReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *cancelFinancialEscrowReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *cancelFinancialEscrowRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *cancelFinancialEscrowRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *cancelFinancialEscrowRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *cancelFinancialEscrowRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *cancelFinancialEscrowRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *cancelFinancialEscrowRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *cancelFinancialEscrowRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *cancelFinancialEscrowRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,211 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var cancelFinancialStandingOrderService = achaemenid.Service{
+	ID:                4046211879,
+	IssueDate:         1792294756,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDDelete,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Cancel Financial Standing Order",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `cancel standing order of the user. Cancelled order never run again.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: CancelFinancialStandingOrderSRPC,
+	HTTPHandler: CancelFinancialStandingOrderHTTP,
+}
+
+// CancelFinancialStandingOrderSRPC is sRPC handler of CancelFinancialStandingOrder service.
+func CancelFinancialStandingOrderSRPC(st *achaemenid.Stream) {
+	var req = &cancelFinancialStandingOrderReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *cancelFinancialStandingOrderRes
+	res, st.Err = cancelFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// CancelFinancialStandingOrderHTTP is HTTP handler of CancelFinancialStandingOrder service.
+func CancelFinancialStandingOrderHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &cancelFinancialStandingOrderReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *cancelFinancialStandingOrderRes
+	res, st.Err = cancelFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type cancelFinancialStandingOrderReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type cancelFinancialStandingOrderRes struct {
+}
+
+func cancelFinancialStandingOrder(st *achaemenid.Stream, req *cancelFinancialStandingOrderReq) (res *cancelFinancialStandingOrderRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var fso = datastore.FinancialStandingOrder{
+		ID: req.ID,
+	}
+	err = fso.GetLastByID()
+	if err != nil {
+		return
+	}
+	if fso.FromUserID != st.Connection.UserID {
+		err = authorization.ErrUserNotOwnRecord
+		return
+	}
+	if fso.Status == datastore.FinancialStandingOrderCancelled || fso.Status == datastore.FinancialStandingOrderFinished {
+		err = ErrFinancialStandingOrderBadStatus
+		return
+	}
+
+	fso.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	fso.Status = datastore.FinancialStandingOrderCancelled
+	err = fso.Set()
+	if err != nil {
+		return
+	}
+	fso.IndexRecordIDForID()
+
+	res = &cancelFinancialStandingOrderRes{}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *cancelFinancialStandingOrderReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *cancelFinancialStandingOrderReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *cancelFinancialStandingOrderReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *cancelFinancialStandingOrderReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *cancelFinancialStandingOrderReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *cancelFinancialStandingOrderReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *cancelFinancialStandingOrderReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *cancelFinancialStandingOrderReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *cancelFinancialStandingOrderRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *cancelFinancialStandingOrderRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *cancelFinancialStandingOrderRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *cancelFinancialStandingOrderRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *cancelFinancialStandingOrderRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *cancelFinancialStandingOrderRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *cancelFinancialStandingOrderRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *cancelFinancialStandingOrderRes) jsonLen() (ln int) {
+	return
+}
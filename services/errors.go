@@ -40,6 +40,10 @@ var (
 	ErrOrgDomainRegistered = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Org Domain Registered",
 		"Given organization domain to register new organization or update exiting organization already registered").Save()
 
+	// UserAppConnection
+	ErrUserAppConnectionBadExpireTime = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "User App Connection Bad Expire Time",
+		"Given expire time to register new connection is passed").Save()
+
 	// Quiddity
 	ErrQuiddityTitleRegistered = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Quiddity Title Registered",
 		"Given quiddity title to register already registered and active for other one!").Save()
@@ -57,6 +61,24 @@ var (
 	ErrProductAuctionNotRegistered = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Not Registered",
 		"Desire product auction not register yet! So you can't update it!").Save()
 
+	ErrProductAuctionNotAllowUser = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Not Allow User",
+		"Product auction is just for other user").Save()
+
+	ErrProductAuctionNotAllowGroup = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Not Allow Group",
+		"Product auction is just for members of a group that buyer is not member of it").Save()
+
+	ErrProductAuctionNotAllowUserType = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Not Allow User Type",
+		"Product auction is not for buyer user type e.g. just for persons or orgs").Save()
+
+	ErrProductAuctionNotAllowWeekday = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Not Allow Weekday",
+		"Product auction is not active in this day of week").Save()
+
+	ErrProductAuctionNotAllowDayhour = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Not Allow Dayhour",
+		"Product auction is not active in this hour of day").Save()
+
+	ErrProductAuctionExpired = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Auction Expired",
+		"Product auction live time passed or it expired or blocked").Save()
+
 	// ProductPrice
 	ErrProductPriceNotRegistered = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Price Not Registered",
 		"Product price not register yet! So you can't update it!").Save()
@@ -71,7 +93,157 @@ var (
 	ErrFinancialTransactionBalance = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Balance",
 		"Financial transaction canceled due to user don't has enough balance").Save()
 
+	ErrFinancialTransactionBadAmount = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Bad Amount",
+		"Financial transaction amount must be a positive number").Save()
+
+	ErrFinancialTransactionSameUser = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Same User",
+		"Can't transfer from a user to itself").Save()
+
+	ErrFinancialTransactionBadPeriod = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Transaction Bad Period",
+		"Requested period of financial transactions is not valid or longer than allowed").Save()
+
+	ErrFinancialStatementTooLarge = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Statement Too Large",
+		"Requested period has more transactions than a statement can hold. Request shorter period").Save()
+
+	ErrFinancialStatementBadFormat = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Statement Bad Format",
+		"Requested format of financial statement is not supported").Save()
+
+	// FinancialRule
+	ErrFinancialRuleBreach = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Rule Breach",
+		"Requested withdraw breach spending rules of the user e.g. daily or per transaction limit").Save()
+
+	ErrFinancialRuleNeedOTP = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Rule Need OTP",
+		"Requested withdraw breach spending rules of the user and need valid OTP of the user to pass").Save()
+
+	// FinancialExchange
+	ErrFinancialExchangeConfig = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Exchange Config",
+		"Financial exchange rates config file is not valid").Save()
+
+	ErrFinancialExchangeNoRate = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Exchange No Rate",
+		"No exchange rate exist for requested currencies now").Save()
+
+	ErrFinancialExchangeSameCurrency = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Exchange Same Currency",
+		"Exchange from a currency to itself is not allowed").Save()
+
+	ErrFinancialExchangeOverflow = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Exchange Overflow",
+		"Exchanged amount is more than a balance can hold").Save()
+
+	// FinancialIdempotency
+	ErrFinancialIdempotencyMismatch = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Idempotency Mismatch",
+		"Given idempotency key used before with other request. Use new key for new request").Save()
+
+	ErrFinancialIdempotencyInProgress = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Idempotency In Progress",
+		"First request with given idempotency key is still in progress. Retry later with same key").Save()
+
+	// FinancialEscrow
+	ErrFinancialEscrowNotPreSale = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Escrow Not Pre Sale",
+		"Escrow can hold just for pre-sale product of other users").Save()
+
+	ErrFinancialEscrowExist = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Escrow Exist",
+		"Requested product already has a held escrow").Save()
+
+	ErrFinancialEscrowBadExpireTime = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Escrow Bad Expire Time",
+		"Expire time of escrow must be in future and not longer than allowed period").Save()
+
+	ErrFinancialEscrowBadAmount = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Escrow Bad Amount",
+		"Escrow amount must be same as payable price of the product by given auction").Save()
+
+	ErrFinancialEscrowDecided = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Escrow Decided",
+		"Requested escrow released or refunded before").Save()
+
+	// FinancialStandingOrder
+	ErrFinancialStandingOrderBadPeriod = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Standing Order Bad Period",
+		"Period days of standing order must be between 1 and 366 and end time must be after first run time").Save()
+
+	ErrFinancialStandingOrderBadStatus = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Standing Order Bad Status",
+		"Requested standing order status not allow requested change e.g. resume an active order").Save()
+
+	// FinancialFreeze
+	ErrFinancialFreezeNoCase = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Freeze No Case",
+		"Each freeze and unfreeze must reference to the justice case that order it").Save()
+
+	ErrFinancialFreezeBadStatus = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Financial Freeze Bad Status",
+		"Requested freeze status not allow requested change").Save()
+
+	// FinancialWebPayment
+	ErrWebPaymentGatewayConfig = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Web Payment Gateway Config",
+		"Web payment gateway config file is not valid").Save()
+
+	ErrWebPaymentBadSignature = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Web Payment Bad Signature",
+		"Web payment callback signature is not valid or not belong to payment gateway").Save()
+
+	// Society
+	ErrSocietyBadTimeZone = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Society Bad Time Zone",
+		"Society time zone in manifest is not a valid IANA time zone name").Save()
+
+	// SocietyTransfer
+	ErrSocietyTransferConfig = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Society Transfer Config",
+		"Societies config file is not valid").Save()
+
+	ErrSocietyTransferBadSignature = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Society Transfer Bad Signature",
+		"Society transfer message signature is not valid or not belong to a known society").Save()
+
+	ErrSocietyTransferUnreachable = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Society Transfer Unreachable",
+		"Can't reach other society to settle the transfer now! Transfer will settle later").Save()
+
+	ErrSocietyTransferRejected = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Society Transfer Rejected",
+		"Other society rejected the transfer and the amount returned to your balance").Save()
+
 	// Product
 	ErrProductInvoiceDelegate = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Delegate Product Invoice",
-		"User of the connection can't register delegate invoice without send valid OTP or Transaction ID of desire user").Save()
+		"User of the connection can't register delegate invoice without send valid OTP of desire user").Save()
+
+	ErrProductInvoiceBadCommission = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Invoice Bad Commission",
+		"Discount and commissions of the product auction are more than product price").Save()
+
+	ErrProductInvoiceSelfSeller = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Invoice Self Seller",
+		"Seller can't register invoice for itself as the buyer").Save()
+
+	ErrProductRefundNotOwned = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Refund Not Owned",
+		"Requested product is not owned by given buyer, so it can't return").Save()
+
+	ErrProductRefundNoPayment = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Refund No Payment",
+		"Can't find invoice payment of the buyer for requested product").Save()
+
+	ErrProductVoid = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Void",
+		"Requested product is void and can't change anymore").Save()
+
+	ErrProductSameOwner = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Same Owner",
+		"New owner of the product must be given and not be same as the current owner").Save()
+
+	ErrProductOwnerNotFound = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Owner Not Found",
+		"New owner of the product must be a registered person or organization").Save()
+
+	ErrProductSameDC = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Same DC",
+		"Receiving DC of the product must be given and not be same as the current DC").Save()
+
+	ErrProductInTransit = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product In Transit",
+		"Requested product is in transit between two DC and can't change until receiving DC acknowledge it").Save()
+
+	ErrProductEscrowHeld = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Escrow Held",
+		"Requested product has held escrow for a buyer and can't dispatch until the escrow release or refund").Save()
+
+	ErrProductNotInTransit = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Not In Transit",
+		"Requested product is not dispatched to the DC").Save()
+
+	ErrProductNotMeasurable = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Not Measurable",
+		"Requested product has no quantity, so it can't split or merge").Save()
+
+	ErrProductQuantityMismatch = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Quantity Mismatch",
+		"Quantities of split or merged products must be more than zero and add up to the products quantity").Save()
+
+	ErrProductQuiddityMismatch = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Quiddity Mismatch",
+		"Quiddity of split or merged products must belong to same organization and has same base unit as the products quiddity").Save()
+
+	ErrProductMergeDC = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Merge DC",
+		"Just products in same DC can merge").Save()
+
+	ErrProductStockBadReorderLevel = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Stock Bad Reorder Level",
+		"Reorder level of a product stock can't be negative").Save()
+
+	ErrProductStockBadRecountDay = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Stock Bad Recount Day",
+		"Recount of a product stock must start from a day in past").Save()
+
+	ErrProductStockNoLeader = er.New().SetDetail(lang.LanguageEnglish, errorEnglishDomain, "Product Stock No Leader",
+		"Organization of the product stock not gave delegate connection to any person to notify about reorder").Save()
 )
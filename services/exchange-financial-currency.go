@@ -0,0 +1,340 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+var exchangeFinancialCurrencyService = achaemenid.Service{
+	ID:                3627410942,
+	IssueDate:         1792295057,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDCreate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Exchange Financial Currency",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `exchange amount of user balance in a currency to other currency by current rate of exchange rate source.
+Withdraw and deposit legs and the used rate record in an exchange record that both legs reference to it.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: ExchangeFinancialCurrencySRPC,
+	HTTPHandler: ExchangeFinancialCurrencyHTTP,
+}
+
+// ExchangeFinancialCurrencySRPC is sRPC handler of ExchangeFinancialCurrency service.
+func ExchangeFinancialCurrencySRPC(st *achaemenid.Stream) {
+	var req = &exchangeFinancialCurrencyReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *exchangeFinancialCurrencyRes
+	res, st.Err = exchangeFinancialCurrency(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// ExchangeFinancialCurrencyHTTP is HTTP handler of ExchangeFinancialCurrency service.
+func ExchangeFinancialCurrencyHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &exchangeFinancialCurrencyReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *exchangeFinancialCurrencyRes
+	res, st.Err = exchangeFinancialCurrency(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type exchangeFinancialCurrencyReq struct {
+	FromCurrency uint16       // ISO 4217 numeric code. Zero is society default currency
+	ToCurrency   uint16       // ISO 4217 numeric code. Zero is society default currency
+	Amount       price.Amount // Amount in FromCurrency
+}
+
+type exchangeFinancialCurrencyRes struct {
+	ID       [32]byte     `json:",string"` // FinancialExchangeID
+	ToAmount price.Amount // Amount deposit in ToCurrency
+	Rate     uint64       // ToCurrency amount for 1000000000 FromCurrency amount
+}
+
+func exchangeFinancialCurrency(st *achaemenid.Stream, req *exchangeFinancialCurrencyReq) (res *exchangeFinancialCurrencyRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+	err = checkFinancialFreeze(st.Connection.UserID)
+	if err != nil {
+		return
+	}
+
+	if financialExchangeRates == nil {
+		err = ErrFinancialExchangeNoRate
+		return
+	}
+	var fx = datastore.FinancialExchange{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		ID:           uuid.Random32Byte(),
+		UserID:       st.Connection.UserID,
+		FromCurrency: req.FromCurrency,
+		FromAmount:   req.Amount,
+		ToCurrency:   req.ToCurrency,
+	}
+	fx.Rate, err = financialExchangeRates.Rate(req.FromCurrency, req.ToCurrency)
+	if err != nil {
+		return
+	}
+	fx.ToAmount, err = exchangeFinancialAmount(req.Amount, fx.Rate)
+	if err != nil {
+		return
+	}
+	if fx.ToAmount == 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+
+	err = writeFinancialExchange(financialExchanges, &fx)
+	if err != nil {
+		return
+	}
+
+	res = &exchangeFinancialCurrencyRes{
+		ID:       fx.ID,
+		ToAmount: fx.ToAmount,
+		Rate:     fx.Rate,
+	}
+	return
+}
+
+// writeFinancialExchange withdraw FromAmount and deposit ToAmount of given exchange and then save it.
+// Written legs reverse if any next step failed, so user balances never change by a failed exchange.
+func writeFinancialExchange(ledger financialExchangeLedger, fx *datastore.FinancialExchange) (err *er.Error) {
+	var withdraw = datastore.FinancialTransaction{
+		AppInstanceID: fx.AppInstanceID,
+		UserID:        fx.UserID,
+		ReferenceID:   fx.ID,
+		ReferenceType: datastore.FinancialTransactionExchange,
+		Amount:        -fx.FromAmount,
+		Currency:      fx.FromCurrency,
+	}
+	err = ledger.SaveLeg(&withdraw)
+	if err != nil {
+		return
+	}
+	fx.FromTransactionID = withdraw.RecordID
+
+	var deposit = datastore.FinancialTransaction{
+		AppInstanceID: fx.AppInstanceID,
+		UserID:        fx.UserID,
+		ReferenceID:   fx.ID,
+		ReferenceType: datastore.FinancialTransactionExchange,
+		Amount:        fx.ToAmount,
+		Currency:      fx.ToCurrency,
+	}
+	err = ledger.SaveLeg(&deposit)
+	if err != nil {
+		rollbackFinancialExchange(ledger, &withdraw)
+		return
+	}
+	fx.ToTransactionID = deposit.RecordID
+
+	err = ledger.SaveExchange(fx)
+	if err != nil {
+		rollbackFinancialExchange(ledger, &deposit, &withdraw)
+	}
+	return
+}
+
+// rollbackFinancialExchange reverse given legs of a failed exchange in given order.
+func rollbackFinancialExchange(ledger financialExchangeLedger, legs ...*datastore.FinancialTransaction) {
+	for _, leg := range legs {
+		var err = ledger.ReverseLeg(leg)
+		if err != nil {
+			log.Warn("Financial exchange leg", leg.RecordID, "can't reverse due to:", err)
+		}
+	}
+}
+
+// financialExchangeLedger write and reverse legs and records of financial exchanges.
+type financialExchangeLedger interface {
+	SaveLeg(leg *datastore.FinancialTransaction) (err *er.Error)
+	ReverseLeg(leg *datastore.FinancialTransaction) (err *er.Error)
+	SaveExchange(fx *datastore.FinancialExchange) (err *er.Error)
+}
+
+var financialExchanges financialExchangeLedger = datastoreFinancialExchangeLedger{}
+
+// datastoreFinancialExchangeLedger keep legs in FinancialTransaction chains and exchanges in FinancialExchange records.
+type datastoreFinancialExchangeLedger struct{}
+
+func (datastoreFinancialExchangeLedger) SaveLeg(leg *datastore.FinancialTransaction) (err *er.Error) {
+	return saveFinancialTransaction(leg)
+}
+
+func (datastoreFinancialExchangeLedger) ReverseLeg(leg *datastore.FinancialTransaction) (err *er.Error) {
+	return reverseFinancialTransaction(leg)
+}
+
+func (datastoreFinancialExchangeLedger) SaveExchange(fx *datastore.FinancialExchange) (err *er.Error) {
+	return fx.SaveNew()
+}
+
+func (req *exchangeFinancialCurrencyReq) validator() (err *er.Error) {
+	if req.Amount <= 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+	if req.FromCurrency == req.ToCurrency {
+		err = ErrFinancialExchangeSameCurrency
+		return
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *exchangeFinancialCurrencyReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	req.FromCurrency = syllab.GetUInt16(buf, 0)
+	req.ToCurrency = syllab.GetUInt16(buf, 2)
+	req.Amount = price.Amount(syllab.GetInt64(buf, 4))
+	return
+}
+
+func (req *exchangeFinancialCurrencyReq) syllabEncoder(buf []byte) {
+	syllab.SetUInt16(buf, 0, req.FromCurrency)
+	syllab.SetUInt16(buf, 2, req.ToCurrency)
+	syllab.SetInt64(buf, 4, int64(req.Amount))
+	return
+}
+
+func (req *exchangeFinancialCurrencyReq) syllabStackLen() (ln uint32) {
+	return 12
+}
+
+func (req *exchangeFinancialCurrencyReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *exchangeFinancialCurrencyReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *exchangeFinancialCurrencyReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *exchangeFinancialCurrencyReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *exchangeFinancialCurrencyReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *exchangeFinancialCurrencyRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.ID[:], buf[0:])
+	res.ToAmount = price.Amount(syllab.GetInt64(buf, 32))
+	res.Rate = syllab.GetUInt64(buf, 40)
+	return
+}
+
+func (res *exchangeFinancialCurrencyRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.ID[:])
+	syllab.SetInt64(buf, 32, int64(res.ToAmount))
+	syllab.SetUInt64(buf, 40, res.Rate)
+	return
+}
+
+func (res *exchangeFinancialCurrencyRes) syllabStackLen() (ln uint32) {
+	return 48
+}
+
+func (res *exchangeFinancialCurrencyRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *exchangeFinancialCurrencyRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *exchangeFinancialCurrencyRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *exchangeFinancialCurrencyRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *exchangeFinancialCurrencyRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,60 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"strconv"
+
+	"../libgo/price"
+)
+
+// financialCurrencyDecimals is number of minor unit digits of ISO 4217 currencies that not use two digits.
+// Society default currency(zero) decimals set by society config.
+var financialCurrencyDecimals = map[uint16]uint8{
+	48:  3, // BHD
+	368: 3, // IQD
+	392: 0, // JPY
+	400: 3, // JOD
+	410: 0, // KRW
+	414: 3, // KWD
+	434: 3, // LYD
+	512: 3, // OMR
+	788: 3, // TND
+}
+
+// getFinancialCurrencyDecimals return number of minor unit digits of given currency.
+func getFinancialCurrencyDecimals(currency uint16) uint8 {
+	if currency == 0 {
+		return localSociety.currencyDecimals
+	}
+	var decimals, ok = financialCurrencyDecimals[currency]
+	if !ok {
+		decimals = 2
+	}
+	return decimals
+}
+
+// appendFinancialAmount append given minor units amount in major units of given currency e.g. 8099 >> 80.99
+func appendFinancialAmount(buf []byte, amount price.Amount, currency uint16) []byte {
+	var decimals = int(getFinancialCurrencyDecimals(currency))
+	var minor = uint64(amount)
+	if amount < 0 {
+		buf = append(buf, '-')
+		minor = uint64(-amount)
+	}
+	var digits = strconv.FormatUint(minor, 10)
+	if decimals == 0 {
+		return append(buf, digits...)
+	}
+	for len(digits) <= decimals {
+		digits = "0" + digits
+	}
+	buf = append(buf, digits[:len(digits)-decimals]...)
+	buf = append(buf, '.')
+	return append(buf, digits[len(digits)-decimals:]...)
+}
+
+// formatFinancialAmount return given minor units amount in major units of given currency e.g. 8099 >> "80.99"
+func formatFinancialAmount(amount price.Amount, currency uint16) string {
+	return string(appendFinancialAmount(nil, amount, currency))
+}
@@ -0,0 +1,49 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../libgo/price"
+)
+
+func TestFormatFinancialAmount(t *testing.T) {
+	var societyDecimals = localSociety.currencyDecimals
+	localSociety.currencyDecimals = 0
+	defer func() { localSociety.currencyDecimals = societyDecimals }()
+
+	var tests = []struct {
+		name     string
+		amount   price.Amount
+		currency uint16
+		want     string
+	}{
+		{"society currency without decimals", 125000, 0, "125000"},
+		{"two decimals", 8099, 840, "80.99"},
+		{"two decimals less than one", 5, 978, "0.05"},
+		{"two decimals zero", 0, 840, "0.00"},
+		{"negative", -8099, 840, "-80.99"},
+		{"three decimals", 12345, 414, "12.345"},
+		{"zero decimals", 1500, 392, "1500"},
+		{"negative zero decimals", -1500, 392, "-1500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatFinancialAmount(tt.amount, tt.currency); got != tt.want {
+				t.Errorf("formatFinancialAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendFinancialAmount_SocietyDecimals(t *testing.T) {
+	var societyDecimals = localSociety.currencyDecimals
+	localSociety.currencyDecimals = 2
+	defer func() { localSociety.currencyDecimals = societyDecimals }()
+
+	var got = string(appendFinancialAmount([]byte("Paid: "), 8099, 0))
+	if got != "Paid: 80.99" {
+		t.Errorf("appendFinancialAmount() = %v, want %v", got, "Paid: 80.99")
+	}
+}
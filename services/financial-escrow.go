@@ -0,0 +1,325 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"time"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/log"
+	"../libgo/price"
+)
+
+const (
+	financialEscrowMaxDuration   = 90 * 24 * 60 * 60 // Second
+	financialEscrowMaxVersions   = 16
+	financialEscrowMaxBuyerHolds = 1000
+	financialEscrowHolderPage    = 64
+	financialEscrowExpireDays    = 2 // How many days back timeout job check for expired escrows.
+	financialEscrowExpireLimit   = 100
+	financialEscrowExpireEvery   = 10 * time.Minute
+)
+
+// getFinancialEscrowAmount return payable price of the pre-sale product by its quiddity price and discount of given auction.
+// Auction must be for the product quiddity by the product owner and allow the buyer in now time.
+func getFinancialEscrowAmount(st *achaemenid.Stream, product *datastore.Product, productAuctionID [32]byte) (amount price.Amount, err *er.Error) {
+	var getProductPriceReq = getProductPriceReq{
+		QuiddityID: product.QuiddityID,
+	}
+	var getProductPriceRes *getProductPriceRes
+	getProductPriceRes, err = getProductPrice(st, &getProductPriceReq)
+	if err != nil {
+		return
+	}
+	amount = getProductPriceRes.Price
+	if productAuctionID == [32]byte{} {
+		return
+	}
+
+	var getProductAuctionReq = getProductAuctionReq{
+		ID: productAuctionID,
+	}
+	var getProductAuctionRes *getProductAuctionRes
+	getProductAuctionRes, err = getProductAuction(st, &getProductAuctionReq)
+	if err != nil {
+		return
+	}
+	if getProductAuctionRes.QuiddityID != product.QuiddityID || getProductAuctionRes.OrgID != product.OwnerID {
+		err = ErrFinancialEscrowBadAmount
+		return
+	}
+	switch getProductAuctionRes.Status {
+	case datastore.ProductAuctionExpired, datastore.ProductAuctionBlocked:
+		err = ErrProductAuctionExpired
+		return
+	}
+	err = checkProductAuctionAuthorization(getProductAuctionRes, st.Connection.UserID, st.Connection.UserType)
+	if err != nil {
+		return
+	}
+	amount -= getProductPriceRes.Price.PerMyriad(getProductAuctionRes.Discount)
+	return
+}
+
+// isFinancialEscrowHeld return true if last escrow of the product is held and not decided yet.
+func isFinancialEscrowHeld(productID [32]byte) (held bool, err *er.Error) {
+	var fe = datastore.FinancialEscrow{
+		ProductID: productID,
+	}
+	var IDs [][32]byte
+	IDs, err = fe.FindIDsByProductID(18446744073709551615, 1)
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return
+	}
+	fe.ID = IDs[0]
+	err = fe.GetLastByID()
+	if err != nil {
+		return
+	}
+	held = fe.Status == datastore.FinancialEscrowHeld
+	return
+}
+
+// findFinancialEscrowHolder return ID of the escrow that hold the product. found is false if no escrow hold it.
+func findFinancialEscrowHolder(productID [32]byte) (holderID [32]byte, found bool, err *er.Error) {
+	var fe = datastore.FinancialEscrow{
+		ProductID: productID,
+	}
+	var decided = func(escrowID [32]byte) (decided bool, err *er.Error) {
+		_, decided, err = findFinancialEscrowDecision(escrowID)
+		return
+	}
+	for offset := uint64(0); ; offset += financialEscrowHolderPage {
+		var IDs [][32]byte
+		IDs, err = fe.FindIDsByProductID(offset, financialEscrowHolderPage)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			return holderID, false, nil
+		}
+		if err != nil {
+			return
+		}
+		holderID, found, err = financialEscrowHolder(IDs, decided)
+		if err != nil || found || len(IDs) < financialEscrowHolderPage {
+			return
+		}
+	}
+}
+
+// financialEscrowHolder return first escrow of given product escrows in write order that not decided yet.
+// Product escrows index only accept append, so concurrent holds of a product agree on exactly one holder.
+func financialEscrowHolder(escrowIDs [][32]byte, decided func(escrowID [32]byte) (bool, *er.Error)) (holderID [32]byte, found bool, err *er.Error) {
+	for _, id := range escrowIDs {
+		var isDecided bool
+		isDecided, err = decided(id)
+		if err != nil {
+			return
+		}
+		if !isDecided {
+			return id, true, nil
+		}
+	}
+	return
+}
+
+// decideFinancialEscrow write new version of the escrow with given status and return decided version of the escrow.
+// First version after held one decide the outcome, so concurrent release and cancel can't both pay.
+func decideFinancialEscrow(fe *datastore.FinancialEscrow, status datastore.FinancialEscrowStatus) (decision datastore.FinancialEscrow, err *er.Error) {
+	var claim = *fe
+	claim.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	claim.Status = status
+	err = claim.Set()
+	if err != nil {
+		return
+	}
+	claim.IndexRecordIDForID()
+
+	var decided bool
+	decision, decided, err = findFinancialEscrowDecision(fe.ID)
+	if err != nil {
+		return
+	}
+	if !decided {
+		// Must not reach here due to our claim exist in list!
+		decision = claim
+	}
+	return
+}
+
+// findFinancialEscrowDecision return first version of the escrow that not held.
+func findFinancialEscrowDecision(escrowID [32]byte) (decision datastore.FinancialEscrow, decided bool, err *er.Error) {
+	var fe = datastore.FinancialEscrow{
+		ID: escrowID,
+	}
+	var recordIDs [][32]byte
+	recordIDs, err = fe.FindRecordIDsByID(0, financialEscrowMaxVersions)
+	if err != nil {
+		return
+	}
+	for _, recordID := range recordIDs {
+		decision = datastore.FinancialEscrow{
+			RecordID: recordID,
+		}
+		err = decision.GetByRecordID()
+		if err != nil {
+			return
+		}
+		if decision.Status != datastore.FinancialEscrowHeld {
+			decided = true
+			return
+		}
+	}
+	return
+}
+
+// settleFinancialEscrow pay decided escrow to seller or buyer. It is safe to call it more than once.
+func settleFinancialEscrow(decision *datastore.FinancialEscrow) (err *er.Error) {
+	var leg = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		ReferenceID:   decision.ID,
+		Amount:        decision.Amount,
+	}
+	switch decision.Status {
+	case datastore.FinancialEscrowReleased:
+		leg.UserID = decision.SellerID
+		leg.ReferenceType = datastore.FinancialTransactionEscrowRelease
+	case datastore.FinancialEscrowRefunded:
+		leg.UserID = decision.BuyerID
+		leg.ReferenceType = datastore.FinancialTransactionEscrowRefund
+	default:
+		return
+	}
+	err = saveFinancialTransactionOnce(&leg)
+	if err != nil {
+		return
+	}
+
+	if decision.Status == datastore.FinancialEscrowReleased {
+		var product = datastore.Product{
+			ID: decision.ProductID,
+		}
+		err = product.GetLastByID()
+		if err != nil {
+			return
+		}
+		if product.OwnerID != decision.BuyerID {
+			var before = product
+			product.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+			product.OwnerID = decision.BuyerID
+			product.Status = datastore.ProductChangeOwner
+			err = product.SaveNew()
+			if err == nil {
+				moveProductStock(&before, &product)
+			}
+		}
+	}
+	return
+}
+
+// getFinancialEscrowHeldBalance return sum of buyer escrows that not decided yet.
+func getFinancialEscrowHeldBalance(buyerID [32]byte) (held price.Amount, err *er.Error) {
+	var fe = datastore.FinancialEscrow{
+		BuyerID: buyerID,
+	}
+	var IDs [][32]byte
+	IDs, err = fe.FindIDsByBuyerID(0, financialEscrowMaxBuyerHolds)
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	for _, id := range IDs {
+		fe = datastore.FinancialEscrow{
+			ID: id,
+		}
+		err = fe.GetLastByID()
+		if err != nil {
+			return
+		}
+		if fe.Status == datastore.FinancialEscrowHeld {
+			held += fe.Amount
+		}
+	}
+	return
+}
+
+// expireFinancialEscrowsJob refund expired escrows to buyers.
+func expireFinancialEscrowsJob() {
+	var ticker = time.NewTicker(financialEscrowExpireEvery)
+	for {
+		expireFinancialEscrows()
+		<-ticker.C
+	}
+}
+
+func expireFinancialEscrows() {
+	var err *er.Error
+	var now = etime.Now()
+	var day = datastore.FinancialEscrow{
+		ExpireTime: now,
+	}
+	for i := 0; i < financialEscrowExpireDays; i++ {
+		var offset uint64
+		for {
+			var IDs [][32]byte
+			IDs, err = day.FindIDsByExpireTimeDaily(offset, financialEscrowExpireLimit)
+			if err != nil {
+				if !err.Equal(ganjine.ErrRecordNotFound) {
+					log.Warn("Financial escrow timeout job can't find escrows due to:", err)
+				}
+				break
+			}
+
+			for _, id := range IDs {
+				err = expireFinancialEscrow(id, now)
+				if err != nil {
+					log.Warn("Financial escrow", id, "can't expire due to:", err)
+				}
+			}
+
+			if len(IDs) < financialEscrowExpireLimit {
+				break
+			}
+			offset += financialEscrowExpireLimit
+		}
+		day.ExpireTime -= (24 * 60 * 60)
+	}
+}
+
+func expireFinancialEscrow(escrowID [32]byte, now etime.Time) (err *er.Error) {
+	var fe = datastore.FinancialEscrow{
+		ID: escrowID,
+	}
+	err = fe.GetLastByID()
+	if err != nil {
+		return
+	}
+	if fe.Status != datastore.FinancialEscrowHeld {
+		// Decided before. Settle again to finish half-done ones, legs write once.
+		var decision datastore.FinancialEscrow
+		decision, _, err = findFinancialEscrowDecision(escrowID)
+		if err != nil {
+			return
+		}
+		return settleFinancialEscrow(&decision)
+	}
+	if fe.ExpireTime > now {
+		return
+	}
+
+	var decision datastore.FinancialEscrow
+	decision, err = decideFinancialEscrow(&fe, datastore.FinancialEscrowRefunded)
+	if err != nil {
+		return
+	}
+	err = settleFinancialEscrow(&decision)
+	return
+}
@@ -0,0 +1,49 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	er "../libgo/error"
+)
+
+func TestFinancialEscrowHolder(t *testing.T) {
+	var first, second, third = [32]byte{1}, [32]byte{2}, [32]byte{3}
+	var tests = []struct {
+		name       string
+		escrowIDs  [][32]byte
+		decided    map[[32]byte]bool
+		wantHolder [32]byte
+		wantFound  bool
+	}{
+		{"no escrow", nil, nil, [32]byte{}, false},
+		{"single hold", [][32]byte{first}, nil, first, true},
+		{"first of concurrent holds win", [][32]byte{first, second}, nil, first, true},
+		{"loser not hold after winner decide", [][32]byte{first, second}, map[[32]byte]bool{first: true, second: true}, [32]byte{}, false},
+		{"hold after refunded hold", [][32]byte{first, second}, map[[32]byte]bool{first: true}, second, true},
+		{"refunded loser not pass hold", [][32]byte{first, second, third}, map[[32]byte]bool{second: true}, first, true},
+		{"all decided", [][32]byte{first, second, third}, map[[32]byte]bool{first: true, second: true, third: true}, [32]byte{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decided = func(escrowID [32]byte) (bool, *er.Error) {
+				return tt.decided[escrowID], nil
+			}
+			var holder, found, err = financialEscrowHolder(tt.escrowIDs, decided)
+			if err != nil || holder != tt.wantHolder || found != tt.wantFound {
+				t.Errorf("financialEscrowHolder() = %v, %v, %v, want %v, %v", holder, found, err, tt.wantHolder, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestFinancialEscrowHolder_DecisionError(t *testing.T) {
+	var decided = func(escrowID [32]byte) (bool, *er.Error) {
+		return false, ErrFinancialEscrowDecided
+	}
+	var _, found, err = financialEscrowHolder([][32]byte{{1}}, decided)
+	if err == nil || found {
+		t.Errorf("financialEscrowHolder() = %v, %v, want error", found, err)
+	}
+}
@@ -0,0 +1,99 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"math"
+	"math/bits"
+
+	"../datastore"
+	er "../libgo/error"
+	"../libgo/json"
+	"../libgo/price"
+)
+
+// financialExchangeRateSource is the interface that each exchange rate provider must implement.
+// Implementation set in financialExchangeRates and exchange service disabled if no source set.
+type financialExchangeRateSource interface {
+	// Rate return amount of to currency for datastore.FinancialExchangeRateScale amount of from currency.
+	Rate(from, to uint16) (rate uint64, err *er.Error)
+	// Currencies return all currencies that source has any rate for them.
+	Currencies() (currencies []uint16)
+}
+
+var financialExchangeRates financialExchangeRateSource
+
+// staticFinancialExchangeRates is a fixed rate table e.g. for tests or rates that set by hand.
+type staticFinancialExchangeRates struct {
+	Rates []staticFinancialExchangeRate
+	table map[[2]uint16]uint64
+}
+
+type staticFinancialExchangeRate struct {
+	From uint16
+	To   uint16
+	Rate uint64
+}
+
+// Init parse rate table from given json e.g. secret/financial-exchange-rates.json
+func (s *staticFinancialExchangeRates) Init(configJSON []byte) (err *er.Error) {
+	err = json.UnMarshal(configJSON, s)
+	if err != nil {
+		return
+	}
+
+	s.table = make(map[[2]uint16]uint64, len(s.Rates))
+	for _, r := range s.Rates {
+		if r.From == r.To || r.Rate == 0 {
+			err = ErrFinancialExchangeConfig
+			return
+		}
+		s.table[[2]uint16{r.From, r.To}] = r.Rate
+	}
+	return
+}
+
+// Rate return rate from the table. Reverse rate of a pair not calculate and must add to table if need.
+func (s *staticFinancialExchangeRates) Rate(from, to uint16) (rate uint64, err *er.Error) {
+	var ok bool
+	rate, ok = s.table[[2]uint16{from, to}]
+	if !ok {
+		err = ErrFinancialExchangeNoRate
+	}
+	return
+}
+
+// Currencies return all currencies that exist in the table in order of the table.
+func (s *staticFinancialExchangeRates) Currencies() (currencies []uint16) {
+	var seen = make(map[uint16]struct{}, len(s.Rates))
+	for _, r := range s.Rates {
+		for _, currency := range [2]uint16{r.From, r.To} {
+			if _, ok := seen[currency]; !ok {
+				seen[currency] = struct{}{}
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	return
+}
+
+// exchangeFinancialAmount return amount in to currency by given rate. It round down in favor of the platform.
+func exchangeFinancialAmount(amount price.Amount, rate uint64) (exchanged price.Amount, err *er.Error) {
+	if amount <= 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+
+	var hi, lo = bits.Mul64(uint64(amount), rate)
+	if hi >= datastore.FinancialExchangeRateScale {
+		err = ErrFinancialExchangeOverflow
+		return
+	}
+	var quo, _ = bits.Div64(hi, lo, datastore.FinancialExchangeRateScale)
+	if quo > math.MaxInt64 {
+		err = ErrFinancialExchangeOverflow
+		return
+	}
+	exchanged = price.Amount(quo)
+	return
+}
@@ -0,0 +1,149 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"../datastore"
+	er "../libgo/error"
+	"../libgo/price"
+)
+
+func TestStaticFinancialExchangeRates(t *testing.T) {
+	var rates staticFinancialExchangeRates
+	var err = rates.Init([]byte(`{"Rates":[{"From":840,"To":978,"Rate":920000000},{"From":978,"To":840,"Rate":1086956521}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rate uint64
+	rate, err = rates.Rate(840, 978)
+	if err != nil || rate != 920000000 {
+		t.Errorf("Rate(840, 978) = %v, %v, want 920000000, nil", rate, err)
+	}
+	_, err = rates.Rate(840, 364)
+	if !err.Equal(ErrFinancialExchangeNoRate) {
+		t.Errorf("Rate(840, 364) error = %v, want ErrFinancialExchangeNoRate", err)
+	}
+
+	err = rates.Init([]byte(`{"Rates":[{"From":840,"To":840,"Rate":1}]}`))
+	if !err.Equal(ErrFinancialExchangeConfig) {
+		t.Errorf("Init() with same currency pair error = %v, want ErrFinancialExchangeConfig", err)
+	}
+}
+
+func TestExchangeFinancialAmount(t *testing.T) {
+	var tests = []struct {
+		name   string
+		amount price.Amount
+		rate   uint64
+		want   price.Amount
+		err    bool
+	}{
+		{"same value", 8099, datastore.FinancialExchangeRateScale, 8099, false},
+		{"round down", 8099, 920000000, 7451, false},
+		{"big rate", 100, 420000 * datastore.FinancialExchangeRateScale, 42000000, false},
+		{"zero amount", 0, datastore.FinancialExchangeRateScale, 0, true},
+		{"overflow", math.MaxInt64, 2 * datastore.FinancialExchangeRateScale, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, err = exchangeFinancialAmount(tt.amount, tt.rate)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("exchangeFinancialAmount() = %v, %v, want %v, error %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+// memoryFinancialExchangeLedger is in-memory financialExchangeLedger that fail the write step number given in fail.
+type memoryFinancialExchangeLedger struct {
+	fail     int
+	steps    int
+	legs     []datastore.FinancialTransaction
+	reversed [][32]byte
+	saved    bool
+}
+
+func (l *memoryFinancialExchangeLedger) step() (err *er.Error) {
+	l.steps++
+	if l.steps == l.fail {
+		err = ErrFinancialTransactionBadAmount
+	}
+	return
+}
+
+func (l *memoryFinancialExchangeLedger) SaveLeg(leg *datastore.FinancialTransaction) (err *er.Error) {
+	err = l.step()
+	if err != nil {
+		return
+	}
+	leg.RecordID = [32]byte{byte(l.steps)}
+	l.legs = append(l.legs, *leg)
+	return
+}
+
+func (l *memoryFinancialExchangeLedger) ReverseLeg(leg *datastore.FinancialTransaction) (err *er.Error) {
+	l.reversed = append(l.reversed, leg.RecordID)
+	return
+}
+
+func (l *memoryFinancialExchangeLedger) SaveExchange(fx *datastore.FinancialExchange) (err *er.Error) {
+	err = l.step()
+	l.saved = err == nil
+	return
+}
+
+func TestWriteFinancialExchange(t *testing.T) {
+	var tests = []struct {
+		name         string
+		fail         int
+		wantErr      bool
+		wantReversed [][32]byte
+	}{
+		{"exchanged", 0, false, nil},
+		{"withdraw failed", 1, true, nil},
+		{"deposit failed", 2, true, [][32]byte{{1}}},
+		{"exchange record failed", 3, true, [][32]byte{{2}, {1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ledger = memoryFinancialExchangeLedger{fail: tt.fail}
+			var fx = datastore.FinancialExchange{
+				ID:           [32]byte{9},
+				UserID:       [32]byte{8},
+				FromCurrency: 840,
+				FromAmount:   8099,
+				ToCurrency:   978,
+				ToAmount:     7451,
+			}
+			var err = writeFinancialExchange(&ledger, &fx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeFinancialExchange() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ledger.reversed, tt.wantReversed) {
+				t.Errorf("reversed legs = %v, want %v", ledger.reversed, tt.wantReversed)
+			}
+			if ledger.saved == tt.wantErr {
+				t.Errorf("exchange saved = %v, want %v", ledger.saved, !tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var withdraw, deposit = ledger.legs[0], ledger.legs[1]
+			if withdraw.UserID != fx.UserID || withdraw.Currency != 840 || withdraw.Amount != -8099 || withdraw.ReferenceID != fx.ID {
+				t.Errorf("withdraw leg = %v, want -8099 of 840 for exchange", withdraw)
+			}
+			if deposit.UserID != fx.UserID || deposit.Currency != 978 || deposit.Amount != 7451 || deposit.ReferenceID != fx.ID {
+				t.Errorf("deposit leg = %v, want 7451 of 978 for exchange", deposit)
+			}
+			if fx.FromTransactionID != withdraw.RecordID || fx.ToTransactionID != deposit.RecordID {
+				t.Errorf("exchange transactions = %v, %v, want %v, %v", fx.FromTransactionID, fx.ToTransactionID, withdraw.RecordID, deposit.RecordID)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/price"
+)
+
+const (
+	financialFreezeMaxUserFreezes = 1000
+)
+
+// checkFinancialFreeze reject spending if justice freeze the user whole account.
+// Partial freezes withdraw frozen amount from user balance, so they don't need any check here.
+func checkFinancialFreeze(userID [32]byte) (err *er.Error) {
+	var freezes []datastore.FinancialFreeze
+	freezes, err = findFinancialFreezes(userID)
+	if err != nil {
+		return
+	}
+	for i := range freezes {
+		if freezes[i].Whole && freezes[i].Status == datastore.FinancialFreezeFrozen {
+			return ErrBlockedByJustice
+		}
+	}
+	return
+}
+
+// getFinancialFrozenBalance return sum of user frozen amounts in given currency.
+func getFinancialFrozenBalance(userID [32]byte, currency uint16) (frozen price.Amount, err *er.Error) {
+	var freezes []datastore.FinancialFreeze
+	freezes, err = findFinancialFreezes(userID)
+	if err != nil {
+		return
+	}
+	for i := range freezes {
+		if freezes[i].Currency == currency && freezes[i].Status == datastore.FinancialFreezeFrozen {
+			frozen += freezes[i].Amount
+		}
+	}
+	return
+}
+
+// findFinancialFreezes return last version of all user freezes.
+func findFinancialFreezes(userID [32]byte) (freezes []datastore.FinancialFreeze, err *er.Error) {
+	var ff = datastore.FinancialFreeze{
+		UserID: userID,
+	}
+	var IDs [][32]byte
+	IDs, err = ff.FindIDsByUserID(0, financialFreezeMaxUserFreezes)
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	freezes = make([]datastore.FinancialFreeze, 0, len(IDs))
+	for _, id := range IDs {
+		ff = datastore.FinancialFreeze{
+			ID: id,
+		}
+		err = ff.GetLastByID()
+		if err != nil {
+			return
+		}
+		freezes = append(freezes, ff)
+	}
+	return
+}
+
+// depositFinancialFreeze deposit frozen amount back to user balance. It is safe to call it more than once.
+func depositFinancialFreeze(ff *datastore.FinancialFreeze) (err *er.Error) {
+	if ff.Amount == 0 {
+		return
+	}
+	var leg = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserID:        ff.UserID,
+		ReferenceID:   ff.ID,
+		ReferenceType: datastore.FinancialTransactionBlocked,
+		Amount:        ff.Amount,
+		Currency:      ff.Currency,
+	}
+	err = saveFinancialTransactionOnce(&leg)
+	return
+}
@@ -0,0 +1,146 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/uuid"
+)
+
+const (
+	financialIdempotencyWindow     = 24 * 60 * 60 // Second, a key can't use for other request in this period
+	financialIdempotencyClaimsPage = 256
+)
+
+// claimFinancialIdempotency claim given key for the request of active user.
+// If same request done before in the window, replayed is true and fi.Response is result of first request.
+// If not replayed, caller must call finishFinancialIdempotency after process the request.
+func claimFinancialIdempotency(st *achaemenid.Stream, key, requestHash [32]byte) (fi datastore.FinancialIdempotency, replayed bool, err *er.Error) {
+	fi = datastore.FinancialIdempotency{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: st.Connection.ID,
+		ClaimID:          uuid.Random32Byte(),
+		UserID:           st.Connection.UserID,
+		Key:              key,
+		ServiceID:        st.Service.ID,
+		RequestHash:      requestHash,
+		Status:           datastore.FinancialIdempotencyInProgress,
+	}
+	err = fi.SaveNew()
+	if err != nil {
+		return
+	}
+
+	var owner datastore.FinancialIdempotency
+	var found bool
+	owner, found, err = findFinancialIdempotencyOwner(&fi)
+	if err != nil {
+		updateFinancialIdempotency(&fi, datastore.FinancialIdempotencyFailed, [32]byte{}, nil)
+		return
+	}
+	if !found {
+		// Our claim is not in the list yet, so we can't know the owner. Give up and let client retry.
+		updateFinancialIdempotency(&fi, datastore.FinancialIdempotencyFailed, [32]byte{}, nil)
+		err = ErrFinancialIdempotencyInProgress
+		return
+	}
+	if owner.ClaimID == fi.ClaimID {
+		return
+	}
+
+	// Other request own the key, so give up our claim.
+	updateFinancialIdempotency(&fi, datastore.FinancialIdempotencyFailed, [32]byte{}, nil)
+	if owner.ServiceID != fi.ServiceID || owner.RequestHash != requestHash {
+		err = ErrFinancialIdempotencyMismatch
+		return
+	}
+	if owner.Status != datastore.FinancialIdempotencyDone {
+		err = ErrFinancialIdempotencyInProgress
+		return
+	}
+	fi = owner
+	replayed = true
+	return
+}
+
+// finishFinancialIdempotency store result of the request that own the key. response is syllab encoded response
+// of the request to replay it completely. Failed requests free the key to let client retry with same key.
+func finishFinancialIdempotency(fi *datastore.FinancialIdempotency, responseID [32]byte, response []byte, err *er.Error) {
+	if err != nil {
+		updateFinancialIdempotency(fi, datastore.FinancialIdempotencyFailed, [32]byte{}, nil)
+	} else {
+		updateFinancialIdempotency(fi, datastore.FinancialIdempotencyDone, responseID, response)
+	}
+}
+
+func updateFinancialIdempotency(fi *datastore.FinancialIdempotency, status datastore.FinancialIdempotencyStatus, responseID [32]byte, response []byte) {
+	fi.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	fi.Status = status
+	fi.ResponseID = responseID
+	fi.Response = response
+	var err = fi.SaveNew()
+	if err != nil {
+		// TODO::: we must retry more, otherwise key stay in progress until window end!
+	}
+}
+
+// findFinancialIdempotencyOwner replay all claims on fi key in write order and return last version of the claim
+// that own the key. First claim in the window that not failed own the key, so all concurrent requests agree on it.
+// found is false if no claim in the window own the key e.g. fi claim is not in the list yet.
+func findFinancialIdempotencyOwner(fi *datastore.FinancialIdempotency) (owner datastore.FinancialIdempotency, found bool, err *er.Error) {
+	var windowStart = etime.Now() - financialIdempotencyWindow
+	var claimIDs [][32]byte
+	var claims = make(map[[32]byte]datastore.FinancialIdempotency)
+	var expired = make(map[[32]byte]bool)
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = fi.FindRecordIDsByUserIDKey(offset, financialIdempotencyClaimsPage)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			// No more claims e.g. last page was full.
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			var claim = datastore.FinancialIdempotency{
+				RecordID: id,
+			}
+			err = claim.GetByRecordID()
+			if err != nil {
+				return
+			}
+			if expired[claim.ClaimID] {
+				continue
+			}
+			if _, ok := claims[claim.ClaimID]; !ok {
+				if claim.WriteTime < windowStart {
+					expired[claim.ClaimID] = true
+					continue
+				}
+				claimIDs = append(claimIDs, claim.ClaimID)
+			}
+			claims[claim.ClaimID] = claim
+		}
+
+		if len(IDs) < financialIdempotencyClaimsPage {
+			break
+		}
+		offset += financialIdempotencyClaimsPage
+	}
+
+	for _, claimID := range claimIDs {
+		owner = claims[claimID]
+		if owner.Status != datastore.FinancialIdempotencyFailed {
+			return owner, true, nil
+		}
+	}
+	return datastore.FinancialIdempotency{}, false, nil
+}
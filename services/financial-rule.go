@@ -0,0 +1,135 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/price"
+)
+
+// checkFinancialRule check user spending rules before withdraw amount from user balance.
+// Breach of rules return ErrFinancialRuleBreach or if user rule let step-up, ErrFinancialRuleNeedOTP until valid OTP send.
+// User without any rule has no limit.
+func checkFinancialRule(st *achaemenid.Stream, userID [32]byte, amount price.Amount, otpNumber uint64) (err *er.Error) {
+	var rule = datastore.FinancialRule{
+		UserID: userID,
+	}
+	err = rule.GetLastByUserID()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	var breached bool
+	breached, err = breachFinancialRule(st, &rule, amount)
+	if err != nil || !breached {
+		return
+	}
+
+	if !rule.OTPStepUp {
+		err = ErrFinancialRuleBreach
+		return
+	}
+	if otpNumber == 0 {
+		err = ErrFinancialRuleNeedOTP
+		return
+	}
+	err = checkPersonOTP(userID, otpNumber)
+	return
+}
+
+// breachFinancialRule report if withdraw amount breach any of given rule limits.
+func breachFinancialRule(st *achaemenid.Stream, rule *datastore.FinancialRule, amount price.Amount) (breached bool, err *er.Error) {
+	if rule.TransactionLimit != 0 && amount > rule.TransactionLimit {
+		return true, nil
+	}
+
+	var now = etime.Now()
+	// Nil stream means platform withdraw by user order e.g. standing orders, so no delegate connection to cool off.
+	if rule.DelegateCoolingOff != 0 && st != nil && st.Connection.UserID == rule.UserID && st.Connection.DelegateUserID != [32]byte{} {
+		var uac = datastore.UserAppConnection{
+			ID: st.Connection.ID,
+		}
+		err = uac.GetFirstByID()
+		if err != nil {
+			return
+		}
+		if uac.WriteTime+etime.Time(rule.DelegateCoolingOff) > now {
+			return true, nil
+		}
+	}
+
+	if rule.DailyLimit == 0 && rule.DailyCountLimit == 0 {
+		return
+	}
+	var spent price.Amount
+	var count uint32
+	spent, count, err = getFinancialDailySpent(rule.UserID, now)
+	if err != nil {
+		return
+	}
+	if rule.DailyLimit != 0 && spent+amount > rule.DailyLimit {
+		return true, nil
+	}
+	if rule.DailyCountLimit != 0 && count >= rule.DailyCountLimit {
+		return true, nil
+	}
+	return
+}
+
+// getFinancialDailySpent return sum and number of user withdraws in day of given time.
+// Reversed withdraws not count in sum but still count in number of withdraws.
+func getFinancialDailySpent(userID [32]byte, day etime.Time) (spent price.Amount, count uint32, err *er.Error) {
+	const limit = 64
+
+	var ft = datastore.FinancialTransaction{
+		UserID:    userID,
+		WriteTime: day,
+	}
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = ft.FindRecordIDsByUserIDWriteTime(offset, limit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			var leg = datastore.FinancialTransaction{
+				RecordID: id,
+			}
+			err = leg.GetByRecordID()
+			if err != nil {
+				return
+			}
+			switch leg.ReferenceType {
+			case datastore.FinancialTransactionDonate, datastore.FinancialTransactionSocietyTransfer,
+				datastore.FinancialTransactionProductAuctionPrice, datastore.FinancialTransactionEscrowHold:
+				if leg.Amount < 0 {
+					spent -= leg.Amount
+					count++
+				}
+			case datastore.FinancialTransactionReversal:
+				if leg.Amount > 0 {
+					spent -= leg.Amount
+				}
+			}
+		}
+
+		if len(IDs) < limit {
+			return
+		}
+		offset += limit
+	}
+}
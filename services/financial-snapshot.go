@@ -0,0 +1,474 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"time"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/log"
+	"../libgo/price"
+)
+
+const (
+	financialSnapshotDay       = 24 * 60 * 60 // Second
+	financialSnapshotPageLimit = 100
+	financialSnapshotEvery     = 1 * time.Hour
+)
+
+// writeFinancialSnapshotsJob write daily and monthly balance snapshots of users.
+func writeFinancialSnapshotsJob() {
+	var ticker = time.NewTicker(financialSnapshotEvery)
+	for {
+		writeFinancialSnapshots(etime.Now())
+		<-ticker.C
+	}
+}
+
+// writeFinancialSnapshots write snapshots of past days after last day that job wrote all its snapshots, so days of
+// cluster down time never miss, and monthly snapshots of each finished month. First run seed snapshot of yesterday for
+// all users. Snapshots of today never write due to today is not finished yet.
+func writeFinancialSnapshots(now etime.Time) {
+	var today = etime.Time(now.RoundToDay())
+	var fs datastore.FinancialSnapshot
+	var lastDay, err = fs.GetLastSnapshotDay()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		var yesterday = today - financialSnapshotDay
+		if seedFinancialSnapshots(yesterday) {
+			fs.IndexSnapshotDay(yesterday)
+		}
+		return
+	}
+	if err != nil {
+		log.Warn("Financial snapshot job can't find its last day due to:", err)
+		return
+	}
+
+	for day := lastDay + financialSnapshotDay; day < today; day += financialSnapshotDay {
+		if !writeDailyFinancialSnapshots(day) {
+			// Next run retry the day, so later days carry balance of it.
+			return
+		}
+		fs.IndexSnapshotDay(day)
+
+		var nextDay = day + financialSnapshotDay
+		if financialSnapshotMonth(nextDay) == nextDay {
+			writeMonthlyFinancialSnapshots(financialSnapshotMonth(day))
+		}
+	}
+}
+
+// seedFinancialSnapshots write snapshot of given day for each user chain that has any transaction until the day, so users
+// without recent transaction has snapshot too. It report if all snapshots written.
+func seedFinancialSnapshots(day etime.Time) (done bool) {
+	var chains = make(map[financialTransactionChain]struct{})
+	for d := financialTransactionFirstDay; d <= day; d += financialSnapshotDay {
+		var err = forEachFinancialSnapshotUser(d, func(userID [32]byte, currency uint16, d etime.Time) {
+			chains[financialTransactionChain{userID, currency}] = struct{}{}
+		})
+		if err != nil {
+			return false
+		}
+	}
+
+	done = true
+	for chain := range chains {
+		var err = writeDailyFinancialSnapshot(chain.userID, chain.currency, day)
+		if err != nil {
+			log.Warn("Financial daily snapshot of", chain.userID, "in", day, "can't seed due to:", err)
+			done = false
+		}
+	}
+	return
+}
+
+// writeDailyFinancialSnapshots write daily snapshots of all users of given day and report if all of them written.
+func writeDailyFinancialSnapshots(day etime.Time) (done bool) {
+	done = true
+	var err = forEachFinancialSnapshotUser(day, func(userID [32]byte, currency uint16, day etime.Time) {
+		var err = writeDailyFinancialSnapshot(userID, currency, day)
+		if err != nil {
+			log.Warn("Financial daily snapshot of", userID, "in", day, "can't write due to:", err)
+			done = false
+		}
+	})
+	return done && err == nil
+}
+
+// writeMonthlyFinancialSnapshots write monthly snapshots of all users that has daily snapshot in given month.
+func writeMonthlyFinancialSnapshots(month etime.Time) {
+	var nextMonth = financialSnapshotNextMonth(month)
+	for day := month; day < nextMonth; day += financialSnapshotDay {
+		forEachFinancialSnapshotUser(day, func(userID [32]byte, currency uint16, day etime.Time) {
+			var err = writeMonthlyFinancialSnapshot(userID, currency, month)
+			if err != nil {
+				log.Warn("Financial monthly snapshot of", userID, "in", month, "can't write due to:", err)
+			}
+		})
+	}
+}
+
+// forEachFinancialSnapshotUser call fn for each user that has any transaction in given day and each known currency
+// and for each user that has daily snapshot in the day before, so balance of users without transaction carry to the day.
+// It return error if users of the day can't find, so caller know some users not visited.
+func forEachFinancialSnapshotUser(day etime.Time, fn func(userID [32]byte, currency uint16, day etime.Time)) (err *er.Error) {
+	var currencies = financialSnapshotCurrencies()
+	var users = make(map[[32]byte]struct{})
+	var ft = datastore.FinancialTransaction{
+		WriteTime: day,
+	}
+	var offset uint64
+	for {
+		var userIDs [][32]byte
+		userIDs, err = ft.FindUserIDsByWriteTimeDaily(offset, financialSnapshotPageLimit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			break
+		}
+		if err != nil {
+			log.Warn("Financial snapshot job can't find users of", day, "due to:", err)
+			return
+		}
+
+		for _, userID := range userIDs {
+			// Index store UserID for each transaction, so skip duplicates.
+			if _, ok := users[userID]; ok {
+				continue
+			}
+			users[userID] = struct{}{}
+			for _, currency := range currencies {
+				fn(userID, currency, day)
+			}
+		}
+
+		if len(userIDs) < financialSnapshotPageLimit {
+			break
+		}
+		offset += financialSnapshotPageLimit
+	}
+
+	for _, currency := range currencies {
+		var fs = datastore.FinancialSnapshot{
+			Currency: currency,
+			Period:   datastore.FinancialSnapshotDaily,
+			Day:      day - financialSnapshotDay,
+		}
+		for offset = 0; ; offset += financialSnapshotPageLimit {
+			var userIDs [][32]byte
+			userIDs, err = fs.FindUserIDsByDay(offset, financialSnapshotPageLimit)
+			if err.Equal(ganjine.ErrRecordNotFound) {
+				err = nil
+				break
+			}
+			if err != nil {
+				log.Warn("Financial snapshot job can't find snapshot users of", fs.Day, "due to:", err)
+				return
+			}
+
+			for _, userID := range userIDs {
+				// Users that has transaction in the day visited in all currencies before.
+				if _, ok := users[userID]; !ok {
+					fn(userID, currency, day)
+				}
+			}
+
+			if len(userIDs) < financialSnapshotPageLimit {
+				break
+			}
+		}
+	}
+	return
+}
+
+// financialSnapshotCurrencies return default currency and all currencies that users can exchange to them.
+func financialSnapshotCurrencies() (currencies []uint16) {
+	currencies = []uint16{0}
+	if financialExchangeRates != nil {
+		for _, currency := range financialExchangeRates.Currencies() {
+			if currency != 0 {
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	return
+}
+
+// writeDailyFinancialSnapshot write snapshot of the user transactions in given day if not written before.
+// User without any transaction in the day get snapshot of last day balance if it is not zero, so any past day balance is one read.
+func writeDailyFinancialSnapshot(userID [32]byte, currency uint16, day etime.Time) (err *er.Error) {
+	var fs = datastore.FinancialSnapshot{
+		UserID:   userID,
+		Currency: currency,
+		Period:   datastore.FinancialSnapshotDaily,
+		Day:      day,
+	}
+	err = fs.GetLastByUserIDDay()
+	if err == nil || !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+
+	var ft = datastore.FinancialTransaction{
+		UserID:    userID,
+		WriteTime: day,
+		Currency:  currency,
+	}
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = ft.FindRecordIDsByUserIDWriteTime(offset, financialSnapshotPageLimit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			var leg = datastore.FinancialTransaction{
+				RecordID: id,
+			}
+			err = leg.GetByRecordID()
+			if err != nil {
+				return
+			}
+			if fs.Transactions == 0 {
+				fs.OpeningBalance = leg.Balance - leg.Amount
+			}
+			fs.ClosingBalance = leg.Balance
+			fs.LastTransactionID = leg.RecordID
+			fs.Transactions++
+			fs.Totals = addFinancialSnapshotTotal(fs.Totals, leg.ReferenceType, leg.Amount)
+		}
+
+		if len(IDs) < financialSnapshotPageLimit {
+			break
+		}
+		offset += financialSnapshotPageLimit
+	}
+	if fs.Transactions == 0 {
+		var last = datastore.FinancialSnapshot{
+			UserID:   userID,
+			Currency: currency,
+			Period:   datastore.FinancialSnapshotDaily,
+			Day:      day - financialSnapshotDay,
+		}
+		err = last.GetLastByUserIDDay()
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			// No snapshot in the day before e.g. job seed snapshots, so read balance from the user chain.
+			fs.ClosingBalance, fs.LastTransactionID, err = findFinancialChainBalanceAt(userID, currency, day+financialSnapshotDay-1)
+		} else if err == nil {
+			fs.ClosingBalance, fs.LastTransactionID = last.ClosingBalance, last.LastTransactionID
+		}
+		if err != nil || fs.ClosingBalance == 0 {
+			return
+		}
+		fs.OpeningBalance = fs.ClosingBalance
+	}
+
+	fs.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	err = fs.SaveNew()
+	return
+}
+
+// writeMonthlyFinancialSnapshot merge daily snapshots of given month if user has any and not written before.
+func writeMonthlyFinancialSnapshot(userID [32]byte, currency uint16, month etime.Time) (err *er.Error) {
+	var fs = datastore.FinancialSnapshot{
+		UserID:   userID,
+		Currency: currency,
+		Period:   datastore.FinancialSnapshotMonthly,
+		Day:      month,
+	}
+	err = fs.GetLastByUserIDDay()
+	if err == nil || !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+	err = nil
+
+	var found bool
+	var nextMonth = financialSnapshotNextMonth(month)
+	for day := month; day < nextMonth; day += financialSnapshotDay {
+		var daily = datastore.FinancialSnapshot{
+			UserID:   userID,
+			Currency: currency,
+			Period:   datastore.FinancialSnapshotDaily,
+			Day:      day,
+		}
+		err = daily.GetLastByUserIDDay()
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		if !found {
+			found = true
+			fs.OpeningBalance = daily.OpeningBalance
+		}
+		fs.ClosingBalance = daily.ClosingBalance
+		fs.LastTransactionID = daily.LastTransactionID
+		fs.Transactions += daily.Transactions
+		for _, total := range daily.Totals {
+			fs.Totals = addFinancialSnapshotTotal(fs.Totals, total.ReferenceType, total.Amount)
+		}
+	}
+	if !found {
+		return
+	}
+
+	fs.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	err = fs.SaveNew()
+	return
+}
+
+func addFinancialSnapshotTotal(totals []datastore.FinancialSnapshotTotal, ty datastore.FinancialTransactionType, amount price.Amount) []datastore.FinancialSnapshotTotal {
+	for i := range totals {
+		if totals[i].ReferenceType == ty {
+			totals[i].Amount += amount
+			return totals
+		}
+	}
+	return append(totals, datastore.FinancialSnapshotTotal{
+		ReferenceType: ty,
+		Amount:        amount,
+	})
+}
+
+// findFinancialBalanceAt return balance of the user in given currency after all transactions that wrote until given time.
+// Days that job not snapshot them yet and the day of given time walk transactions and other days answer by one read.
+func findFinancialBalanceAt(userID [32]byte, currency uint16, at etime.Time) (balance price.Amount, err *er.Error) {
+	var fs datastore.FinancialSnapshot
+	var snapshotDay etime.Time
+	snapshotDay, err = fs.GetLastSnapshotDay()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		// Job not seed snapshots yet.
+		balance, _, err = findFinancialChainBalanceAt(userID, currency, at)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	var found bool
+	var day = etime.Time(at.RoundToDay())
+	if at < day+financialSnapshotDay-1 && day <= snapshotDay {
+		balance, found, err = findFinancialDayBalanceAt(userID, currency, day, at)
+		if err != nil || found {
+			return
+		}
+		day -= financialSnapshotDay
+	}
+	for ; day > snapshotDay; day -= financialSnapshotDay {
+		balance, found, err = findFinancialDayBalanceAt(userID, currency, day, at)
+		if err != nil || found {
+			return
+		}
+	}
+
+	fs = datastore.FinancialSnapshot{
+		UserID:   userID,
+		Currency: currency,
+		Period:   datastore.FinancialSnapshotDaily,
+		Day:      day,
+	}
+	err = fs.GetLastByUserIDDay()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		// Job not write snapshot for users with zero balance and for days before its seed, so walk the user chain.
+		balance, _, err = findFinancialChainBalanceAt(userID, currency, at)
+		return
+	}
+	if err != nil {
+		return
+	}
+	balance = fs.ClosingBalance
+	return
+}
+
+// findFinancialChainBalanceAt walk the user chain back from its last transaction to last transaction that wrote until
+// given time and return its balance. It read all transactions after given time, so use it just when no snapshot exist.
+func findFinancialChainBalanceAt(userID [32]byte, currency uint16, at etime.Time) (balance price.Amount, lastTransactionID [32]byte, err *er.Error) {
+	var leg = datastore.FinancialTransaction{
+		UserID:   userID,
+		Currency: currency,
+	}
+	err = leg.GetLastTransactionByUserID()
+	for err == nil && leg.WriteTime > at {
+		if leg.PreviousTransactionID == [32]byte{} {
+			// First transaction of the user wrote after given time.
+			return 0, [32]byte{}, nil
+		}
+		leg = datastore.FinancialTransaction{
+			RecordID: leg.PreviousTransactionID,
+		}
+		err = leg.GetByRecordID()
+	}
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		return 0, [32]byte{}, nil
+	}
+	if err != nil {
+		return
+	}
+	return leg.Balance, leg.RecordID, nil
+}
+
+// findFinancialDayBalanceAt return balance after last transaction of the day that wrote until given time.
+// found is false if user has no transaction in the day.
+func findFinancialDayBalanceAt(userID [32]byte, currency uint16, day, at etime.Time) (balance price.Amount, found bool, err *er.Error) {
+	var ft = datastore.FinancialTransaction{
+		UserID:    userID,
+		WriteTime: day,
+		Currency:  currency,
+	}
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = ft.FindRecordIDsByUserIDWriteTime(offset, financialSnapshotPageLimit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			var leg = datastore.FinancialTransaction{
+				RecordID: id,
+			}
+			err = leg.GetByRecordID()
+			if err != nil {
+				return
+			}
+			if !found {
+				found = true
+				balance = leg.Balance - leg.Amount
+			}
+			if leg.WriteTime > at {
+				return
+			}
+			balance = leg.Balance
+		}
+
+		if len(IDs) < financialSnapshotPageLimit {
+			return
+		}
+		offset += financialSnapshotPageLimit
+	}
+}
+
+// financialSnapshotMonth return first day of the month of given time.
+func financialSnapshotMonth(t etime.Time) etime.Time {
+	var date = time.Unix(int64(t), 0).UTC()
+	return etime.Time(time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC).Unix())
+}
+
+// financialSnapshotNextMonth return first day of the month after the month of given first day.
+func financialSnapshotNextMonth(month etime.Time) etime.Time {
+	return etime.Time(time.Unix(int64(month), 0).UTC().AddDate(0, 1, 0).Unix())
+}
@@ -0,0 +1,187 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"crypto/sha512"
+	"time"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/log"
+	"../libgo/syllab"
+)
+
+const (
+	financialStandingOrderMaxRetries = 3
+	financialStandingOrderRetryAfter = 6 * 60 * 60 // Second
+	financialStandingOrderRunDays    = 7           // How many days back scheduler start to check for due orders when no run day indexed yet.
+	financialStandingOrderRunLimit   = 100
+	financialStandingOrderRunEvery   = 1 * time.Minute
+)
+
+// runFinancialStandingOrder run due order through transfer saga and write run result as new version of the order.
+// Low balance, financial rule breach and other failures retry financialStandingOrderMaxRetries times before skip the period.
+func runFinancialStandingOrder(fso *datastore.FinancialStandingOrder, now etime.Time) (err *er.Error) {
+	if fso.Status != datastore.FinancialStandingOrderActive || fso.NextRunTime > now {
+		return
+	}
+	if fso.EndTime != 0 && fso.ScheduledTime > fso.EndTime {
+		fso.Status = datastore.FinancialStandingOrderFinished
+		return updateFinancialStandingOrderRun(fso, false)
+	}
+
+	var ftr = datastore.FinancialTransfer{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		ID:            financialStandingOrderRunTransferID(fso),
+		FromUserID:    fso.FromUserID,
+		ToUserID:      fso.ToUserID,
+		Amount:        fso.Amount,
+	}
+	var transferErr = checkFinancialFreeze(fso.FromUserID)
+	if transferErr == nil {
+		// No user is online to send OTP, so rules that need OTP step up fail the run.
+		transferErr = checkFinancialRule(nil, fso.FromUserID, fso.Amount, 0)
+	}
+	if transferErr == nil {
+		_, transferErr = registerFinancialTransfer(&ftr)
+	}
+	fso.LastTransferID = ftr.ID
+	if transferErr == nil {
+		fso.LastRunStatus = datastore.FinancialStandingOrderRunDone
+		nextFinancialStandingOrderPeriod(fso)
+	} else if fso.Retries < financialStandingOrderMaxRetries {
+		fso.LastRunStatus = datastore.FinancialStandingOrderRunRetry
+		fso.Retries++
+		fso.NextRunTime = now + financialStandingOrderRetryAfter
+	} else {
+		fso.LastRunStatus = datastore.FinancialStandingOrderRunSkipped
+		nextFinancialStandingOrderPeriod(fso)
+	}
+	return updateFinancialStandingOrderRun(fso, true)
+}
+
+// updateFinancialStandingOrderRun write run result as new version. User changes to status from the time order read keep.
+func updateFinancialStandingOrderRun(fso *datastore.FinancialStandingOrder, nextRunChanged bool) (err *er.Error) {
+	var last = datastore.FinancialStandingOrder{
+		ID: fso.ID,
+	}
+	err = last.GetLastByID()
+	if err != nil {
+		return
+	}
+	if last.Status != datastore.FinancialStandingOrderActive {
+		fso.Status = last.Status
+	}
+
+	fso.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	err = fso.Set()
+	if err != nil {
+		return
+	}
+	fso.IndexRecordIDForID()
+	if nextRunChanged && fso.Status == datastore.FinancialStandingOrderActive {
+		fso.IndexIDForNextRunTimeDaily()
+	}
+	return
+}
+
+// financialStandingOrderRunTransferID return FinancialTransferID of current try of current period run.
+// Same ID let just one node transfer money if more than one node run the order at same time.
+func financialStandingOrderRunTransferID(fso *datastore.FinancialStandingOrder) (id [32]byte) {
+	var buf = make([]byte, 41) // 32+8+1
+	copy(buf[0:], fso.ID[:])
+	syllab.SetInt64(buf, 32, int64(fso.ScheduledTime))
+	syllab.SetUInt8(buf, 40, fso.Retries)
+	return sha512.Sum512_256(buf)
+}
+
+// nextFinancialStandingOrderPeriod move order to its next period run or finish it if next period is after EndTime.
+func nextFinancialStandingOrderPeriod(fso *datastore.FinancialStandingOrder) {
+	fso.ScheduledTime += etime.Time(fso.PeriodDays) * (24 * 60 * 60)
+	fso.NextRunTime = fso.ScheduledTime
+	fso.Retries = 0
+	if fso.EndTime != 0 && fso.ScheduledTime > fso.EndTime {
+		fso.Status = datastore.FinancialStandingOrderFinished
+	}
+}
+
+// runFinancialStandingOrdersJob run due standing orders.
+func runFinancialStandingOrdersJob() {
+	var ticker = time.NewTicker(financialStandingOrderRunEvery)
+	for {
+		runFinancialStandingOrders()
+		<-ticker.C
+	}
+}
+
+// runFinancialStandingOrders run due orders of each day from last indexed run day until today.
+// Past days that all due orders of them run without error skip in next runs by index new run day.
+func runFinancialStandingOrders() {
+	var now = etime.Now()
+	var today = etime.Time(now.RoundToDay())
+	var index datastore.FinancialStandingOrder
+	var runDay, err = index.GetLastRunDay()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		runDay = today - financialStandingOrderRunDays*(24*60*60)
+	} else if err != nil {
+		log.Warn("Financial standing order scheduler can't find run day due to:", err)
+		return
+	}
+
+	var nextRunDay = runDay
+	for day := runDay; day <= today; day += (24 * 60 * 60) {
+		var done = runFinancialStandingOrdersOfDay(day, now)
+		if done && day == nextRunDay && day < today {
+			nextRunDay = day + (24 * 60 * 60)
+		}
+	}
+	if nextRunDay != runDay {
+		index.IndexRunDay(nextRunDay)
+	}
+}
+
+// runFinancialStandingOrdersOfDay run due orders that indexed for given day and report if all of them read and run without error.
+func runFinancialStandingOrdersOfDay(day, now etime.Time) (done bool) {
+	var err *er.Error
+	var index = datastore.FinancialStandingOrder{
+		NextRunTime: day,
+	}
+	done = true
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = index.FindIDsByNextRunTimeDaily(offset, financialStandingOrderRunLimit)
+		if err != nil {
+			if !err.Equal(ganjine.ErrRecordNotFound) {
+				log.Warn("Financial standing order scheduler can't find orders due to:", err)
+				done = false
+			}
+			return
+		}
+
+		for _, id := range IDs {
+			var fso = datastore.FinancialStandingOrder{
+				ID: id,
+			}
+			err = fso.GetLastByID()
+			if err != nil {
+				done = false
+				continue
+			}
+			err = runFinancialStandingOrder(&fso, now)
+			if err != nil {
+				log.Warn("Financial standing order", fso.ID, "can't run due to:", err)
+				done = false
+			}
+		}
+
+		if len(IDs) < financialStandingOrderRunLimit {
+			return
+		}
+		offset += financialStandingOrderRunLimit
+	}
+}
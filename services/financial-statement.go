@@ -0,0 +1,223 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"../datastore"
+	etime "../libgo/earth-time"
+	lang "../libgo/language"
+)
+
+// financialStatementFormat indicate output format of financial transaction statement
+type financialStatementFormat uint8
+
+// Financial statement formats
+const (
+	financialStatementFormatJSON financialStatementFormat = iota
+	financialStatementFormatCSV
+	financialStatementFormatHTML
+)
+
+const financialStatementTimeLayout = "2006-01-02 15:04:05"
+
+type financialStatementLabels struct {
+	Direction      string // HTML text direction
+	Title          string
+	User           string
+	Period         string
+	OpeningBalance string
+	ClosingBalance string
+	ID             string
+	Time           string
+	Type           string
+	Reference      string
+	Amount         string
+	Balance        string
+	Types          map[datastore.FinancialTransactionType]string
+}
+
+var financialStatementLanguages = map[lang.Language]*financialStatementLabels{
+	lang.LanguageEnglish: {
+		Direction:      "ltr",
+		Title:          "Account Statement",
+		User:           "User",
+		Period:         "Period",
+		OpeningBalance: "Opening Balance",
+		ClosingBalance: "Closing Balance",
+		ID:             "Transaction",
+		Time:           "Time (UTC)",
+		Type:           "Type",
+		Reference:      "Reference",
+		Amount:         "Amount",
+		Balance:        "Balance",
+		Types: map[datastore.FinancialTransactionType]string{
+			datastore.FinancialTransactionUnset:                    "Unset",
+			datastore.FinancialTransactionFailed:                   "Failed",
+			datastore.FinancialTransactionBlocked:                  "Blocked",
+			datastore.FinancialTransactionDonate:                   "Donate",
+			datastore.FinancialTransactionBankTransfer:             "Bank Transfer",
+			datastore.FinancialTransactionPOSTransfer:              "POS Transfer",
+			datastore.FinancialTransactionWebTransfer:              "Web Transfer",
+			datastore.FinancialTransactionProductAuctionCommission: "Product Auction Commission",
+			datastore.FinancialTransactionProductAuctionPrice:      "Product Auction Price",
+			datastore.FinancialTransactionReversal:                 "Reversal",
+			datastore.FinancialTransactionSocietyTransfer:          "Society Transfer",
+			datastore.FinancialTransactionRefund:                   "Refund",
+			datastore.FinancialTransactionEscrowHold:               "Escrow Hold",
+			datastore.FinancialTransactionEscrowRelease:            "Escrow Release",
+			datastore.FinancialTransactionEscrowRefund:             "Escrow Refund",
+			datastore.FinancialTransactionExchange:                 "Currency Exchange",
+		},
+	},
+	lang.LanguagePersian: {
+		Direction:      "rtl",
+		Title:          "صورتحساب",
+		User:           "کاربر",
+		Period:         "دوره",
+		OpeningBalance: "مانده ابتدای دوره",
+		ClosingBalance: "مانده پایان دوره",
+		ID:             "تراکنش",
+		Time:           "زمان (UTC)",
+		Type:           "نوع",
+		Reference:      "مرجع",
+		Amount:         "مبلغ",
+		Balance:        "مانده",
+		Types: map[datastore.FinancialTransactionType]string{
+			datastore.FinancialTransactionUnset:                    "نامشخص",
+			datastore.FinancialTransactionFailed:                   "ناموفق",
+			datastore.FinancialTransactionBlocked:                  "مسدودی",
+			datastore.FinancialTransactionDonate:                   "انتقال",
+			datastore.FinancialTransactionBankTransfer:             "انتقال بانکی",
+			datastore.FinancialTransactionPOSTransfer:              "کارتخوان",
+			datastore.FinancialTransactionWebTransfer:              "درگاه اینترنتی",
+			datastore.FinancialTransactionProductAuctionCommission: "کارمزد فروش کالا",
+			datastore.FinancialTransactionProductAuctionPrice:      "خرید کالا",
+			datastore.FinancialTransactionReversal:                 "برگشت",
+			datastore.FinancialTransactionSocietyTransfer:          "انتقال بین جامعه",
+			datastore.FinancialTransactionRefund:                   "مرجوعی",
+			datastore.FinancialTransactionEscrowHold:               "بلوکه امانی",
+			datastore.FinancialTransactionEscrowRelease:            "آزادسازی امانی",
+			datastore.FinancialTransactionEscrowRefund:             "برگشت امانی",
+			datastore.FinancialTransactionExchange:                 "تبدیل ارز",
+		},
+	},
+}
+
+// getFinancialStatementLabels return labels of given language or English labels if language not supported.
+func getFinancialStatementLabels(language lang.Language) (labels *financialStatementLabels) {
+	labels = financialStatementLanguages[language]
+	if labels == nil {
+		labels = financialStatementLanguages[lang.LanguageEnglish]
+	}
+	return
+}
+
+func (l *financialStatementLabels) typeName(ty datastore.FinancialTransactionType) string {
+	var name, ok = l.Types[ty]
+	if !ok {
+		name = strconv.FormatUint(uint64(ty), 10)
+	}
+	return name
+}
+
+func formatFinancialStatementTime(t etime.Time) string {
+	return time.Unix(int64(t), 0).UTC().Format(financialStatementTimeLayout)
+}
+
+func formatFinancialStatementID(id [32]byte) string {
+	if id == [32]byte{} {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+// csvEncoder encode statement as CSV with a header row and a row for opening and closing balance.
+func (res *getFinancialTransactionStatementRes) csvEncoder(language lang.Language) (buf []byte) {
+	var labels = getFinancialStatementLabels(language)
+	var sb strings.Builder
+	var w = csv.NewWriter(&sb)
+
+	w.Write([]string{labels.ID, labels.Time, labels.Type, labels.Reference, labels.Amount, labels.Balance})
+	w.Write([]string{"", formatFinancialStatementTime(res.FromTime), labels.OpeningBalance, "", "", formatFinancialAmount(res.OpeningBalance, res.Currency)})
+	for i := range res.Transactions {
+		var t = &res.Transactions[i]
+		w.Write([]string{
+			formatFinancialStatementID(t.ID),
+			formatFinancialStatementTime(t.WriteTime),
+			labels.typeName(t.ReferenceType),
+			formatFinancialStatementID(t.ReferenceID),
+			formatFinancialAmount(t.Amount, res.Currency),
+			formatFinancialAmount(t.Balance, res.Currency),
+		})
+	}
+	w.Write([]string{"", formatFinancialStatementTime(res.ToTime), labels.ClosingBalance, "", "", formatFinancialAmount(res.ClosingBalance, res.Currency)})
+	w.Flush()
+
+	return []byte(sb.String())
+}
+
+// htmlEncoder encode statement as standalone printable HTML page.
+func (res *getFinancialTransactionStatementRes) htmlEncoder(language lang.Language) (buf []byte) {
+	var labels = getFinancialStatementLabels(language)
+	var sb strings.Builder
+
+	sb.WriteString(`<!DOCTYPE html><html dir="`)
+	sb.WriteString(labels.Direction)
+	sb.WriteString(`"><head><meta charset="utf-8"><title>`)
+	sb.WriteString(html.EscapeString(labels.Title))
+	sb.WriteString(`</title><style>body{font-family:sans-serif}table{border-collapse:collapse;width:100%}` +
+		`th,td{border:1px solid #999;padding:4px}td.n{text-align:end;font-family:monospace}` +
+		`@media print{thead{display:table-header-group}tr{break-inside:avoid}}</style></head><body><h1>`)
+	sb.WriteString(html.EscapeString(labels.Title))
+	sb.WriteString(`</h1><p>`)
+	sb.WriteString(html.EscapeString(labels.User))
+	sb.WriteString(`: `)
+	sb.WriteString(formatFinancialStatementID(res.UserID))
+	sb.WriteString(`<br>`)
+	sb.WriteString(html.EscapeString(labels.Period))
+	sb.WriteString(`: `)
+	sb.WriteString(formatFinancialStatementTime(res.FromTime))
+	sb.WriteString(` - `)
+	sb.WriteString(formatFinancialStatementTime(res.ToTime))
+	sb.WriteString(`<br>`)
+	sb.WriteString(html.EscapeString(labels.OpeningBalance))
+	sb.WriteString(`: `)
+	sb.WriteString(formatFinancialAmount(res.OpeningBalance, res.Currency))
+	sb.WriteString(`</p><table><thead><tr>`)
+	for _, title := range [...]string{labels.ID, labels.Time, labels.Type, labels.Reference, labels.Amount, labels.Balance} {
+		sb.WriteString(`<th>`)
+		sb.WriteString(html.EscapeString(title))
+		sb.WriteString(`</th>`)
+	}
+	sb.WriteString(`</tr></thead><tbody>`)
+	for i := range res.Transactions {
+		var t = &res.Transactions[i]
+		sb.WriteString(`<tr><td>`)
+		sb.WriteString(formatFinancialStatementID(t.ID))
+		sb.WriteString(`</td><td>`)
+		sb.WriteString(formatFinancialStatementTime(t.WriteTime))
+		sb.WriteString(`</td><td>`)
+		sb.WriteString(html.EscapeString(labels.typeName(t.ReferenceType)))
+		sb.WriteString(`</td><td>`)
+		sb.WriteString(formatFinancialStatementID(t.ReferenceID))
+		sb.WriteString(`</td><td class="n">`)
+		sb.WriteString(formatFinancialAmount(t.Amount, res.Currency))
+		sb.WriteString(`</td><td class="n">`)
+		sb.WriteString(formatFinancialAmount(t.Balance, res.Currency))
+		sb.WriteString(`</td></tr>`)
+	}
+	sb.WriteString(`</tbody></table><p>`)
+	sb.WriteString(html.EscapeString(labels.ClosingBalance))
+	sb.WriteString(`: `)
+	sb.WriteString(formatFinancialAmount(res.ClosingBalance, res.Currency))
+	sb.WriteString(`</p></body></html>`)
+
+	return []byte(sb.String())
+}
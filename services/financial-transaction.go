@@ -0,0 +1,226 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"time"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/log"
+)
+
+const (
+	financialTransactionFirstDay   etime.Time = 1599264000 // First day that platform has any financial transaction
+	financialTransactionIndexRetry            = 1 * time.Hour
+)
+
+// saveFinancialTransaction lock ft.UserID transactions chain and append ft to the chain.
+// It reject withdraw transactions if user don't has enough balance.
+// datastore.ErrFinancialTransactionLocked return if other writer hold the chain and caller can retry later.
+func saveFinancialTransaction(ft *datastore.FinancialTransaction) (err *er.Error) {
+	var last = datastore.FinancialTransaction{
+		UserID:   ft.UserID,
+		Currency: ft.Currency,
+	}
+	err = last.Lock()
+	if err != nil {
+		return
+	}
+
+	if ft.Amount < 0 && last.Balance < -ft.Amount {
+		last.CancelLock()
+		err = ErrFinancialTransactionBalance
+		return
+	}
+
+	ft.PreviousTransactionID = last.RecordID
+	ft.Balance = last.Balance + ft.Amount
+	err = ft.UnLock()
+	return
+}
+
+// saveFinancialTransactionOnce do same as saveFinancialTransaction but first check in locked situation that
+// no other leg with same UserID, Currency, ReferenceID, ReferenceType and amount sign exist. if exist ft fill by exiting leg.
+// Use it when more than one process (e.g. recovery job) can try to write same leg!
+func saveFinancialTransactionOnce(ft *datastore.FinancialTransaction) (err *er.Error) {
+	var last = datastore.FinancialTransaction{
+		UserID:   ft.UserID,
+		Currency: ft.Currency,
+	}
+	err = last.Lock()
+	if err != nil {
+		return
+	}
+
+	var legs []datastore.FinancialTransaction
+	legs, err = findFinancialTransactionsByReferenceID(ft.ReferenceID)
+	if err != nil {
+		last.CancelLock()
+		return
+	}
+	for _, leg := range legs {
+		if leg.UserID == ft.UserID && leg.Currency == ft.Currency && leg.ReferenceType == ft.ReferenceType && (leg.Amount < 0) == (ft.Amount < 0) {
+			last.CancelLock()
+			*ft = leg
+			return
+		}
+	}
+
+	if ft.Amount < 0 && last.Balance < -ft.Amount {
+		last.CancelLock()
+		err = ErrFinancialTransactionBalance
+		return
+	}
+
+	ft.PreviousTransactionID = last.RecordID
+	ft.Balance = last.Balance + ft.Amount
+	err = ft.UnLock()
+	return
+}
+
+// reverseFinancialTransaction write compensating leg for given leg if not written before.
+// The reversal leg store reversed leg RecordID as its ReferenceID.
+func reverseFinancialTransaction(leg *datastore.FinancialTransaction) (err *er.Error) {
+	var reversal = datastore.FinancialTransaction{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: leg.UserConnectionID,
+		UserID:           leg.UserID,
+		ReferenceID:      leg.RecordID,
+		ReferenceType:    datastore.FinancialTransactionReversal,
+		Amount:           -leg.Amount,
+		Currency:         leg.Currency,
+	}
+	err = saveFinancialTransactionOnce(&reversal)
+	return
+}
+
+// findFinancialTransactionsByReferenceID return all legs reference to given ID.
+func findFinancialTransactionsByReferenceID(referenceID [32]byte) (legs []datastore.FinancialTransaction, err *er.Error) {
+	const limit = 64
+
+	var ft = datastore.FinancialTransaction{
+		ReferenceID: referenceID,
+	}
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = ft.FindRecordIDsByReferenceID(offset, limit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			var leg = datastore.FinancialTransaction{
+				RecordID: id,
+			}
+			err = leg.GetByRecordID()
+			if err != nil {
+				return
+			}
+			legs = append(legs, leg)
+		}
+
+		if len(IDs) < limit {
+			return
+		}
+		offset += limit
+	}
+}
+
+// indexFinancialTransactionChainsJob index last old transaction of each user chain that written before user chain index,
+// so lock and balance services can tell users without any transaction from users that has no recent transaction.
+// TODO::: remove it when all app instances of the society run it once.
+func indexFinancialTransactionChainsJob() {
+	for !indexFinancialTransactionChains() {
+		time.Sleep(financialTransactionIndexRetry)
+	}
+	datastore.SetFinancialTransactionChainsIndexed()
+}
+
+// financialTransactionChain is a user chain in a currency.
+type financialTransactionChain struct {
+	userID   [32]byte
+	currency uint16
+}
+
+// indexFinancialTransactionChains walk days before chain index in descending order and days after it, and report if all chains indexed.
+// App instances that not run chain index yet may write transactions after chain index date, so head of each chain that has
+// transaction after the date index again from its last transaction.
+func indexFinancialTransactionChains() (indexed bool) {
+	var chainIndexDate, err = (&datastore.FinancialTransaction{}).ClaimChainIndexDate()
+	if err != nil {
+		log.Warn("Financial transaction chain index date can't claim due to:", err)
+		return false
+	}
+
+	indexed = true
+	var lastDay = etime.Time(chainIndexDate.RoundToDay())
+	for day := lastDay; day >= financialTransactionFirstDay; day -= financialSnapshotDay {
+		err = forEachFinancialSnapshotUser(day, func(userID [32]byte, currency uint16, day etime.Time) {
+			var ft = datastore.FinancialTransaction{
+				UserID:    userID,
+				WriteTime: day,
+				Currency:  currency,
+			}
+			var err = ft.IndexOldRecordIDForUserID()
+			if err != nil && !err.Equal(ganjine.ErrRecordNotFound) {
+				log.Warn("Financial transaction chain of", userID, "in", day, "can't index due to:", err)
+				indexed = false
+			}
+		})
+		if err != nil {
+			indexed = false
+		}
+	}
+	if !indexed {
+		return
+	}
+
+	var chains = make(map[financialTransactionChain]struct{})
+	var today = etime.Time(etime.Now().RoundToDay())
+	for day := lastDay; day <= today; day += financialSnapshotDay {
+		err = forEachFinancialSnapshotUser(day, func(userID [32]byte, currency uint16, day etime.Time) {
+			chains[financialTransactionChain{userID, currency}] = struct{}{}
+		})
+		if err != nil {
+			return false
+		}
+	}
+	for chain := range chains {
+		err = indexFinancialTransactionChainHead(chain.userID, chain.currency)
+		if err != nil {
+			log.Warn("Financial transaction chain head of", chain.userID, "can't index due to:", err)
+			indexed = false
+		}
+	}
+	return
+}
+
+// indexFinancialTransactionChainHead lock the user chain and index its last transaction as chain head.
+func indexFinancialTransactionChainHead(userID [32]byte, currency uint16) (err *er.Error) {
+	var last = datastore.FinancialTransaction{
+		UserID:   userID,
+		Currency: currency,
+	}
+	err = last.Lock()
+	if err.Equal(datastore.ErrFinancialTransactionChainNotIndexed) {
+		// Old chains indexed before, so user has no transaction in the currency.
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if last.RecordID != [32]byte{} {
+		err = last.IndexChainHead()
+	}
+	last.CancelLock()
+	return
+}
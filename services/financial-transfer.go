@@ -0,0 +1,227 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"time"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/log"
+	"../libgo/uuid"
+)
+
+const (
+	financialTransferRecoverAfter = 60 // Second, transfers not finished in this period assume half-done by a crashed node.
+	financialTransferRecoverDays  = 2  // How many days back recovery job check for half-done transfers.
+	financialTransferRecoverLimit = 100
+	financialTransferRecoverEvery = 1 * time.Minute
+)
+
+// registerFinancialTransfer transfer ftr.Amount from ftr.FromUserID to ftr.ToUserID as a saga.
+// Both legs store ftr.ID as ReferenceID and if deposit leg failed, a reversal leg restore the withdraw leg.
+// If even reversal leg failed, recovery job will finish the transfer later!
+// Caller can set ftr.ID to make transfer once e.g. scheduled runs, otherwise a random ID use.
+func registerFinancialTransfer(ftr *datastore.FinancialTransfer) (withdraw datastore.FinancialTransaction, err *er.Error) {
+	var onceTransfer = ftr.ID != [32]byte{}
+	if !onceTransfer {
+		ftr.ID = uuid.Random32Byte()
+	}
+	ftr.Status = datastore.FinancialTransferRegistered
+	err = ftr.SaveNew()
+	if err != nil {
+		return
+	}
+
+	withdraw = datastore.FinancialTransaction{
+		AppInstanceID:    ftr.AppInstanceID,
+		UserConnectionID: ftr.UserConnectionID,
+		UserID:           ftr.FromUserID,
+		ReferenceID:      ftr.ID,
+		ReferenceType:    datastore.FinancialTransactionDonate,
+		Amount:           -ftr.Amount,
+	}
+	if onceTransfer {
+		err = saveFinancialTransactionOnce(&withdraw)
+	} else {
+		err = saveFinancialTransaction(&withdraw)
+	}
+	if err != nil {
+		updateFinancialTransferStatus(ftr, datastore.FinancialTransferFailed)
+		return
+	}
+	updateFinancialTransferStatus(ftr, datastore.FinancialTransferDebited)
+
+	err = depositFinancialTransfer(ftr)
+	if err != nil {
+		var reverseErr = reverseFinancialTransfer(ftr)
+		if reverseErr != nil {
+			log.Warn("Financial transfer", ftr.ID, "stay half-done and wait for recovery job due to:", reverseErr)
+		}
+	}
+	return
+}
+
+// depositFinancialTransfer write ToUserID leg of the transfer if not written before.
+func depositFinancialTransfer(ftr *datastore.FinancialTransfer) (err *er.Error) {
+	var deposit = datastore.FinancialTransaction{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: ftr.UserConnectionID,
+		UserID:           ftr.ToUserID,
+		ReferenceID:      ftr.ID,
+		ReferenceType:    datastore.FinancialTransactionDonate,
+		Amount:           ftr.Amount,
+	}
+	err = saveFinancialTransactionOnce(&deposit)
+	if err != nil {
+		return
+	}
+	updateFinancialTransferStatus(ftr, datastore.FinancialTransferCredited)
+	return
+}
+
+// reverseFinancialTransfer write compensating leg for FromUserID to restore the withdraw leg if not written before.
+func reverseFinancialTransfer(ftr *datastore.FinancialTransfer) (err *er.Error) {
+	var reversal = datastore.FinancialTransaction{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: ftr.UserConnectionID,
+		UserID:           ftr.FromUserID,
+		ReferenceID:      ftr.ID,
+		ReferenceType:    datastore.FinancialTransactionReversal,
+		Amount:           ftr.Amount,
+	}
+	err = saveFinancialTransactionOnce(&reversal)
+	if err != nil {
+		return
+	}
+	updateFinancialTransferStatus(ftr, datastore.FinancialTransferReversed)
+	return
+}
+
+// recoverFinancialTransfer check written legs of a half-done transfer and finish it.
+func recoverFinancialTransfer(ftr *datastore.FinancialTransfer) (err *er.Error) {
+	var societyID = achaemenid.Server.Manifest.SocietyID
+	if ftr.FromSocietyID != 0 && ftr.FromSocietyID != societyID {
+		// Payer society drive incoming transfers by send request again.
+		return
+	}
+
+	var legs []datastore.FinancialTransaction
+	legs, err = findFinancialTransactionsByReferenceID(ftr.ID)
+	if err != nil {
+		return
+	}
+
+	if ftr.ToSocietyID != 0 && ftr.ToSocietyID != societyID && len(legs) > 0 {
+		for _, leg := range legs {
+			if leg.ReferenceType == datastore.FinancialTransactionReversal {
+				updateFinancialTransferStatus(ftr, datastore.FinancialTransferReversed)
+				return
+			}
+		}
+		err = localSociety.settleTransfer(societyMessageOfFinancialTransfer(ftr))
+		if err.Equal(ErrSocietyTransferRejected) {
+			err = nil
+		}
+		return
+	}
+
+	var withdrawn, deposited, reversed bool
+	for _, leg := range legs {
+		switch {
+		case leg.ReferenceType == datastore.FinancialTransactionReversal:
+			reversed = true
+		case leg.UserID == ftr.FromUserID && leg.Amount < 0:
+			withdrawn = true
+		case leg.UserID == ftr.ToUserID && leg.Amount > 0:
+			deposited = true
+		}
+	}
+
+	switch {
+	case reversed:
+		updateFinancialTransferStatus(ftr, datastore.FinancialTransferReversed)
+	case deposited:
+		updateFinancialTransferStatus(ftr, datastore.FinancialTransferCredited)
+	case withdrawn:
+		err = depositFinancialTransfer(ftr)
+		if err != nil {
+			err = reverseFinancialTransfer(ftr)
+		}
+	default:
+		updateFinancialTransferStatus(ftr, datastore.FinancialTransferFailed)
+	}
+	return
+}
+
+// updateFinancialTransferStatus write new version of the transfer with given status.
+// Error is not important here due to legs are source of truth and recovery job will fix status later!
+func updateFinancialTransferStatus(ftr *datastore.FinancialTransfer, status datastore.FinancialTransferStatus) {
+	ftr.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	ftr.Status = status
+	var err = ftr.Set()
+	if err != nil {
+		return
+	}
+	ftr.IndexRecordIDForID()
+}
+
+// recoverFinancialTransfersJob find and finish half-done transfers e.g. after a node restart.
+func recoverFinancialTransfersJob() {
+	var ticker = time.NewTicker(financialTransferRecoverEvery)
+	for {
+		recoverFinancialTransfers()
+		<-ticker.C
+	}
+}
+
+func recoverFinancialTransfers() {
+	var err *er.Error
+	var now = etime.Now()
+	var day = datastore.FinancialTransfer{
+		WriteTime: now,
+	}
+	for i := 0; i < financialTransferRecoverDays; i++ {
+		var offset uint64
+		for {
+			var IDs [][32]byte
+			IDs, err = day.FindIDsByWriteTimeDaily(offset, financialTransferRecoverLimit)
+			if err != nil {
+				if !err.Equal(ganjine.ErrRecordNotFound) {
+					log.Warn("Financial transfer recovery job can't find transfers due to:", err)
+				}
+				break
+			}
+
+			for _, id := range IDs {
+				var ftr = datastore.FinancialTransfer{
+					ID: id,
+				}
+				err = ftr.GetLastByID()
+				if err != nil {
+					continue
+				}
+				if ftr.Status != datastore.FinancialTransferRegistered && ftr.Status != datastore.FinancialTransferDebited {
+					continue
+				}
+				if now-ftr.WriteTime < financialTransferRecoverAfter {
+					continue
+				}
+
+				err = recoverFinancialTransfer(&ftr)
+				if err != nil {
+					log.Warn("Financial transfer", ftr.ID, "can't recover due to:", err)
+				}
+			}
+
+			if len(IDs) < financialTransferRecoverLimit {
+				break
+			}
+			offset += financialTransferRecoverLimit
+		}
+		day.WriteTime -= (24 * 60 * 60)
+	}
+}
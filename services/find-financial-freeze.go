@@ -0,0 +1,322 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var findFinancialFreezeService = achaemenid.Service{
+	ID:                3871025964,
+	IssueDate:         1792295516,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Find Financial Freeze",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `find freezes of requester user or, just for justice department, freezes of any user or a justice case.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: FindFinancialFreezeSRPC,
+	HTTPHandler: FindFinancialFreezeHTTP,
+}
+
+// FindFinancialFreezeSRPC is sRPC handler of FindFinancialFreeze service.
+func FindFinancialFreezeSRPC(st *achaemenid.Stream) {
+	var req = &findFinancialFreezeReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *findFinancialFreezeRes
+	res, st.Err = findFinancialFreeze(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// FindFinancialFreezeHTTP is HTTP handler of FindFinancialFreeze service.
+func FindFinancialFreezeHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &findFinancialFreezeReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *findFinancialFreezeRes
+	res, st.Err = findFinancialFreeze(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type findFinancialFreezeReq struct {
+	UserID [32]byte `json:",string,optional"` // Empty means requester user. Just justice department can request other users freezes
+	CaseID [32]byte `json:",string,optional"` // Find freezes of a justice case instead of a user. Just justice department can request
+	Offset uint64
+	Limit  uint64
+}
+
+type findFinancialFreezeRes struct {
+	Freezes []financialFreeze
+}
+
+func findFinancialFreeze(st *achaemenid.Stream, req *findFinancialFreezeReq) (res *findFinancialFreezeRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	if req.CaseID == [32]byte{} && req.UserID == [32]byte{} {
+		req.UserID = st.Connection.UserID
+	}
+	if (req.CaseID != [32]byte{} || req.UserID != st.Connection.UserID) && st.Connection.UserID != justiceUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var ff = datastore.FinancialFreeze{
+		UserID: req.UserID,
+		CaseID: req.CaseID,
+	}
+	var IDs [][32]byte
+	if req.CaseID != [32]byte{} {
+		IDs, err = ff.FindIDsByCaseID(req.Offset, req.Limit)
+	} else {
+		IDs, err = ff.FindIDsByUserID(req.Offset, req.Limit)
+	}
+	if err != nil {
+		return
+	}
+
+	res = &findFinancialFreezeRes{
+		Freezes: make([]financialFreeze, 0, len(IDs)),
+	}
+	for _, id := range IDs {
+		ff = datastore.FinancialFreeze{
+			ID: id,
+		}
+		err = ff.GetLastByID()
+		if err != nil {
+			return
+		}
+		res.Freezes = append(res.Freezes, financialFreeze{
+			ID:       ff.ID,
+			UserID:   ff.UserID,
+			CaseID:   ff.CaseID,
+			Currency: ff.Currency,
+			Amount:   ff.Amount,
+			Whole:    ff.Whole,
+			Status:   ff.Status,
+		})
+	}
+	return
+}
+
+func (req *findFinancialFreezeReq) validator() (err *er.Error) {
+	if req.Limit == 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	return
+}
+
+type financialFreeze struct {
+	ID       [32]byte `json:",string"`
+	UserID   [32]byte `json:",string"`
+	CaseID   [32]byte `json:",string"`
+	Currency uint16
+	Amount   price.Amount
+	Whole    bool
+	Status   datastore.FinancialFreezeStatus
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *findFinancialFreezeReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.UserID[:], buf[0:])
+	copy(req.CaseID[:], buf[32:])
+	req.Offset = syllab.GetUInt64(buf, 64)
+	req.Limit = syllab.GetUInt64(buf, 72)
+	return
+}
+
+func (req *findFinancialFreezeReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.UserID[:])
+	copy(buf[32:], req.CaseID[:])
+	syllab.SetUInt64(buf, 64, req.Offset)
+	syllab.SetUInt64(buf, 72, req.Limit)
+	return
+}
+
+func (req *findFinancialFreezeReq) syllabStackLen() (ln uint32) {
+	return 80
+}
+
+func (req *findFinancialFreezeReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *findFinancialFreezeReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *findFinancialFreezeReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *findFinancialFreezeReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *findFinancialFreezeReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *findFinancialFreezeRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Freezes, err = decodeFinancialFreezes(buf, 0)
+	return
+}
+
+func (res *findFinancialFreezeRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	encodeFinancialFreezes(buf, res.Freezes, 0, hsi)
+	return
+}
+
+func (res *findFinancialFreezeRes) syllabStackLen() (ln uint32) {
+	return 8 // fixed size data + variables data add&&len
+}
+
+func (res *findFinancialFreezeRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.Freezes)) * financialFreezeSyllabLen
+	return
+}
+
+func (res *findFinancialFreezeRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *findFinancialFreezeRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *findFinancialFreezeRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *findFinancialFreezeRes) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Freeze Encoders & Decoders
+*/
+
+// financialFreezeSyllabLen is fixed size of each freeze in heap.
+const financialFreezeSyllabLen uint32 = 108
+
+// decodeFinancialFreezes decode freezes slice that its add&&len store in given stack index.
+func decodeFinancialFreezes(buf []byte, stackIndex uint32) (freezes []financialFreeze, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(financialFreezeSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	freezes = make([]financialFreeze, ln)
+	for i := range freezes {
+		var freeze = &freezes[i]
+		var dbuf = buf[add+uint32(i)*financialFreezeSyllabLen:]
+		copy(freeze.ID[:], dbuf[0:])
+		copy(freeze.UserID[:], dbuf[32:])
+		copy(freeze.CaseID[:], dbuf[64:])
+		freeze.Currency = syllab.GetUInt16(dbuf, 96)
+		freeze.Amount = price.Amount(syllab.GetInt64(dbuf, 98))
+		freeze.Whole = dbuf[106] == 1
+		freeze.Status = datastore.FinancialFreezeStatus(syllab.GetUInt8(dbuf, 107))
+	}
+	return
+}
+
+// encodeFinancialFreezes encode freezes in heap from given heap index and its add&&len in given stack index.
+func encodeFinancialFreezes(buf []byte, freezes []financialFreeze, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(freezes)))
+	for i := range freezes {
+		var freeze = &freezes[i]
+		var dbuf = buf[hsi+uint32(i)*financialFreezeSyllabLen:]
+		copy(dbuf[0:], freeze.ID[:])
+		copy(dbuf[32:], freeze.UserID[:])
+		copy(dbuf[64:], freeze.CaseID[:])
+		syllab.SetUInt16(dbuf, 96, freeze.Currency)
+		syllab.SetInt64(dbuf, 98, int64(freeze.Amount))
+		if freeze.Whole {
+			dbuf[106] = 1
+		}
+		syllab.SetUInt8(dbuf, 107, uint8(freeze.Status))
+	}
+}
@@ -0,0 +1,324 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var findFinancialStandingOrderService = achaemenid.Service{
+	ID:                1733508862,
+	IssueDate:         1792294756,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Find Financial Standing Order",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `find standing orders that user pay or get paid by them.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: FindFinancialStandingOrderSRPC,
+	HTTPHandler: FindFinancialStandingOrderHTTP,
+}
+
+// FindFinancialStandingOrderSRPC is sRPC handler of FindFinancialStandingOrder service.
+func FindFinancialStandingOrderSRPC(st *achaemenid.Stream) {
+	var req = &findFinancialStandingOrderReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *findFinancialStandingOrderRes
+	res, st.Err = findFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// FindFinancialStandingOrderHTTP is HTTP handler of FindFinancialStandingOrder service.
+func FindFinancialStandingOrderHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &findFinancialStandingOrderReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *findFinancialStandingOrderRes
+	res, st.Err = findFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type findFinancialStandingOrderReq struct {
+	Incoming bool // Find orders that pay to user instead of orders that user pay
+	Offset   uint64
+	Limit    uint64
+}
+
+type findFinancialStandingOrderRes struct {
+	Orders []financialStandingOrder
+}
+
+func findFinancialStandingOrder(st *achaemenid.Stream, req *findFinancialStandingOrderReq) (res *findFinancialStandingOrderRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	var fso = datastore.FinancialStandingOrder{
+		FromUserID: st.Connection.UserID,
+		ToUserID:   st.Connection.UserID,
+	}
+	var IDs [][32]byte
+	if req.Incoming {
+		IDs, err = fso.FindIDsByToUserID(req.Offset, req.Limit)
+	} else {
+		IDs, err = fso.FindIDsByFromUserID(req.Offset, req.Limit)
+	}
+	if err != nil {
+		return
+	}
+
+	res = &findFinancialStandingOrderRes{
+		Orders: make([]financialStandingOrder, 0, len(IDs)),
+	}
+	for _, id := range IDs {
+		fso = datastore.FinancialStandingOrder{
+			ID: id,
+		}
+		err = fso.GetLastByID()
+		if err != nil {
+			return
+		}
+		res.Orders = append(res.Orders, financialStandingOrder{
+			ID:             fso.ID,
+			FromUserID:     fso.FromUserID,
+			ToUserID:       fso.ToUserID,
+			Amount:         fso.Amount,
+			PeriodDays:     fso.PeriodDays,
+			NextRunTime:    fso.NextRunTime,
+			EndTime:        fso.EndTime,
+			LastTransferID: fso.LastTransferID,
+			LastRunStatus:  fso.LastRunStatus,
+			Status:         fso.Status,
+		})
+	}
+	return
+}
+
+func (req *findFinancialStandingOrderReq) validator() (err *er.Error) {
+	if req.Limit == 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	return
+}
+
+type financialStandingOrder struct {
+	ID             [32]byte `json:",string"`
+	FromUserID     [32]byte `json:",string"`
+	ToUserID       [32]byte `json:",string"`
+	Amount         price.Amount
+	PeriodDays     uint32
+	NextRunTime    etime.Time
+	EndTime        etime.Time
+	LastTransferID [32]byte `json:",string"`
+	LastRunStatus  datastore.FinancialStandingOrderRunStatus
+	Status         datastore.FinancialStandingOrderStatus
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *findFinancialStandingOrderReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	req.Incoming = buf[0] == 1
+	req.Offset = syllab.GetUInt64(buf, 1)
+	req.Limit = syllab.GetUInt64(buf, 9)
+	return
+}
+
+func (req *findFinancialStandingOrderReq) syllabEncoder(buf []byte) {
+	if req.Incoming {
+		buf[0] = 1
+	}
+	syllab.SetUInt64(buf, 1, req.Offset)
+	syllab.SetUInt64(buf, 9, req.Limit)
+	return
+}
+
+func (req *findFinancialStandingOrderReq) syllabStackLen() (ln uint32) {
+	return 17
+}
+
+func (req *findFinancialStandingOrderReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *findFinancialStandingOrderReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *findFinancialStandingOrderReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *findFinancialStandingOrderReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *findFinancialStandingOrderReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *findFinancialStandingOrderRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Orders, err = decodeFinancialStandingOrders(buf, 0)
+	return
+}
+
+func (res *findFinancialStandingOrderRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	encodeFinancialStandingOrders(buf, res.Orders, 0, hsi)
+	return
+}
+
+func (res *findFinancialStandingOrderRes) syllabStackLen() (ln uint32) {
+	return 8 // fixed size data + variables data add&&len
+}
+
+func (res *findFinancialStandingOrderRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.Orders)) * financialStandingOrderSyllabLen
+	return
+}
+
+func (res *findFinancialStandingOrderRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *findFinancialStandingOrderRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *findFinancialStandingOrderRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *findFinancialStandingOrderRes) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Order Encoders & Decoders
+*/
+
+// financialStandingOrderSyllabLen is fixed size of each order in heap.
+const financialStandingOrderSyllabLen uint32 = 158
+
+// decodeFinancialStandingOrders decode orders slice that its add&&len store in given stack index.
+func decodeFinancialStandingOrders(buf []byte, stackIndex uint32) (orders []financialStandingOrder, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(financialStandingOrderSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	orders = make([]financialStandingOrder, ln)
+	for i := range orders {
+		var order = &orders[i]
+		var dbuf = buf[add+uint32(i)*financialStandingOrderSyllabLen:]
+		copy(order.ID[:], dbuf[0:])
+		copy(order.FromUserID[:], dbuf[32:])
+		copy(order.ToUserID[:], dbuf[64:])
+		order.Amount = price.Amount(syllab.GetInt64(dbuf, 96))
+		order.PeriodDays = syllab.GetUInt32(dbuf, 104)
+		order.NextRunTime = etime.Time(syllab.GetInt64(dbuf, 108))
+		order.EndTime = etime.Time(syllab.GetInt64(dbuf, 116))
+		copy(order.LastTransferID[:], dbuf[124:])
+		order.LastRunStatus = datastore.FinancialStandingOrderRunStatus(syllab.GetUInt8(dbuf, 156))
+		order.Status = datastore.FinancialStandingOrderStatus(syllab.GetUInt8(dbuf, 157))
+	}
+	return
+}
+
+// encodeFinancialStandingOrders encode orders in heap from given heap index and its add&&len in given stack index.
+func encodeFinancialStandingOrders(buf []byte, orders []financialStandingOrder, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(orders)))
+	for i := range orders {
+		var order = &orders[i]
+		var dbuf = buf[hsi+uint32(i)*financialStandingOrderSyllabLen:]
+		copy(dbuf[0:], order.ID[:])
+		copy(dbuf[32:], order.FromUserID[:])
+		copy(dbuf[64:], order.ToUserID[:])
+		syllab.SetInt64(dbuf, 96, int64(order.Amount))
+		syllab.SetUInt32(dbuf, 104, order.PeriodDays)
+		syllab.SetInt64(dbuf, 108, int64(order.NextRunTime))
+		syllab.SetInt64(dbuf, 116, int64(order.EndTime))
+		copy(dbuf[124:], order.LastTransferID[:])
+		syllab.SetUInt8(dbuf, 156, uint8(order.LastRunStatus))
+		syllab.SetUInt8(dbuf, 157, uint8(order.Status))
+	}
+}
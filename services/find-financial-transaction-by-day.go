@@ -86,6 +86,7 @@ type findFinancialTransactionByDayReq struct {
 	WriteTime etime.Time
 	Offset    uint64
 	Limit     uint64 `valid:"Limit[1:100]"`
+	Currency  uint16 `json:",optional"` // ISO 4217 numeric code. Zero is society default currency
 }
 
 type findFinancialTransactionByDayRes struct {
@@ -108,6 +109,7 @@ func findFinancialTransactionByDay(st *achaemenid.Stream, req *findFinancialTran
 	var ft = datastore.FinancialTransaction{
 		WriteTime: req.WriteTime,
 		UserID:    st.Connection.UserID,
+		Currency:  req.Currency,
 	}
 	res.IDs, err = ft.FindRecordIDsByUserIDWriteTime(req.Offset, req.Limit)
 	return
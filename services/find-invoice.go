@@ -0,0 +1,260 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var findInvoiceService = achaemenid.Service{
+	ID:                2508817346,
+	IssueDate:         1792296169,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Find Invoice",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `find invoice IDs of a buyer or a seller in a day or of a distribution center.`,
+	},
+	TAGS: []string{
+		"Product", "FinancialTransaction",
+	},
+
+	SRPCHandler: FindInvoiceSRPC,
+	HTTPHandler: FindInvoiceHTTP,
+}
+
+// FindInvoiceSRPC is sRPC handler of FindInvoice service.
+func FindInvoiceSRPC(st *achaemenid.Stream) {
+	var req = &findInvoiceReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *findInvoiceRes
+	res, st.Err = findInvoice(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// FindInvoiceHTTP is HTTP handler of FindInvoice service.
+func FindInvoiceHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &findInvoiceReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *findInvoiceRes
+	res, st.Err = findInvoice(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type findInvoiceReq struct {
+	BuyerID  [32]byte   `json:",string,optional"` // Empty all of BuyerID, SellerID and DCID means requester invoices as buyer
+	SellerID [32]byte   `json:",string,optional"`
+	DCID     [32]byte   `json:",string,optional"` // DC invoices not divide by days, so Day not use
+	Day      etime.Time `json:",optional"`        // Zero means today
+	Offset   uint64
+	Limit    uint64
+}
+
+type findInvoiceRes struct {
+	IDs [][32]byte `json:",string"`
+}
+
+func findInvoice(st *achaemenid.Stream, req *findInvoiceReq) (res *findInvoiceRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	var userID = req.BuyerID
+	switch {
+	case req.DCID != [32]byte{}:
+		userID = req.DCID
+	case req.SellerID != [32]byte{}:
+		userID = req.SellerID
+	case req.BuyerID == [32]byte{}:
+		req.BuyerID = st.Connection.UserID
+		userID = req.BuyerID
+	}
+	if st.Connection.UserID != userID && st.Connection.UserID != adminUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var inv = datastore.Invoice{
+		BuyerID:   req.BuyerID,
+		SellerID:  req.SellerID,
+		WriteTime: req.Day,
+	}
+	res = &findInvoiceRes{}
+	switch {
+	case req.DCID != [32]byte{}:
+		res.IDs, err = inv.FindIDsByDCID(req.DCID, req.Offset, req.Limit)
+	case req.SellerID != [32]byte{}:
+		res.IDs, err = inv.FindIDsBySellerIDDaily(req.Offset, req.Limit)
+	default:
+		res.IDs, err = inv.FindIDsByBuyerIDDaily(req.Offset, req.Limit)
+	}
+	if err != nil {
+		res = nil
+	}
+	return
+}
+
+func (req *findInvoiceReq) validator() (err *er.Error) {
+	if req.Limit == 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	if req.Day == 0 {
+		req.Day = etime.Now()
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *findInvoiceReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.BuyerID[:], buf[0:])
+	copy(req.SellerID[:], buf[32:])
+	copy(req.DCID[:], buf[64:])
+	req.Day = etime.Time(syllab.GetInt64(buf, 96))
+	req.Offset = syllab.GetUInt64(buf, 104)
+	req.Limit = syllab.GetUInt64(buf, 112)
+	return
+}
+
+func (req *findInvoiceReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.BuyerID[:])
+	copy(buf[32:], req.SellerID[:])
+	copy(buf[64:], req.DCID[:])
+	syllab.SetInt64(buf, 96, int64(req.Day))
+	syllab.SetUInt64(buf, 104, req.Offset)
+	syllab.SetUInt64(buf, 112, req.Limit)
+	return
+}
+
+func (req *findInvoiceReq) syllabStackLen() (ln uint32) {
+	return 120
+}
+
+func (req *findInvoiceReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *findInvoiceReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *findInvoiceReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *findInvoiceReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *findInvoiceReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *findInvoiceRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.IDs = syllab.UnsafeGet32ByteArraySlice(buf, 0)
+	return
+}
+
+func (res *findInvoiceRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	syllab.Set32ByteArrayArray(buf, res.IDs, 0, hsi)
+	return
+}
+
+func (res *findInvoiceRes) syllabStackLen() (ln uint32) {
+	return 8 // fixed size data + variables data add&&len
+}
+
+func (res *findInvoiceRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.IDs) * 32)
+	return
+}
+
+func (res *findInvoiceRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *findInvoiceRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *findInvoiceRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *findInvoiceRes) jsonLen() (ln int) {
+	return
+}
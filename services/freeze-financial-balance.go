@@ -0,0 +1,290 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+var freezeFinancialBalanceService = achaemenid.Service{
+	ID:                2617409853,
+	IssueDate:         1792295516,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDCreate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Freeze Financial Balance",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `Just justice department can freeze all or part of a user balance by a justice case. Frozen amount withdraw from user balance by a blocked transaction.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: FreezeFinancialBalanceSRPC,
+	HTTPHandler: FreezeFinancialBalanceHTTP,
+}
+
+// FreezeFinancialBalanceSRPC is sRPC handler of FreezeFinancialBalance service.
+func FreezeFinancialBalanceSRPC(st *achaemenid.Stream) {
+	var req = &freezeFinancialBalanceReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *freezeFinancialBalanceRes
+	res, st.Err = freezeFinancialBalance(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// FreezeFinancialBalanceHTTP is HTTP handler of FreezeFinancialBalance service.
+func FreezeFinancialBalanceHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &freezeFinancialBalanceReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *freezeFinancialBalanceRes
+	res, st.Err = freezeFinancialBalance(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type freezeFinancialBalanceReq struct {
+	UserID   [32]byte     `json:",string"`
+	CaseID   [32]byte     `json:",string"`   // Justice case that order the freeze
+	Currency uint16       `json:",optional"` // ISO 4217 numeric code. Zero is society default currency
+	Amount   price.Amount `json:",optional"` // Zero means freeze whole account
+}
+
+type freezeFinancialBalanceRes struct {
+	ID     [32]byte     `json:",string"`
+	Amount price.Amount // Frozen amount that withdraw from user balance
+}
+
+func freezeFinancialBalance(st *achaemenid.Stream, req *freezeFinancialBalanceReq) (res *freezeFinancialBalanceRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+	if st.Connection.UserID != justiceUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var ff = datastore.FinancialFreeze{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		ID:       uuid.Random32Byte(),
+		UserID:   req.UserID,
+		CaseID:   req.CaseID,
+		Currency: req.Currency,
+		Amount:   req.Amount,
+		Status:   datastore.FinancialFreezeFrozen,
+	}
+	if req.Amount == 0 {
+		ff.Whole = true
+		var last = datastore.FinancialTransaction{
+			UserID:    req.UserID,
+			WriteTime: etime.Now(),
+			Currency:  req.Currency,
+		}
+		err = last.GetLastTransactionByUserID()
+		if err == nil {
+			ff.Amount = last.Balance
+		} else if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+		} else {
+			return
+		}
+	}
+
+	var block datastore.FinancialTransaction
+	if ff.Amount > 0 {
+		block = datastore.FinancialTransaction{
+			AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+			UserID:        ff.UserID,
+			ReferenceID:   ff.ID,
+			ReferenceType: datastore.FinancialTransactionBlocked,
+			Amount:        -ff.Amount,
+			Currency:      ff.Currency,
+		}
+		err = saveFinancialTransaction(&block)
+		if err != nil {
+			return
+		}
+	}
+
+	err = ff.SaveNew()
+	if err != nil {
+		if ff.Amount > 0 {
+			var reverseErr = reverseFinancialTransaction(&block)
+			if reverseErr != nil {
+				log.Warn("Financial freeze block", block.RecordID, "can't reverse due to:", reverseErr)
+			}
+		}
+		return
+	}
+
+	res = &freezeFinancialBalanceRes{
+		ID:     ff.ID,
+		Amount: ff.Amount,
+	}
+	return
+}
+
+func (req *freezeFinancialBalanceReq) validator() (err *er.Error) {
+	if req.Amount < 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+	if req.UserID == [32]byte{} || req.CaseID == [32]byte{} {
+		err = ErrFinancialFreezeNoCase
+		return
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *freezeFinancialBalanceReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.UserID[:], buf[0:])
+	copy(req.CaseID[:], buf[32:])
+	req.Currency = syllab.GetUInt16(buf, 64)
+	req.Amount = price.Amount(syllab.GetInt64(buf, 66))
+	return
+}
+
+func (req *freezeFinancialBalanceReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.UserID[:])
+	copy(buf[32:], req.CaseID[:])
+	syllab.SetUInt16(buf, 64, req.Currency)
+	syllab.SetInt64(buf, 66, int64(req.Amount))
+	return
+}
+
+func (req *freezeFinancialBalanceReq) syllabStackLen() (ln uint32) {
+	return 74
+}
+
+func (req *freezeFinancialBalanceReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *freezeFinancialBalanceReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *freezeFinancialBalanceReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *freezeFinancialBalanceReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *freezeFinancialBalanceReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *freezeFinancialBalanceRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.ID[:], buf[0:])
+	res.Amount = price.Amount(syllab.GetInt64(buf, 32))
+	return
+}
+
+func (res *freezeFinancialBalanceRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.ID[:])
+	syllab.SetInt64(buf, 32, int64(res.Amount))
+	return
+}
+
+func (res *freezeFinancialBalanceRes) syllabStackLen() (ln uint32) {
+	return 40
+}
+
+func (res *freezeFinancialBalanceRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *freezeFinancialBalanceRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *freezeFinancialBalanceRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *freezeFinancialBalanceRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *freezeFinancialBalanceRes) jsonLen() (ln int) {
+	return
+}
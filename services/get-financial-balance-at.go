@@ -0,0 +1,210 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var getFinancialBalanceAtService = achaemenid.Service{
+	ID:                3408170521,
+	IssueDate:         1792295394,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Financial Balance At",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return user balance in given currency at given time by use of daily balance snapshots.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: GetFinancialBalanceAtSRPC,
+	HTTPHandler: GetFinancialBalanceAtHTTP,
+}
+
+// GetFinancialBalanceAtSRPC is sRPC handler of GetFinancialBalanceAt service.
+func GetFinancialBalanceAtSRPC(st *achaemenid.Stream) {
+	var req = &getFinancialBalanceAtReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getFinancialBalanceAtRes
+	res, st.Err = getFinancialBalanceAt(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetFinancialBalanceAtHTTP is HTTP handler of GetFinancialBalanceAt service.
+func GetFinancialBalanceAtHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getFinancialBalanceAtReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getFinancialBalanceAtRes
+	res, st.Err = getFinancialBalanceAt(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type getFinancialBalanceAtReq struct {
+	UserID   [32]byte   `json:",string,optional"` // Empty means requester user. Just admin can request other users balance
+	Currency uint16     `json:",optional"`        // ISO 4217 numeric code. Zero is society default currency
+	Time     etime.Time // Balance after all transactions that wrote until this time
+}
+
+type getFinancialBalanceAtRes struct {
+	Balance price.Amount
+}
+
+func getFinancialBalanceAt(st *achaemenid.Stream, req *getFinancialBalanceAtReq) (res *getFinancialBalanceAtRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	if req.UserID == [32]byte{} {
+		req.UserID = st.Connection.UserID
+	} else if req.UserID != st.Connection.UserID && st.Connection.UserID != adminUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	res = &getFinancialBalanceAtRes{}
+	res.Balance, err = findFinancialBalanceAt(req.UserID, req.Currency, req.Time)
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getFinancialBalanceAtReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.UserID[:], buf[0:])
+	req.Currency = syllab.GetUInt16(buf, 32)
+	req.Time = etime.Time(syllab.GetInt64(buf, 34))
+	return
+}
+
+func (req *getFinancialBalanceAtReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.UserID[:])
+	syllab.SetUInt16(buf, 32, req.Currency)
+	syllab.SetInt64(buf, 34, int64(req.Time))
+	return
+}
+
+func (req *getFinancialBalanceAtReq) syllabStackLen() (ln uint32) {
+	return 42
+}
+
+func (req *getFinancialBalanceAtReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getFinancialBalanceAtReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getFinancialBalanceAtReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getFinancialBalanceAtReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getFinancialBalanceAtReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getFinancialBalanceAtRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Balance = price.Amount(syllab.GetInt64(buf, 0))
+	return
+}
+
+func (res *getFinancialBalanceAtRes) syllabEncoder(buf []byte) {
+	syllab.SetInt64(buf, 0, int64(res.Balance))
+	return
+}
+
+func (res *getFinancialBalanceAtRes) syllabStackLen() (ln uint32) {
+	return 8
+}
+
+func (res *getFinancialBalanceAtRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *getFinancialBalanceAtRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getFinancialBalanceAtRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getFinancialBalanceAtRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getFinancialBalanceAtRes) jsonLen() (ln int) {
+	return
+}
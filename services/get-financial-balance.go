@@ -0,0 +1,228 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var getFinancialBalanceService = achaemenid.Service{
+	ID:                2051476983,
+	IssueDate:         1792294530,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Financial Balance",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return user available balance and amounts that held in escrows or frozen by justice department separately.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: GetFinancialBalanceSRPC,
+	HTTPHandler: GetFinancialBalanceHTTP,
+}
+
+// GetFinancialBalanceSRPC is sRPC handler of GetFinancialBalance service.
+func GetFinancialBalanceSRPC(st *achaemenid.Stream) {
+	var req = &getFinancialBalanceReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getFinancialBalanceRes
+	res, st.Err = getFinancialBalance(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetFinancialBalanceHTTP is HTTP handler of GetFinancialBalance service.
+func GetFinancialBalanceHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getFinancialBalanceReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getFinancialBalanceRes
+	res, st.Err = getFinancialBalance(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type getFinancialBalanceReq struct {
+	Currency uint16 `json:",optional"` // ISO 4217 numeric code. Zero is society default currency
+}
+
+type getFinancialBalanceRes struct {
+	Available price.Amount
+	Held      price.Amount // Sum of not decided escrows
+	Frozen    price.Amount // Sum of amounts that justice department freeze
+}
+
+func getFinancialBalance(st *achaemenid.Stream, req *getFinancialBalanceReq) (res *getFinancialBalanceRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	res = &getFinancialBalanceRes{}
+
+	var last = datastore.FinancialTransaction{
+		UserID:    st.Connection.UserID,
+		WriteTime: etime.Now(),
+		Currency:  req.Currency,
+	}
+	err = last.GetLastTransactionByUserID()
+	if err == nil {
+		res.Available = last.Balance
+	} else if err.Equal(ganjine.ErrRecordNotFound) {
+		err = nil
+	} else {
+		return
+	}
+
+	res.Frozen, err = getFinancialFrozenBalance(st.Connection.UserID, req.Currency)
+	if err != nil {
+		return
+	}
+
+	// Escrows are just in default currency.
+	if req.Currency == 0 {
+		res.Held, err = getFinancialEscrowHeldBalance(st.Connection.UserID)
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getFinancialBalanceReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	req.Currency = syllab.GetUInt16(buf, 0)
+	return
+}
+
+func (req *getFinancialBalanceReq) syllabEncoder(buf []byte) {
+	syllab.SetUInt16(buf, 0, req.Currency)
+	return
+}
+
+func (req *getFinancialBalanceReq) syllabStackLen() (ln uint32) {
+	return 2
+}
+
+func (req *getFinancialBalanceReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getFinancialBalanceReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getFinancialBalanceReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getFinancialBalanceReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getFinancialBalanceReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getFinancialBalanceRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Available = price.Amount(syllab.GetInt64(buf, 0))
+	res.Held = price.Amount(syllab.GetInt64(buf, 8))
+	res.Frozen = price.Amount(syllab.GetInt64(buf, 16))
+	return
+}
+
+func (res *getFinancialBalanceRes) syllabEncoder(buf []byte) {
+	syllab.SetInt64(buf, 0, int64(res.Available))
+	syllab.SetInt64(buf, 8, int64(res.Held))
+	syllab.SetInt64(buf, 16, int64(res.Frozen))
+	return
+}
+
+func (res *getFinancialBalanceRes) syllabStackLen() (ln uint32) {
+	return 24
+}
+
+func (res *getFinancialBalanceRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *getFinancialBalanceRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getFinancialBalanceRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getFinancialBalanceRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getFinancialBalanceRes) jsonLen() (ln int) {
+	return
+}
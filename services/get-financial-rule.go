@@ -0,0 +1,230 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var getFinancialRuleService = achaemenid.Service{
+	ID:                1318870925,
+	IssueDate:         1792294891,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Financial Rule",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return active spending limits of the user and how much of daily limits used today.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: GetFinancialRuleSRPC,
+	HTTPHandler: GetFinancialRuleHTTP,
+}
+
+// GetFinancialRuleSRPC is sRPC handler of GetFinancialRule service.
+func GetFinancialRuleSRPC(st *achaemenid.Stream) {
+	var req = &getFinancialRuleReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getFinancialRuleRes
+	res, st.Err = getFinancialRule(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetFinancialRuleHTTP is HTTP handler of GetFinancialRule service.
+func GetFinancialRuleHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getFinancialRuleReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getFinancialRuleRes
+	res, st.Err = getFinancialRule(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type getFinancialRuleReq struct {
+}
+
+type getFinancialRuleRes struct {
+	DailyLimit         price.Amount // Zero means no limit
+	TransactionLimit   price.Amount // Zero means no limit
+	DailyCountLimit    uint32       // Zero means no limit
+	DelegateCoolingOff uint32       // Second
+	OTPStepUp          bool
+	DailySpent         price.Amount
+	DailyCount         uint32
+}
+
+func getFinancialRule(st *achaemenid.Stream, req *getFinancialRuleReq) (res *getFinancialRuleRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	res = &getFinancialRuleRes{}
+
+	var fr = datastore.FinancialRule{
+		UserID: st.Connection.UserID,
+	}
+	err = fr.GetLastByUserID()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		// User has no rule, so no limit exist.
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	res.DailyLimit = fr.DailyLimit
+	res.TransactionLimit = fr.TransactionLimit
+	res.DailyCountLimit = fr.DailyCountLimit
+	res.DelegateCoolingOff = fr.DelegateCoolingOff
+	res.OTPStepUp = fr.OTPStepUp
+	res.DailySpent, res.DailyCount, err = getFinancialDailySpent(st.Connection.UserID, etime.Now())
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getFinancialRuleReq) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (req *getFinancialRuleReq) syllabEncoder(buf []byte) {
+	return
+}
+
+func (req *getFinancialRuleReq) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (req *getFinancialRuleReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getFinancialRuleReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getFinancialRuleReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getFinancialRuleReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getFinancialRuleReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getFinancialRuleRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.DailyLimit = price.Amount(syllab.GetInt64(buf, 0))
+	res.TransactionLimit = price.Amount(syllab.GetInt64(buf, 8))
+	res.DailyCountLimit = syllab.GetUInt32(buf, 16)
+	res.DelegateCoolingOff = syllab.GetUInt32(buf, 20)
+	res.OTPStepUp = buf[24] == 1
+	res.DailySpent = price.Amount(syllab.GetInt64(buf, 25))
+	res.DailyCount = syllab.GetUInt32(buf, 33)
+	return
+}
+
+func (res *getFinancialRuleRes) syllabEncoder(buf []byte) {
+	syllab.SetInt64(buf, 0, int64(res.DailyLimit))
+	syllab.SetInt64(buf, 8, int64(res.TransactionLimit))
+	syllab.SetUInt32(buf, 16, res.DailyCountLimit)
+	syllab.SetUInt32(buf, 20, res.DelegateCoolingOff)
+	if res.OTPStepUp {
+		buf[24] = 1
+	}
+	syllab.SetInt64(buf, 25, int64(res.DailySpent))
+	syllab.SetUInt32(buf, 33, res.DailyCount)
+	return
+}
+
+func (res *getFinancialRuleRes) syllabStackLen() (ln uint32) {
+	return 37
+}
+
+func (res *getFinancialRuleRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *getFinancialRuleRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getFinancialRuleRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getFinancialRuleRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getFinancialRuleRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,408 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+const (
+	financialStatementMaxDays         = 366
+	financialStatementMaxTransactions = 10000
+	financialStatementPageLimit       = 100
+)
+
+var getFinancialTransactionStatementService = achaemenid.Service{
+	ID:                2483019756,
+	IssueDate:         1792294231,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Financial Transaction Statement",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return user financial transactions in given period with opening and closing balance.
+HTTP clients can request statement as CSV or printable HTML in desire language.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: GetFinancialTransactionStatementSRPC,
+	HTTPHandler: GetFinancialTransactionStatementHTTP,
+}
+
+// GetFinancialTransactionStatementSRPC is sRPC handler of GetFinancialTransactionStatement service.
+func GetFinancialTransactionStatementSRPC(st *achaemenid.Stream) {
+	var req = &getFinancialTransactionStatementReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getFinancialTransactionStatementRes
+	res, st.Err = getFinancialTransactionStatement(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetFinancialTransactionStatementHTTP is HTTP handler of GetFinancialTransactionStatement service.
+func GetFinancialTransactionStatementHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getFinancialTransactionStatementReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getFinancialTransactionStatementRes
+	res, st.Err = getFinancialTransactionStatement(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	switch req.Format {
+	case financialStatementFormatCSV:
+		httpRes.Header.Set(http.HeaderKeyContentType, "text/csv; charset=utf-8")
+		httpRes.Body = res.csvEncoder(req.Language)
+	case financialStatementFormatHTML:
+		httpRes.Header.Set(http.HeaderKeyContentType, "text/html; charset=utf-8")
+		httpRes.Body = res.htmlEncoder(req.Language)
+	default:
+		httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+		httpRes.Body = res.jsonEncoder()
+	}
+}
+
+type getFinancialTransactionStatementReq struct {
+	FromTime etime.Time
+	ToTime   etime.Time `json:",optional"` // Zero means now
+	Format   financialStatementFormat
+	Language lang.Language // Language of CSV and HTML formats
+	Currency uint16        `json:",optional"` // ISO 4217 numeric code. Zero is society default currency
+}
+
+type getFinancialTransactionStatementRes struct {
+	UserID         [32]byte `json:",string"`
+	Currency       uint16
+	FromTime       etime.Time
+	ToTime         etime.Time
+	OpeningBalance price.Amount
+	ClosingBalance price.Amount
+	Transactions   []financialStatementTransaction
+}
+
+type financialStatementTransaction struct {
+	ID            [32]byte `json:",string"`
+	WriteTime     etime.Time
+	ReferenceID   [32]byte `json:",string"`
+	ReferenceType datastore.FinancialTransactionType
+	Amount        price.Amount
+	Balance       price.Amount
+}
+
+func getFinancialTransactionStatement(st *achaemenid.Stream, req *getFinancialTransactionStatementReq) (res *getFinancialTransactionStatementRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	res = &getFinancialTransactionStatementRes{
+		UserID:   st.Connection.UserID,
+		Currency: req.Currency,
+		FromTime: req.FromTime,
+		ToTime:   req.ToTime,
+	}
+	res.Transactions, err = findFinancialStatementTransactions(st.Connection.UserID, req.Currency, req.FromTime, req.ToTime)
+	if err != nil {
+		return
+	}
+
+	err = res.setBalances(findFinancialBalanceAt)
+	return
+}
+
+// setBalances set opening and closing balances of the statement by its transactions or by balance at period start
+// if no transaction wrote in the period.
+func (res *getFinancialTransactionStatementRes) setBalances(balanceAt func(userID [32]byte, currency uint16, at etime.Time) (price.Amount, *er.Error)) (err *er.Error) {
+	if len(res.Transactions) > 0 {
+		var first = res.Transactions[0]
+		res.OpeningBalance = first.Balance - first.Amount
+		res.ClosingBalance = res.Transactions[len(res.Transactions)-1].Balance
+		return
+	}
+
+	// No transaction in the period, so balance not change in the period.
+	res.OpeningBalance, err = balanceAt(res.UserID, res.Currency, res.FromTime)
+	if err != nil {
+		return
+	}
+	res.ClosingBalance = res.OpeningBalance
+	return
+}
+
+// findFinancialStatementTransactions return userID transactions in given currency that wrote in fromTime to toTime period in write order.
+func findFinancialStatementTransactions(userID [32]byte, currency uint16, fromTime, toTime etime.Time) (transactions []financialStatementTransaction, err *er.Error) {
+	var day = datastore.FinancialTransaction{
+		UserID:    userID,
+		WriteTime: etime.Time(fromTime.RoundToDay()),
+		Currency:  currency,
+	}
+	// Walk days by their start, so a period that end before fromTime hour in the last day still read the last day.
+	var lastDay = etime.Time(toTime.RoundToDay())
+	for ; day.WriteTime <= lastDay; day.WriteTime += (24 * 60 * 60) {
+		var offset uint64
+		for {
+			var IDs [][32]byte
+			IDs, err = day.FindRecordIDsByUserIDWriteTime(offset, financialStatementPageLimit)
+			if err.Equal(ganjine.ErrRecordNotFound) {
+				err = nil
+				break
+			}
+			if err != nil {
+				return
+			}
+
+			for _, id := range IDs {
+				var ft = datastore.FinancialTransaction{
+					RecordID: id,
+				}
+				err = ft.GetByRecordID()
+				if err != nil {
+					return
+				}
+				if ft.WriteTime < fromTime || ft.WriteTime > toTime {
+					continue
+				}
+				if len(transactions) == financialStatementMaxTransactions {
+					err = ErrFinancialStatementTooLarge
+					return
+				}
+				transactions = append(transactions, financialStatementTransaction{
+					ID:            ft.RecordID,
+					WriteTime:     ft.WriteTime,
+					ReferenceID:   ft.ReferenceID,
+					ReferenceType: ft.ReferenceType,
+					Amount:        ft.Amount,
+					Balance:       ft.Balance,
+				})
+			}
+
+			if len(IDs) < financialStatementPageLimit {
+				break
+			}
+			offset += financialStatementPageLimit
+		}
+	}
+	return
+}
+
+func (req *getFinancialTransactionStatementReq) validator() (err *er.Error) {
+	if req.ToTime == 0 {
+		req.ToTime = etime.Now()
+	}
+	if req.FromTime > req.ToTime || req.ToTime-req.FromTime > financialStatementMaxDays*(24*60*60) {
+		err = ErrFinancialTransactionBadPeriod
+		return
+	}
+	if req.Format > financialStatementFormatHTML {
+		err = ErrFinancialStatementBadFormat
+		return
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getFinancialTransactionStatementReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	req.FromTime = etime.Time(syllab.GetInt64(buf, 0))
+	req.ToTime = etime.Time(syllab.GetInt64(buf, 8))
+	req.Format = financialStatementFormat(syllab.GetUInt8(buf, 16))
+	req.Language = lang.Language(syllab.GetUInt32(buf, 17))
+	req.Currency = syllab.GetUInt16(buf, 21)
+	return
+}
+
+func (req *getFinancialTransactionStatementReq) syllabEncoder(buf []byte) {
+	syllab.SetInt64(buf, 0, int64(req.FromTime))
+	syllab.SetInt64(buf, 8, int64(req.ToTime))
+	syllab.SetUInt8(buf, 16, uint8(req.Format))
+	syllab.SetUInt32(buf, 17, uint32(req.Language))
+	syllab.SetUInt16(buf, 21, req.Currency)
+	return
+}
+
+func (req *getFinancialTransactionStatementReq) syllabStackLen() (ln uint32) {
+	return 23
+}
+
+func (req *getFinancialTransactionStatementReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getFinancialTransactionStatementReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getFinancialTransactionStatementReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getFinancialTransactionStatementReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getFinancialTransactionStatementReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getFinancialTransactionStatementRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.UserID[:], buf[0:])
+	res.Currency = syllab.GetUInt16(buf, 32)
+	res.FromTime = etime.Time(syllab.GetInt64(buf, 34))
+	res.ToTime = etime.Time(syllab.GetInt64(buf, 42))
+	res.OpeningBalance = price.Amount(syllab.GetInt64(buf, 50))
+	res.ClosingBalance = price.Amount(syllab.GetInt64(buf, 58))
+	res.Transactions, err = decodeFinancialStatementTransactions(buf, 66)
+	return
+}
+
+func (res *getFinancialTransactionStatementRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	copy(buf[0:], res.UserID[:])
+	syllab.SetUInt16(buf, 32, res.Currency)
+	syllab.SetInt64(buf, 34, int64(res.FromTime))
+	syllab.SetInt64(buf, 42, int64(res.ToTime))
+	syllab.SetInt64(buf, 50, int64(res.OpeningBalance))
+	syllab.SetInt64(buf, 58, int64(res.ClosingBalance))
+	encodeFinancialStatementTransactions(buf, res.Transactions, 66, hsi)
+	return
+}
+
+func (res *getFinancialTransactionStatementRes) syllabStackLen() (ln uint32) {
+	return 74 // fixed size data + variables data add&&len
+}
+
+func (res *getFinancialTransactionStatementRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.Transactions)) * financialStatementTransactionSyllabLen
+	return
+}
+
+func (res *getFinancialTransactionStatementRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getFinancialTransactionStatementRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getFinancialTransactionStatementRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getFinancialTransactionStatementRes) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Transaction Encoders & Decoders
+*/
+
+// financialStatementTransactionSyllabLen is fixed size of each transaction in heap.
+const financialStatementTransactionSyllabLen uint32 = 89
+
+// decodeFinancialStatementTransactions decode transactions slice that its add&&len store in given stack index.
+func decodeFinancialStatementTransactions(buf []byte, stackIndex uint32) (transactions []financialStatementTransaction, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(financialStatementTransactionSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	transactions = make([]financialStatementTransaction, ln)
+	for i := range transactions {
+		var transaction = &transactions[i]
+		var dbuf = buf[add+uint32(i)*financialStatementTransactionSyllabLen:]
+		copy(transaction.ID[:], dbuf[0:])
+		transaction.WriteTime = etime.Time(syllab.GetInt64(dbuf, 32))
+		copy(transaction.ReferenceID[:], dbuf[40:])
+		transaction.ReferenceType = datastore.FinancialTransactionType(syllab.GetUInt8(dbuf, 72))
+		transaction.Amount = price.Amount(syllab.GetInt64(dbuf, 73))
+		transaction.Balance = price.Amount(syllab.GetInt64(dbuf, 81))
+	}
+	return
+}
+
+// encodeFinancialStatementTransactions encode transactions in heap from given heap index and its add&&len in given stack index.
+func encodeFinancialStatementTransactions(buf []byte, transactions []financialStatementTransaction, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(transactions)))
+	for i := range transactions {
+		var transaction = &transactions[i]
+		var dbuf = buf[hsi+uint32(i)*financialStatementTransactionSyllabLen:]
+		copy(dbuf[0:], transaction.ID[:])
+		syllab.SetInt64(dbuf, 32, int64(transaction.WriteTime))
+		copy(dbuf[40:], transaction.ReferenceID[:])
+		syllab.SetUInt8(dbuf, 72, uint8(transaction.ReferenceType))
+		syllab.SetInt64(dbuf, 73, int64(transaction.Amount))
+		syllab.SetInt64(dbuf, 81, int64(transaction.Balance))
+	}
+}
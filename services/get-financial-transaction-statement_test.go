@@ -0,0 +1,82 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/price"
+)
+
+func TestGetFinancialTransactionStatementRes_SetBalances(t *testing.T) {
+	var userID = [32]byte{1}
+	var dayBefore = etime.Now() - 2*financialSnapshotDay
+	// balances is balance of the user after each change time.
+	var balances = []struct {
+		at      etime.Time
+		balance price.Amount
+	}{
+		{dayBefore - 10*financialSnapshotDay, 500},
+		{dayBefore - 5*financialSnapshotDay, 700},
+		{dayBefore, 900},
+	}
+	var balanceAt = func(id [32]byte, currency uint16, at etime.Time) (balance price.Amount, err *er.Error) {
+		if id != userID || currency != 0 {
+			return 0, nil
+		}
+		for _, b := range balances {
+			if b.at <= at {
+				balance = b.balance
+			}
+		}
+		return
+	}
+
+	var tests = []struct {
+		name        string
+		res         getFinancialTransactionStatementRes
+		wantOpening price.Amount
+		wantClosing price.Amount
+	}{
+		{"empty period in the past", getFinancialTransactionStatementRes{
+			UserID:   userID,
+			FromTime: dayBefore - 8*financialSnapshotDay,
+			ToTime:   dayBefore - 6*financialSnapshotDay,
+		}, 500, 500},
+		{"empty period after last change", getFinancialTransactionStatementRes{
+			UserID:   userID,
+			FromTime: dayBefore + 1,
+			ToTime:   etime.Now(),
+		}, 900, 900},
+		{"empty period before first change", getFinancialTransactionStatementRes{
+			UserID:   userID,
+			FromTime: dayBefore - 20*financialSnapshotDay,
+			ToTime:   dayBefore - 15*financialSnapshotDay,
+		}, 0, 0},
+		{"other currency", getFinancialTransactionStatementRes{
+			UserID:   userID,
+			Currency: 840,
+			FromTime: dayBefore - 8*financialSnapshotDay,
+			ToTime:   dayBefore - 6*financialSnapshotDay,
+		}, 0, 0},
+		{"period with transactions", getFinancialTransactionStatementRes{
+			UserID:   userID,
+			FromTime: dayBefore - 6*financialSnapshotDay,
+			ToTime:   dayBefore,
+			Transactions: []financialStatementTransaction{
+				{WriteTime: dayBefore - 5*financialSnapshotDay, Amount: 200, Balance: 700},
+				{WriteTime: dayBefore, Amount: 200, Balance: 900},
+			},
+		}, 500, 900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err = tt.res.setBalances(balanceAt)
+			if err != nil || tt.res.OpeningBalance != tt.wantOpening || tt.res.ClosingBalance != tt.wantClosing {
+				t.Errorf("setBalances() = %v, %v, %v, want %v, %v", tt.res.OpeningBalance, tt.res.ClosingBalance, err, tt.wantOpening, tt.wantClosing)
+			}
+		})
+	}
+}
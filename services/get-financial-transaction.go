@@ -95,6 +95,7 @@ type getFinancialTransactionRes struct {
 	PreviousTransactionID [32]byte `json:",string"`
 	Amount                price.Amount
 	Balance               price.Amount
+	Currency              uint16 // ISO 4217 numeric code. Zero is society default currency
 }
 
 func getFinancialTransaction(st *achaemenid.Stream, req *getFinancialTransactionReq) (res *getFinancialTransactionRes, err *er.Error) {
@@ -124,6 +125,7 @@ func getFinancialTransaction(st *achaemenid.Stream, req *getFinancialTransaction
 		PreviousTransactionID: ft.PreviousTransactionID,
 		Amount:                ft.Amount,
 		Balance:               ft.Balance,
+		Currency:              ft.Currency,
 	}
 	return
 }
@@ -213,6 +215,7 @@ func (res *getFinancialTransactionRes) syllabDecoder(buf []byte) (err *er.Error)
 	copy(res.PreviousTransactionID[:], buf[97:])
 	res.Amount = price.Amount(syllab.GetInt64(buf, 129))
 	res.Balance = price.Amount(syllab.GetInt64(buf, 137))
+	res.Currency = syllab.GetUInt16(buf, 145)
 	return
 }
 
@@ -224,11 +227,12 @@ func (res *getFinancialTransactionRes) syllabEncoder(buf []byte) {
 	copy(buf[97:], res.PreviousTransactionID[:])
 	syllab.SetInt64(buf, 129, int64(res.Amount))
 	syllab.SetInt64(buf, 137, int64(res.Balance))
+	syllab.SetUInt16(buf, 145, res.Currency)
 	return
 }
 
 func (res *getFinancialTransactionRes) syllabStackLen() (ln uint32) {
-	return 145
+	return 147
 }
 
 func (res *getFinancialTransactionRes) syllabHeapLen() (ln uint32) {
@@ -266,6 +270,8 @@ func (res *getFinancialTransactionRes) jsonDecoder(buf []byte) (err *er.Error) {
 			var num int64
 			num, err = decoder.DecodeInt64()
 			res.Balance = price.Amount(num)
+		case "Currency":
+			res.Currency, err = decoder.DecodeUInt16()
 		default:
 			err = decoder.NotFoundKeyStrict()
 		}
@@ -303,11 +309,14 @@ func (res *getFinancialTransactionRes) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`,"Balance":`)
 	encoder.EncodeInt64(int64(res.Balance))
 
+	encoder.EncodeString(`,"Currency":`)
+	encoder.EncodeUInt16(res.Currency)
+
 	encoder.EncodeByte('}')
 	return encoder.Buf
 }
 
 func (res *getFinancialTransactionRes) jsonLen() (ln int) {
-	ln = 356
+	ln = 373
 	return
 }
@@ -0,0 +1,348 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var getInvoiceService = achaemenid.Service{
+	ID:                1740392685,
+	IssueDate:         1792296169,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Invoice",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return an invoice with its lines. Just buyer, seller and DCs of the invoice lines can get it.`,
+	},
+	TAGS: []string{
+		"Product", "FinancialTransaction",
+	},
+
+	SRPCHandler: GetInvoiceSRPC,
+	HTTPHandler: GetInvoiceHTTP,
+}
+
+// GetInvoiceSRPC is sRPC handler of GetInvoice service.
+func GetInvoiceSRPC(st *achaemenid.Stream) {
+	var req = &getInvoiceReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getInvoiceRes
+	res, st.Err = getInvoice(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetInvoiceHTTP is HTTP handler of GetInvoice service.
+func GetInvoiceHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getInvoiceReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getInvoiceRes
+	res, st.Err = getInvoice(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type getInvoiceReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type getInvoiceRes struct {
+	WriteTime        etime.Time
+	BuyerID          [32]byte `json:",string"`
+	SellerID         [32]byte `json:",string"`
+	Price            price.Amount
+	Discount         price.Amount
+	DCCommission     price.Amount
+	SellerCommission price.Amount
+	Tax              price.Amount
+	Payable          price.Amount
+	Status           datastore.InvoiceStatus
+	SMSStatus        datastore.InvoiceReceiptStatus
+	EmailStatus      datastore.InvoiceReceiptStatus
+	Lines            []invoiceLine
+}
+
+func getInvoice(st *achaemenid.Stream, req *getInvoiceReq) (res *getInvoiceRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var inv = datastore.Invoice{
+		ID: req.ID,
+	}
+	err = inv.GetLastByID()
+	if err != nil {
+		return
+	}
+	if !canGetInvoice(st.Connection.UserID, &inv) {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	res = &getInvoiceRes{
+		WriteTime:        inv.WriteTime,
+		BuyerID:          inv.BuyerID,
+		SellerID:         inv.SellerID,
+		Price:            inv.Price,
+		Discount:         inv.Discount,
+		DCCommission:     inv.DCCommission,
+		SellerCommission: inv.SellerCommission,
+		Tax:              inv.Tax,
+		Payable:          inv.Payable,
+		Status:           inv.Status,
+		SMSStatus:        inv.SMSStatus,
+		EmailStatus:      inv.EmailStatus,
+		Lines:            make([]invoiceLine, len(inv.Lines)),
+	}
+	for i, line := range inv.Lines {
+		res.Lines[i] = invoiceLine(line)
+	}
+	return
+}
+
+// canGetInvoice report if user is buyer, seller or DC of any line of the invoice or admin.
+func canGetInvoice(userID [32]byte, inv *datastore.Invoice) bool {
+	if userID == inv.BuyerID || userID == inv.SellerID || userID == adminUserID {
+		return true
+	}
+	for _, line := range inv.Lines {
+		if userID == line.DCID {
+			return true
+		}
+	}
+	return false
+}
+
+type invoiceLine struct {
+	ProductID        [32]byte `json:",string"`
+	QuiddityID       [32]byte `json:",string"`
+	ProductAuctionID [32]byte `json:",string"`
+	DCID             [32]byte `json:",string"`
+	TransactionID    [32]byte `json:",string"`
+	Price            price.Amount
+	Discount         price.Amount
+	DCCommission     price.Amount
+	SellerCommission price.Amount
+	Tax              price.Amount
+	Payable          price.Amount
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getInvoiceReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *getInvoiceReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *getInvoiceReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *getInvoiceReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getInvoiceReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getInvoiceReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getInvoiceReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getInvoiceReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getInvoiceRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.WriteTime = etime.Time(syllab.GetInt64(buf, 0))
+	copy(res.BuyerID[:], buf[8:])
+	copy(res.SellerID[:], buf[40:])
+	res.Price = price.Amount(syllab.GetInt64(buf, 72))
+	res.Discount = price.Amount(syllab.GetInt64(buf, 80))
+	res.DCCommission = price.Amount(syllab.GetInt64(buf, 88))
+	res.SellerCommission = price.Amount(syllab.GetInt64(buf, 96))
+	res.Tax = price.Amount(syllab.GetInt64(buf, 104))
+	res.Payable = price.Amount(syllab.GetInt64(buf, 112))
+	res.Status = datastore.InvoiceStatus(syllab.GetUInt8(buf, 120))
+	res.SMSStatus = datastore.InvoiceReceiptStatus(syllab.GetUInt8(buf, 121))
+	res.EmailStatus = datastore.InvoiceReceiptStatus(syllab.GetUInt8(buf, 122))
+	res.Lines, err = decodeInvoiceLines(buf, 123)
+	return
+}
+
+func (res *getInvoiceRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	syllab.SetInt64(buf, 0, int64(res.WriteTime))
+	copy(buf[8:], res.BuyerID[:])
+	copy(buf[40:], res.SellerID[:])
+	syllab.SetInt64(buf, 72, int64(res.Price))
+	syllab.SetInt64(buf, 80, int64(res.Discount))
+	syllab.SetInt64(buf, 88, int64(res.DCCommission))
+	syllab.SetInt64(buf, 96, int64(res.SellerCommission))
+	syllab.SetInt64(buf, 104, int64(res.Tax))
+	syllab.SetInt64(buf, 112, int64(res.Payable))
+	syllab.SetUInt8(buf, 120, uint8(res.Status))
+	syllab.SetUInt8(buf, 121, uint8(res.SMSStatus))
+	syllab.SetUInt8(buf, 122, uint8(res.EmailStatus))
+	encodeInvoiceLines(buf, res.Lines, 123, hsi)
+	return
+}
+
+func (res *getInvoiceRes) syllabStackLen() (ln uint32) {
+	return 131 // fixed size data + variables data add&&len
+}
+
+func (res *getInvoiceRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.Lines)) * invoiceLineSyllabLen
+	return
+}
+
+func (res *getInvoiceRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getInvoiceRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getInvoiceRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getInvoiceRes) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Line Encoders & Decoders
+*/
+
+// invoiceLineSyllabLen is fixed size of each invoice line in heap.
+const invoiceLineSyllabLen uint32 = 208
+
+// decodeInvoiceLines decode lines slice that its add&&len store in given stack index.
+func decodeInvoiceLines(buf []byte, stackIndex uint32) (lines []invoiceLine, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(invoiceLineSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	lines = make([]invoiceLine, ln)
+	for i := range lines {
+		var line = &lines[i]
+		var dbuf = buf[add+uint32(i)*invoiceLineSyllabLen:]
+		copy(line.ProductID[:], dbuf[0:])
+		copy(line.QuiddityID[:], dbuf[32:])
+		copy(line.ProductAuctionID[:], dbuf[64:])
+		copy(line.DCID[:], dbuf[96:])
+		copy(line.TransactionID[:], dbuf[128:])
+		line.Price = price.Amount(syllab.GetInt64(dbuf, 160))
+		line.Discount = price.Amount(syllab.GetInt64(dbuf, 168))
+		line.DCCommission = price.Amount(syllab.GetInt64(dbuf, 176))
+		line.SellerCommission = price.Amount(syllab.GetInt64(dbuf, 184))
+		line.Tax = price.Amount(syllab.GetInt64(dbuf, 192))
+		line.Payable = price.Amount(syllab.GetInt64(dbuf, 200))
+	}
+	return
+}
+
+// encodeInvoiceLines encode lines in heap from given heap index and its add&&len in given stack index.
+func encodeInvoiceLines(buf []byte, lines []invoiceLine, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(lines)))
+	for i := range lines {
+		var line = &lines[i]
+		var dbuf = buf[hsi+uint32(i)*invoiceLineSyllabLen:]
+		copy(dbuf[0:], line.ProductID[:])
+		copy(dbuf[32:], line.QuiddityID[:])
+		copy(dbuf[64:], line.ProductAuctionID[:])
+		copy(dbuf[96:], line.DCID[:])
+		copy(dbuf[128:], line.TransactionID[:])
+		syllab.SetInt64(dbuf, 160, int64(line.Price))
+		syllab.SetInt64(dbuf, 168, int64(line.Discount))
+		syllab.SetInt64(dbuf, 176, int64(line.DCCommission))
+		syllab.SetInt64(dbuf, 184, int64(line.SellerCommission))
+		syllab.SetInt64(dbuf, 192, int64(line.Tax))
+		syllab.SetInt64(dbuf, 200, int64(line.Payable))
+	}
+}
@@ -0,0 +1,41 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../datastore"
+)
+
+func TestCanGetInvoice(t *testing.T) {
+	var buyer, seller, dc, otherDC = [32]byte{1}, [32]byte{2}, [32]byte{3}, [32]byte{4}
+	var inv = datastore.Invoice{
+		BuyerID:  buyer,
+		SellerID: seller,
+		Lines: []datastore.InvoiceLine{
+			{ProductID: [32]byte{5}, DCID: otherDC},
+			{ProductID: [32]byte{6}, DCID: dc},
+		},
+	}
+	var tests = []struct {
+		name   string
+		userID [32]byte
+		inv    datastore.Invoice
+		want   bool
+	}{
+		{"buyer", buyer, inv, true},
+		{"seller", seller, inv, true},
+		{"DC of last line", dc, inv, true},
+		{"DC of first line", otherDC, inv, true},
+		{"admin", adminUserID, inv, true},
+		{"other user", [32]byte{7}, inv, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canGetInvoice(tt.userID, &tt.inv); got != tt.want {
+				t.Errorf("canGetInvoice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var getProductHistoryService = achaemenid.Service{
+	ID:                2964818113,
+	IssueDate:         1792295726,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Product History",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return all versions of a product in write order and then versions of its parent products by ProductionID links, so whole chain from production to current owner can be seen.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: GetProductHistorySRPC,
+	HTTPHandler: GetProductHistoryHTTP,
+}
+
+// GetProductHistorySRPC is sRPC handler of GetProductHistory service.
+func GetProductHistorySRPC(st *achaemenid.Stream) {
+	var req = &getProductHistoryReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getProductHistoryRes
+	res, st.Err = getProductHistory(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetProductHistoryHTTP is HTTP handler of GetProductHistory service.
+func GetProductHistoryHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getProductHistoryReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getProductHistoryRes
+	res, st.Err = getProductHistory(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type getProductHistoryReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type getProductHistoryRes struct {
+	Products []productHistory // Requested product first and then its parents level by level to the produced ones
+}
+
+func getProductHistory(st *achaemenid.Stream, req *getProductHistoryReq) (res *getProductHistoryRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	res = &getProductHistoryRes{}
+	res.Products, err = walkProductHistory(st.Connection.UserID, req.ID, findProductHistory, findProductParents)
+	return
+}
+
+// walkProductHistory return history of given product and then its parents level by level, so merged products show all their parents.
+// Just current owner and regulators can see product provenance.
+func walkProductHistory(userID, id [32]byte, find func(id [32]byte) (productHistory, *er.Error),
+	findParents func(history *productHistory) ([][32]byte, *er.Error)) (products []productHistory, err *er.Error) {
+	products = make([]productHistory, 0, 1)
+	var level = [][32]byte{id}
+	var seen = map[[32]byte]bool{id: true}
+	for depth := 0; depth < productHistoryMaxDepth && len(level) != 0; depth++ {
+		var parents [][32]byte
+		for _, id := range level {
+			if len(products) == productHistoryMaxProducts {
+				return
+			}
+
+			var history productHistory
+			history, err = find(id)
+			if err != nil {
+				if depth > 0 && err.Equal(ganjine.ErrRecordNotFound) {
+					// Parent link can be a production ID not a product.
+					err = nil
+					continue
+				}
+				return
+			}
+			if depth == 0 {
+				var last = history.Versions[len(history.Versions)-1]
+				if last.OwnerID != userID && userID != justiceUserID && userID != adminUserID {
+					err = authorization.ErrUserNotAllow
+					return
+				}
+			}
+			products = append(products, history)
+
+			var parentIDs [][32]byte
+			parentIDs, err = findParents(&history)
+			if err != nil {
+				return
+			}
+			for _, parentID := range parentIDs {
+				if !seen[parentID] {
+					seen[parentID] = true
+					parents = append(parents, parentID)
+				}
+			}
+		}
+		level = parents
+	}
+	return
+}
+
+// findProductParents return IDs of products that given product split or merged from them.
+// Products that merged before parents index just refer to their first parent by ProductionID.
+func findProductParents(history *productHistory) (parentIDs [][32]byte, err *er.Error) {
+	// Just products that split or merged from other products link to a parent product.
+	var first = history.Versions[0]
+	if first.Status != datastore.ProductChangeQuiddity || first.ProductionID == [32]byte{} {
+		return
+	}
+
+	var p = datastore.Product{
+		ID: history.ID,
+	}
+	parentIDs, err = p.FindParentIDsByID(0, productMaxSplitMerge)
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		return [][32]byte{first.ProductionID}, nil
+	}
+	return
+}
+
+// findProductHistory return all versions of given product ID in write order.
+func findProductHistory(id [32]byte) (history productHistory, err *er.Error) {
+	var p = datastore.Product{
+		ID: id,
+	}
+	var recordIDs [][32]byte
+	recordIDs, err = p.FindRecordIDsByID(0, productHistoryMaxVersions)
+	if err != nil {
+		return
+	}
+
+	history = productHistory{
+		ID:       id,
+		Versions: make([]productVersion, 0, len(recordIDs)),
+	}
+	for _, recordID := range recordIDs {
+		p = datastore.Product{
+			RecordID: recordID,
+		}
+		err = p.GetByRecordID()
+		if err != nil {
+			return
+		}
+		history.Versions = append(history.Versions, productVersion{
+			WriteTime:        p.WriteTime,
+			OwnerID:          p.OwnerID,
+			QuiddityID:       p.QuiddityID,
+			SellerID:         p.SellerID,
+			ProductionID:     p.ProductionID,
+			DCID:             p.DCID,
+			ProductAuctionID: p.ProductAuctionID,
+			Status:           p.Status,
+			Quantity:         p.Quantity,
+		})
+	}
+	return
+}
+
+type productHistory struct {
+	ID       [32]byte `json:",string"`
+	Versions []productVersion
+}
+
+type productVersion struct {
+	WriteTime        etime.Time
+	OwnerID          [32]byte `json:",string"`
+	QuiddityID       [32]byte `json:",string"`
+	SellerID         [32]byte `json:",string"`
+	ProductionID     [32]byte `json:",string"`
+	DCID             [32]byte `json:",string"`
+	ProductAuctionID [32]byte `json:",string"`
+	Status           datastore.ProductStatus
+	Quantity         uint64
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getProductHistoryReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *getProductHistoryReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *getProductHistoryReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *getProductHistoryReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getProductHistoryReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getProductHistoryReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getProductHistoryReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getProductHistoryReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getProductHistoryRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Products, err = decodeProductHistories(buf, 0)
+	return
+}
+
+func (res *getProductHistoryRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	encodeProductHistories(buf, res.Products, 0, hsi)
+	return
+}
+
+func (res *getProductHistoryRes) syllabStackLen() (ln uint32) {
+	return 8 // fixed size data + variables data add&&len
+}
+
+func (res *getProductHistoryRes) syllabHeapLen() (ln uint32) {
+	ln += productHistoriesSyllabHeapLen(res.Products)
+	return
+}
+
+func (res *getProductHistoryRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getProductHistoryRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getProductHistoryRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getProductHistoryRes) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Version Encoders & Decoders
+*/
+
+// productVersionSyllabLen is fixed size of each version in heap.
+const productVersionSyllabLen uint32 = 209
+
+// decodeProductVersions decode versions slice that its add&&len store in given stack index.
+func decodeProductVersions(buf []byte, stackIndex uint32) (versions []productVersion, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(productVersionSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	versions = make([]productVersion, ln)
+	for i := range versions {
+		var version = &versions[i]
+		var dbuf = buf[add+uint32(i)*productVersionSyllabLen:]
+		version.WriteTime = etime.Time(syllab.GetInt64(dbuf, 0))
+		copy(version.OwnerID[:], dbuf[8:])
+		copy(version.QuiddityID[:], dbuf[40:])
+		copy(version.SellerID[:], dbuf[72:])
+		copy(version.ProductionID[:], dbuf[104:])
+		copy(version.DCID[:], dbuf[136:])
+		copy(version.ProductAuctionID[:], dbuf[168:])
+		version.Status = datastore.ProductStatus(syllab.GetUInt8(dbuf, 200))
+		version.Quantity = syllab.GetUInt64(dbuf, 201)
+	}
+	return
+}
+
+// encodeProductVersions encode versions in heap from given heap index and its add&&len in given stack index.
+func encodeProductVersions(buf []byte, versions []productVersion, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(versions)))
+	for i := range versions {
+		var version = &versions[i]
+		var dbuf = buf[hsi+uint32(i)*productVersionSyllabLen:]
+		syllab.SetInt64(dbuf, 0, int64(version.WriteTime))
+		copy(dbuf[8:], version.OwnerID[:])
+		copy(dbuf[40:], version.QuiddityID[:])
+		copy(dbuf[72:], version.SellerID[:])
+		copy(dbuf[104:], version.ProductionID[:])
+		copy(dbuf[136:], version.DCID[:])
+		copy(dbuf[168:], version.ProductAuctionID[:])
+		syllab.SetUInt8(dbuf, 200, uint8(version.Status))
+		syllab.SetUInt64(dbuf, 201, version.Quantity)
+	}
+}
+
+/*
+	History Encoders & Decoders
+*/
+
+// productHistorySyllabLen is fixed size of each history in heap. Versions of each history store after all histories.
+const productHistorySyllabLen uint32 = 40
+
+// productHistoriesSyllabHeapLen return heap size of given histories and their versions.
+func productHistoriesSyllabHeapLen(histories []productHistory) (ln uint32) {
+	ln = uint32(len(histories)) * productHistorySyllabLen
+	for i := range histories {
+		ln += uint32(len(histories[i].Versions)) * productVersionSyllabLen
+	}
+	return
+}
+
+// decodeProductHistories decode histories slice that its add&&len store in given stack index.
+func decodeProductHistories(buf []byte, stackIndex uint32) (histories []productHistory, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(productHistorySyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	histories = make([]productHistory, ln)
+	for i := range histories {
+		var history = &histories[i]
+		var index = add + uint32(i)*productHistorySyllabLen
+		copy(history.ID[:], buf[index:])
+		history.Versions, err = decodeProductVersions(buf, index+32)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// encodeProductHistories encode histories and then their versions in heap from given heap index and its add&&len in given stack index.
+func encodeProductHistories(buf []byte, histories []productHistory, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(histories)))
+	var vhsi = hsi + uint32(len(histories))*productHistorySyllabLen // Versions heap start index
+	for i := range histories {
+		var history = &histories[i]
+		var index = hsi + uint32(i)*productHistorySyllabLen
+		copy(buf[index:], history.ID[:])
+		encodeProductVersions(buf, history.Versions, index+32, vhsi)
+		vhsi += uint32(len(history.Versions)) * productVersionSyllabLen
+	}
+}
@@ -0,0 +1,78 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"../datastore"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/ganjine"
+)
+
+func TestWalkProductHistory(t *testing.T) {
+	var owner = [32]byte{1}
+	var production = [32]byte{2} // Production ID that is not a product
+	// Each product link to its parents by ProductionID of its first version like split products.
+	var products = map[[32]byte][][32]byte{
+		{10}: {{11}},
+		{11}: {{12}},
+		{12}: {production},
+		{20}: {{21}, {22}},
+		{21}: {{23}},
+		{22}: {{23}},
+		{23}: nil,
+		{30}: {{31}},
+		{31}: {{30}},
+	}
+	var find = func(id [32]byte) (history productHistory, err *er.Error) {
+		var parents, ok = products[id]
+		if !ok {
+			err = ganjine.ErrRecordNotFound
+			return
+		}
+		history = productHistory{ID: id, Versions: []productVersion{{OwnerID: owner, Status: datastore.ProductChangeQuiddity}}}
+		if len(parents) != 0 {
+			history.Versions[0].ProductionID = parents[0]
+		}
+		return
+	}
+	var findParents = func(history *productHistory) (parentIDs [][32]byte, err *er.Error) {
+		return products[history.ID], nil
+	}
+
+	var tests = []struct {
+		name   string
+		userID [32]byte
+		id     [32]byte
+		want   [][32]byte
+		err    *er.Error
+	}{
+		{"production chain", owner, [32]byte{10}, [][32]byte{{10}, {11}, {12}}, nil},
+		{"merged parents once", owner, [32]byte{20}, [][32]byte{{20}, {21}, {22}, {23}}, nil},
+		{"circular links", owner, [32]byte{30}, [][32]byte{{30}, {31}}, nil},
+		{"justice", justiceUserID, [32]byte{10}, [][32]byte{{10}, {11}, {12}}, nil},
+		{"not owner", [32]byte{3}, [32]byte{10}, nil, authorization.ErrUserNotAllow},
+		{"not exist", owner, production, nil, ganjine.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, err = walkProductHistory(tt.userID, tt.id, find, findParents)
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Fatalf("walkProductHistory() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			var ids = make([][32]byte, len(got))
+			for i := range got {
+				ids[i] = got[i].ID
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("walkProductHistory() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
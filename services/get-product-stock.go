@@ -0,0 +1,234 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var getProductStockService = achaemenid.Service{
+	ID:                3320497168,
+	IssueDate:         1792295957,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Get Product Stock",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return live number of products of a quiddity in a distribution center or owned by an org. Measurable products count by their quantity in quiddity base unit.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: GetProductStockSRPC,
+	HTTPHandler: GetProductStockHTTP,
+}
+
+// GetProductStockSRPC is sRPC handler of GetProductStock service.
+func GetProductStockSRPC(st *achaemenid.Stream) {
+	var req = &getProductStockReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *getProductStockRes
+	res, st.Err = getProductStock(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// GetProductStockHTTP is HTTP handler of GetProductStock service.
+func GetProductStockHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &getProductStockReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *getProductStockRes
+	res, st.Err = getProductStock(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type getProductStockReq struct {
+	QuiddityID [32]byte `json:",string"`
+	DCID       [32]byte `json:",string,optional"` // Stock of a distribution center
+	OrgID      [32]byte `json:",string,optional"` // Stock owned by an org in all its DCs. Empty both means requester org
+}
+
+type getProductStockRes struct {
+	OnHand       int64
+	InTransit    int64
+	PreSale      int64
+	ReorderLevel int64
+}
+
+func getProductStock(st *achaemenid.Stream, req *getProductStockReq) (res *getProductStockRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var ps = datastore.ProductStock{
+		QuiddityID: req.QuiddityID,
+	}
+	ps.ScopeID, ps.Scope = getProductStockScope(st, req.DCID, req.OrgID)
+	if st.Connection.UserID != ps.ScopeID && st.Connection.UserID != adminUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	err = readProductStock(&ps)
+	if err != nil {
+		return
+	}
+	var reorderLevel int64
+	reorderLevel, err = getProductStockReorderLevel(ps.QuiddityID, ps.Scope, ps.ScopeID)
+	if err != nil {
+		return
+	}
+
+	res = &getProductStockRes{
+		OnHand:       ps.OnHand,
+		InTransit:    ps.InTransit,
+		PreSale:      ps.PreSale,
+		ReorderLevel: reorderLevel,
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *getProductStockReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.QuiddityID[:], buf[0:])
+	copy(req.DCID[:], buf[32:])
+	copy(req.OrgID[:], buf[64:])
+	return
+}
+
+func (req *getProductStockReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.QuiddityID[:])
+	copy(buf[32:], req.DCID[:])
+	copy(buf[64:], req.OrgID[:])
+	return
+}
+
+func (req *getProductStockReq) syllabStackLen() (ln uint32) {
+	return 96
+}
+
+func (req *getProductStockReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *getProductStockReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *getProductStockReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *getProductStockReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *getProductStockReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *getProductStockRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.OnHand = syllab.GetInt64(buf, 0)
+	res.InTransit = syllab.GetInt64(buf, 8)
+	res.PreSale = syllab.GetInt64(buf, 16)
+	res.ReorderLevel = syllab.GetInt64(buf, 24)
+	return
+}
+
+func (res *getProductStockRes) syllabEncoder(buf []byte) {
+	syllab.SetInt64(buf, 0, res.OnHand)
+	syllab.SetInt64(buf, 8, res.InTransit)
+	syllab.SetInt64(buf, 16, res.PreSale)
+	syllab.SetInt64(buf, 24, res.ReorderLevel)
+	return
+}
+
+func (res *getProductStockRes) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (res *getProductStockRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *getProductStockRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *getProductStockRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *getProductStockRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *getProductStockRes) jsonLen() (ln int) {
+	return
+}
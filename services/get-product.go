@@ -99,6 +99,7 @@ type getProductRes struct {
 	DCID             [32]byte `json:",string"`
 	ProductAuctionID [32]byte `json:",string"`
 	Status           datastore.ProductStatus
+	Quantity         uint64
 }
 
 func getProduct(st *achaemenid.Stream, req *getProductReq) (res *getProductRes, err *er.Error) {
@@ -132,6 +133,7 @@ func getProduct(st *achaemenid.Stream, req *getProductReq) (res *getProductRes,
 		DCID:             p.DCID,
 		ProductAuctionID: p.ProductAuctionID,
 		Status:           p.Status,
+		Quantity:         p.Quantity,
 	}
 
 	return
@@ -98,6 +98,7 @@ type getQuiddityRes struct {
 	URI    string
 	Title  string
 	Status datastore.QuiddityStatus
+	Unit   datastore.QuiddityUnit
 }
 
 func getQuiddity(st *achaemenid.Stream, req *getQuiddityReq) (res *getQuiddityRes, err *er.Error) {
@@ -120,6 +121,7 @@ func getQuiddity(st *achaemenid.Stream, req *getQuiddityReq) (res *getQuiddityRe
 		URI:    w.URI,
 		Title:  w.Title,
 		Status: w.Status,
+		Unit:   w.Unit,
 	}
 
 	return
@@ -220,6 +222,7 @@ func (res *getQuiddityRes) syllabDecoder(buf []byte) (err *er.Error) {
 	res.URI = syllab.UnsafeGetString(buf, 104)
 	res.Title = syllab.UnsafeGetString(buf, 112)
 	res.Status = datastore.QuiddityStatus(syllab.GetUInt8(buf, 120))
+	res.Unit = datastore.QuiddityUnit(syllab.GetUInt8(buf, 121))
 	return
 }
 
@@ -233,11 +236,12 @@ func (res *getQuiddityRes) syllabEncoder(buf []byte) {
 	hsi = syllab.SetString(buf, res.URI, 104, hsi)
 	hsi = syllab.SetString(buf, res.Title, 112, hsi)
 	syllab.SetUInt8(buf, 120, uint8(res.Status))
+	syllab.SetUInt8(buf, 121, uint8(res.Unit))
 	return
 }
 
 func (res *getQuiddityRes) syllabStackLen() (ln uint32) {
-	return 121
+	return 122
 }
 
 func (res *getQuiddityRes) syllabHeapLen() (ln uint32) {
@@ -275,6 +279,10 @@ func (res *getQuiddityRes) jsonDecoder(buf []byte) (err *er.Error) {
 			var num uint8
 			num, err = decoder.DecodeUInt8()
 			res.Status = datastore.QuiddityStatus(num)
+		case "Unit":
+			var num uint8
+			num, err = decoder.DecodeUInt8()
+			res.Unit = datastore.QuiddityUnit(num)
 		default:
 			err = decoder.NotFoundKeyStrict()
 		}
@@ -312,12 +320,15 @@ func (res *getQuiddityRes) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`","Status":`)
 	encoder.EncodeUInt8(uint8(res.Status))
 
+	encoder.EncodeString(`,"Unit":`)
+	encoder.EncodeUInt8(uint8(res.Unit))
+
 	encoder.EncodeByte('}')
 	return encoder.Buf
 }
 
 func (res *getQuiddityRes) jsonLen() (ln int) {
 	ln = len(res.URI) + len(res.Title)
-	ln += 248
+	ln += 260
 	return
 }
@@ -0,0 +1,315 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+var holdFinancialEscrowService = achaemenid.Service{
+	ID:                2611845397,
+	IssueDate:         1792294530,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDCreate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Hold Financial Escrow",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `hold part of user balance against a pre-sale product until product DC confirm delivery.
+Held amount refund to user on cancel or when escrow expire.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction", "Product",
+	},
+
+	SRPCHandler: HoldFinancialEscrowSRPC,
+	HTTPHandler: HoldFinancialEscrowHTTP,
+}
+
+// HoldFinancialEscrowSRPC is sRPC handler of HoldFinancialEscrow service.
+func HoldFinancialEscrowSRPC(st *achaemenid.Stream) {
+	var req = &holdFinancialEscrowReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *holdFinancialEscrowRes
+	res, st.Err = holdFinancialEscrow(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// HoldFinancialEscrowHTTP is HTTP handler of HoldFinancialEscrow service.
+func HoldFinancialEscrowHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &holdFinancialEscrowReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *holdFinancialEscrowRes
+	res, st.Err = holdFinancialEscrow(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type holdFinancialEscrowReq struct {
+	ProductID        [32]byte     `json:",string"`
+	ProductAuctionID [32]byte     `json:",string,optional"` // Auction that its discount apply to the product price, if any
+	Amount           price.Amount // Must be same as payable price of the product that buyer see
+	ExpireTime       etime.Time
+}
+
+type holdFinancialEscrowRes struct {
+	ID [32]byte `json:",string"`
+}
+
+func holdFinancialEscrow(st *achaemenid.Stream, req *holdFinancialEscrowReq) (res *holdFinancialEscrowRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+	err = checkFinancialFreeze(st.Connection.UserID)
+	if err != nil {
+		return
+	}
+
+	var product = datastore.Product{
+		ID: req.ProductID,
+	}
+	err = product.GetLastByID()
+	if err != nil {
+		return
+	}
+	if product.Status != datastore.ProductPreSale || product.OwnerID == st.Connection.UserID {
+		err = ErrFinancialEscrowNotPreSale
+		return
+	}
+	// Release give the product to the buyer, so buyer can't choose the amount that seller get.
+	var amount price.Amount
+	amount, err = getFinancialEscrowAmount(st, &product, req.ProductAuctionID)
+	if err != nil {
+		return
+	}
+	if req.Amount != amount {
+		err = ErrFinancialEscrowBadAmount
+		return
+	}
+
+	var held bool
+	held, err = isFinancialEscrowHeld(req.ProductID)
+	if err != nil {
+		return
+	}
+	if held {
+		err = ErrFinancialEscrowExist
+		return
+	}
+
+	var fe = datastore.FinancialEscrow{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		ID:         uuid.Random32Byte(),
+		ProductID:  product.ID,
+		BuyerID:    st.Connection.UserID,
+		SellerID:   product.OwnerID,
+		DCID:       product.DCID,
+		Amount:     req.Amount,
+		ExpireTime: req.ExpireTime,
+		Status:     datastore.FinancialEscrowHeld,
+	}
+	var hold = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserID:        fe.BuyerID,
+		ReferenceID:   fe.ID,
+		ReferenceType: datastore.FinancialTransactionEscrowHold,
+		Amount:        -fe.Amount,
+	}
+	err = saveFinancialTransaction(&hold)
+	if err != nil {
+		return
+	}
+
+	err = fe.SaveNew()
+	if err != nil {
+		var reverseErr = reverseFinancialTransaction(&hold)
+		if reverseErr != nil {
+			log.Warn("Financial escrow hold", hold.RecordID, "can't reverse due to:", reverseErr)
+		}
+		return
+	}
+
+	// Other buyer may hold the product after our check, so first escrow of the product hold it and others refund.
+	var holderID [32]byte
+	holderID, _, err = findFinancialEscrowHolder(product.ID)
+	if err != nil || holderID != fe.ID {
+		var decision, refundErr = decideFinancialEscrow(&fe, datastore.FinancialEscrowRefunded)
+		if refundErr == nil {
+			refundErr = settleFinancialEscrow(&decision)
+		}
+		if refundErr != nil {
+			log.Warn("Financial escrow", fe.ID, "that not hold the product can't refund due to:", refundErr)
+		}
+		if err == nil {
+			err = ErrFinancialEscrowExist
+		}
+		return
+	}
+
+	res = &holdFinancialEscrowRes{
+		ID: fe.ID,
+	}
+	return
+}
+
+func (req *holdFinancialEscrowReq) validator() (err *er.Error) {
+	if req.Amount <= 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+	var now = etime.Now()
+	if req.ExpireTime <= now || req.ExpireTime-now > financialEscrowMaxDuration {
+		err = ErrFinancialEscrowBadExpireTime
+		return
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *holdFinancialEscrowReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ProductID[:], buf[0:])
+	copy(req.ProductAuctionID[:], buf[32:])
+	req.Amount = price.Amount(syllab.GetInt64(buf, 64))
+	req.ExpireTime = etime.Time(syllab.GetInt64(buf, 72))
+	return
+}
+
+func (req *holdFinancialEscrowReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ProductID[:])
+	copy(buf[32:], req.ProductAuctionID[:])
+	syllab.SetInt64(buf, 64, int64(req.Amount))
+	syllab.SetInt64(buf, 72, int64(req.ExpireTime))
+	return
+}
+
+func (req *holdFinancialEscrowReq) syllabStackLen() (ln uint32) {
+	return 80
+}
+
+func (req *holdFinancialEscrowReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *holdFinancialEscrowReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *holdFinancialEscrowReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *holdFinancialEscrowReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *holdFinancialEscrowReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *holdFinancialEscrowRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.ID[:], buf[0:])
+	return
+}
+
+func (res *holdFinancialEscrowRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.ID[:])
+	return
+}
+
+func (res *holdFinancialEscrowRes) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (res *holdFinancialEscrowRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *holdFinancialEscrowRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *holdFinancialEscrowRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *holdFinancialEscrowRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *holdFinancialEscrowRes) jsonLen() (ln int) {
+	return
+}
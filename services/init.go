@@ -23,6 +23,8 @@ var (
 	sepPOS sep.POS
 
 	adminUserID = [32]byte{128}
+	// justiceUserID is justice department user that can freeze users balance by a justice case.
+	justiceUserID = [32]byte{129}
 )
 
 func init() {
@@ -46,6 +48,40 @@ func init() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	var webPaymentJSON = achaemenid.Server.Assets.Secret.GetFile("web-payment-gateway.json")
+	if webPaymentJSON != nil {
+		var gw hmacWebPaymentGateway
+		err = gw.Init(webPaymentJSON.Data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		webPaymentGateways['0'] = &gw
+	} else {
+		log.Warn("Can't find 'web-payment-gateway.json' file in 'secret' folder, so web payment disabled")
+	}
+
+	var exchangeRatesJSON = achaemenid.Server.Assets.Secret.GetFile("financial-exchange-rates.json")
+	if exchangeRatesJSON != nil {
+		var rates staticFinancialExchangeRates
+		err = rates.Init(exchangeRatesJSON.Data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		financialExchangeRates = &rates
+	} else {
+		log.Warn("Can't find 'financial-exchange-rates.json' file in 'secret' folder, so currency exchange disabled")
+	}
+
+	var societiesJSON = achaemenid.Server.Assets.Secret.GetFile("societies.json")
+	if societiesJSON != nil {
+		err = initSocieties(societiesJSON.Data)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		log.Warn("Can't find 'societies.json' file in 'secret' folder, so inter-society transfers disabled")
+	}
 }
 
 func init() {
@@ -117,6 +153,19 @@ func init() {
 
 	// Product
 	achaemenid.Server.Services.RegisterService(&registerProductService)
+	achaemenid.Server.Services.RegisterService(&registerProductInvoiceService)
+	achaemenid.Server.Services.RegisterService(&refundProductInvoiceService)
+	achaemenid.Server.Services.RegisterService(&updateProductOwnerService)
+	achaemenid.Server.Services.RegisterService(&updateProductDcService)
+	achaemenid.Server.Services.RegisterService(&acknowledgeProductDcService)
+	achaemenid.Server.Services.RegisterService(&splitProductService)
+	achaemenid.Server.Services.RegisterService(&mergeProductService)
+	achaemenid.Server.Services.RegisterService(&getProductHistoryService)
+	achaemenid.Server.Services.RegisterService(&getProductStockService)
+	achaemenid.Server.Services.RegisterService(&setProductStockReorderService)
+	achaemenid.Server.Services.RegisterService(&recountProductStockService)
+	achaemenid.Server.Services.RegisterService(&getInvoiceService)
+	achaemenid.Server.Services.RegisterService(&findInvoiceService)
 	// achaemenid.Server.Services.RegisterService(&approveProductAuctionByWarehouseService)
 	// achaemenid.Server.Services.RegisterService(&)
 	// achaemenid.Server.Services.RegisterService(&)
@@ -126,6 +175,26 @@ func init() {
 	achaemenid.Server.Services.RegisterService(&registerFinancialTransactionService)
 	achaemenid.Server.Services.RegisterService(&getFinancialTransactionService)
 	achaemenid.Server.Services.RegisterService(&findFinancialTransactionByDayService)
+	achaemenid.Server.Services.RegisterService(&getFinancialTransactionStatementService)
+	achaemenid.Server.Services.RegisterService(&getFinancialBalanceService)
+	achaemenid.Server.Services.RegisterService(&setFinancialRuleService)
+	achaemenid.Server.Services.RegisterService(&getFinancialRuleService)
+	achaemenid.Server.Services.RegisterService(&exchangeFinancialCurrencyService)
+	achaemenid.Server.Services.RegisterService(&getFinancialBalanceAtService)
+	achaemenid.Server.Services.RegisterService(&freezeFinancialBalanceService)
+	achaemenid.Server.Services.RegisterService(&unfreezeFinancialBalanceService)
+	achaemenid.Server.Services.RegisterService(&findFinancialFreezeService)
+	achaemenid.Server.Services.RegisterService(&holdFinancialEscrowService)
+	achaemenid.Server.Services.RegisterService(&releaseFinancialEscrowService)
+	achaemenid.Server.Services.RegisterService(&cancelFinancialEscrowService)
+	achaemenid.Server.Services.RegisterService(&registerFinancialStandingOrderService)
+	achaemenid.Server.Services.RegisterService(&findFinancialStandingOrderService)
+	achaemenid.Server.Services.RegisterService(&pauseFinancialStandingOrderService)
+	achaemenid.Server.Services.RegisterService(&resumeFinancialStandingOrderService)
+	achaemenid.Server.Services.RegisterService(&cancelFinancialStandingOrderService)
+	achaemenid.Server.Services.RegisterService(&verifyFinancialTransactionChainService)
+	achaemenid.Server.Services.RegisterService(&verifyFinancialWebPaymentService)
+	achaemenid.Server.Services.RegisterService(&settleSocietyFinancialTransferService)
 
 	// ForeignDetail
 	// achaemenid.Server.Services.RegisterService(&)
@@ -2,9 +2,100 @@
 
 package services
 
+import (
+	"encoding/base64"
+	"strconv"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/ganjine"
+	lang "../libgo/language"
+	"../libgo/log"
+	"../libgo/sdk/asanak.com"
+)
+
 const invoiceSMS = `
 سپاس از خرید شما
-برای مشاهده جزییات آخرین خرید خود به آدرس زیر مراجعه كنید
-www.sabz.city/invoice?today
+مبلغ پرداختی: `
+
+const invoiceSMSLink = `
+برای مشاهده جزییات خرید خود به آدرس زیر مراجعه كنید
+`
+
+const invoiceSMSFooter = `
 فروشگاه های زنجیره ای سبز
 `
+
+const (
+	englishInvoiceSMS       = "SabzCity\n\nThanks for your purchase\nPaid: "
+	englishInvoiceSMSLink   = "\nSee your invoice details at\n"
+	englishInvoiceSMSFooter = "\nSabz chain stores\n"
+)
+
+// sendInvoiceReceipt send receipt of a registered invoice to the buyer and record delivery status in new invoice version.
+// Call it in new goroutine due to it must not block the money transaction.
+func sendInvoiceReceipt(inv datastore.Invoice, language lang.Language) {
+	inv.SMSStatus, inv.SMSID = sendInvoiceSMS(&inv, language)
+	// TODO::: send receipt to buyer email when persons can register an email and platform has an email provider.
+	inv.EmailStatus = datastore.InvoiceReceiptNoContact
+
+	inv.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	var err = inv.Set()
+	if err != nil {
+		log.Warn("Invoice", inv.ID, "receipt status can't write due to:", err)
+		return
+	}
+	inv.IndexRecordIDForID()
+}
+
+// sendInvoiceSMS send receipt SMS to buyer active number and return send status with provider message ID.
+func sendInvoiceSMS(inv *datastore.Invoice, language lang.Language) (status datastore.InvoiceReceiptStatus, smsID uint64) {
+	var pn = datastore.PersonNumber{
+		PersonID: inv.BuyerID,
+	}
+	var err = pn.GetLastByPersonID()
+	if err.Equal(ganjine.ErrRecordNotFound) || (err == nil && pn.Status != datastore.PersonNumberRegister) {
+		return datastore.InvoiceReceiptNoContact, 0
+	}
+	if err != nil {
+		log.Warn("Invoice", inv.ID, "receipt SMS can't send due to:", err)
+		return datastore.InvoiceReceiptFailed, 0
+	}
+
+	var sendSMSReq = asanak.SendSMSReq{
+		Destination: []string{strconv.FormatUint(pn.Number, 10)},
+		Message:     invoiceSMSMessage(inv, language),
+	}
+	if log.DevMode {
+		log.Debug("Invoice", inv.ID, "receipt SMS:", string(sendSMSReq.Message))
+		return datastore.InvoiceReceiptSent, 0
+	}
+	var sendSMSRes asanak.SendSMSRes
+	sendSMSRes, err = smsProvider.SendSMS(&sendSMSReq)
+	if err != nil {
+		log.Warn("Invoice", inv.ID, "receipt SMS can't send due to:", err)
+		return datastore.InvoiceReceiptFailed, 0
+	}
+	if len(sendSMSRes) > 0 {
+		smsID = sendSMSRes[0]
+	}
+	return datastore.InvoiceReceiptSent, smsID
+}
+
+// invoiceSMSMessage make receipt summary of the invoice in given language with a link to the invoice page.
+func invoiceSMSMessage(inv *datastore.Invoice, language lang.Language) (message []byte) {
+	var head, link, footer = englishInvoiceSMS, englishInvoiceSMSLink, englishInvoiceSMSFooter
+	if language == lang.LanguagePersian {
+		head, link, footer = invoiceSMS, invoiceSMSLink, invoiceSMSFooter
+	}
+
+	message = make([]byte, 0, len(head)+len(link)+len(footer)+len(achaemenid.Server.Manifest.DomainName)+80)
+	message = append(message, head...)
+	// Invoice always is in society default currency.
+	message = appendFinancialAmount(message, inv.Payable, 0)
+	message = append(message, link...)
+	message = append(message, achaemenid.Server.Manifest.DomainName+"/invoice?id="...)
+	message = append(message, base64.RawURLEncoding.EncodeToString(inv.ID[:])...)
+	message = append(message, footer...)
+	return
+}
@@ -0,0 +1,12 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+// StartJobs start platform background jobs. Call it after datastore initialized!
+func StartJobs() {
+	go indexFinancialTransactionChainsJob()
+	go recoverFinancialTransfersJob()
+	go expireFinancialEscrowsJob()
+	go runFinancialStandingOrdersJob()
+	go writeFinancialSnapshotsJob()
+}
@@ -0,0 +1,295 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+var mergeProductService = achaemenid.Service{
+	ID:                3140672219,
+	IssueDate:         1792295704,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Merge Product",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `merge some measurable products in same DC to one product of given quiddity. Products void and refer to merged product by ProductionID.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: MergeProductSRPC,
+	HTTPHandler: MergeProductHTTP,
+}
+
+// MergeProductSRPC is sRPC handler of MergeProduct service.
+func MergeProductSRPC(st *achaemenid.Stream) {
+	var req = &mergeProductReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *mergeProductRes
+	res, st.Err = mergeProduct(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// MergeProductHTTP is HTTP handler of MergeProduct service.
+func MergeProductHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &mergeProductReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *mergeProductRes
+	res, st.Err = mergeProduct(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type mergeProductReq struct {
+	IDs        [][32]byte `json:",string"`
+	QuiddityID [32]byte   `json:",string"` // Quiddity of merged product. Must have same base unit as the products quiddity
+	Quantity   uint64     // Must be equal to sum of the products quantity
+}
+
+type mergeProductRes struct {
+	ID [32]byte `json:",string"`
+}
+
+func mergeProduct(st *achaemenid.Stream, req *mergeProductReq) (res *mergeProductRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	var parents = make([]datastore.Product, len(req.IDs))
+	for i, id := range req.IDs {
+		var parent = &parents[i]
+		parent.ID = id
+		err = parent.GetLastByID()
+		if err != nil {
+			return
+		}
+		err = checkProductChangeable(st, parent)
+		if err != nil {
+			return
+		}
+		err = checkProductChangeQuiddity(parent.QuiddityID, req.QuiddityID)
+		if err != nil {
+			return
+		}
+	}
+	err = checkProductMerge(parents, req.Quantity)
+	if err != nil {
+		return
+	}
+
+	var child = datastore.Product{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: st.Connection.ID,
+		ID:               uuid.Random32Byte(),
+		OwnerID:          parents[0].OwnerID,
+		QuiddityID:       req.QuiddityID,
+		ProductionID:     parents[0].ID,
+		DCID:             parents[0].DCID,
+		Status:           datastore.ProductChangeQuiddity,
+		Quantity:         req.Quantity,
+	}
+
+	// Void parents first, so failure in middle of the merge never add stock to the DC.
+	// Void parents refer to merged product and merged product index all parents as its ProductionID refer to first one.
+	for i := range parents {
+		err = voidProduct(st, &parents[i], child.ID)
+		if err != nil {
+			return
+		}
+	}
+
+	err = child.SaveNew()
+	if err != nil {
+		return
+	}
+	for i := range parents {
+		child.IndexParentIDForID(parents[i].ID)
+	}
+	moveProductStock(nil, &child)
+
+	res = &mergeProductRes{
+		ID: child.ID,
+	}
+	return
+}
+
+func (req *mergeProductReq) validator() (err *er.Error) {
+	if len(req.IDs) < 2 || len(req.IDs) > productMaxSplitMerge || req.Quantity == 0 {
+		err = ErrProductQuantityMismatch
+		return
+	}
+	for i := range req.IDs {
+		for j := i + 1; j < len(req.IDs); j++ {
+			if req.IDs[i] == req.IDs[j] {
+				err = ErrProductQuantityMismatch
+				return
+			}
+		}
+	}
+	return
+}
+
+// checkProductMerge check measurable parents in same DC can merge to a product with given quantity.
+func checkProductMerge(parents []datastore.Product, quantity uint64) (err *er.Error) {
+	var quantities = make([]uint64, len(parents))
+	for i := range parents {
+		if parents[i].Quantity == 0 {
+			return ErrProductNotMeasurable
+		}
+		if parents[i].DCID != parents[0].DCID {
+			return ErrProductMergeDC
+		}
+		quantities[i] = parents[i].Quantity
+	}
+	return checkProductQuantities(quantity, quantities)
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *mergeProductReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	req.IDs = syllab.UnsafeGet32ByteArraySlice(buf, 0)
+	copy(req.QuiddityID[:], buf[8:])
+	req.Quantity = syllab.GetUInt64(buf, 40)
+	return
+}
+
+func (req *mergeProductReq) syllabEncoder(buf []byte) {
+	var hsi uint32 = req.syllabStackLen() // Heap start index || Stack size!
+
+	syllab.Set32ByteArrayArray(buf, req.IDs, 0, hsi)
+	copy(buf[8:], req.QuiddityID[:])
+	syllab.SetUInt64(buf, 40, req.Quantity)
+	return
+}
+
+func (req *mergeProductReq) syllabStackLen() (ln uint32) {
+	return 48 // fixed size data + variables data add&&len
+}
+
+func (req *mergeProductReq) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(req.IDs) * 32)
+	return
+}
+
+func (req *mergeProductReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *mergeProductReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *mergeProductReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *mergeProductReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *mergeProductRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.ID[:], buf[0:])
+	return
+}
+
+func (res *mergeProductRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.ID[:])
+	return
+}
+
+func (res *mergeProductRes) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (res *mergeProductRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *mergeProductRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *mergeProductRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *mergeProductRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *mergeProductRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,55 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../datastore"
+	er "../libgo/error"
+)
+
+func TestCheckProductMerge(t *testing.T) {
+	var dc, otherDC = [32]byte{1}, [32]byte{2}
+	var tests = []struct {
+		name     string
+		parents  []datastore.Product
+		quantity uint64
+		err      *er.Error
+	}{
+		{"merge", []datastore.Product{{DCID: dc, Quantity: 3}, {DCID: dc, Quantity: 4}}, 7, nil},
+		{"not measurable", []datastore.Product{{DCID: dc, Quantity: 3}, {DCID: dc}}, 3, ErrProductNotMeasurable},
+		{"other DC", []datastore.Product{{DCID: dc, Quantity: 3}, {DCID: otherDC, Quantity: 4}}, 7, ErrProductMergeDC},
+		{"less than parents", []datastore.Product{{DCID: dc, Quantity: 3}, {DCID: dc, Quantity: 4}}, 6, ErrProductQuantityMismatch},
+		{"more than parents", []datastore.Product{{DCID: dc, Quantity: 3}, {DCID: dc, Quantity: 4}}, 8, ErrProductQuantityMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err = checkProductMerge(tt.parents, tt.quantity)
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Errorf("checkProductMerge() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMergeProductReq_Validator(t *testing.T) {
+	var tests = []struct {
+		name string
+		req  mergeProductReq
+		err  *er.Error
+	}{
+		{"two products", mergeProductReq{IDs: [][32]byte{{1}, {2}}, Quantity: 2}, nil},
+		{"one product", mergeProductReq{IDs: [][32]byte{{1}}, Quantity: 1}, ErrProductQuantityMismatch},
+		{"zero quantity", mergeProductReq{IDs: [][32]byte{{1}, {2}}}, ErrProductQuantityMismatch},
+		{"same product twice", mergeProductReq{IDs: [][32]byte{{1}, {2}, {1}}, Quantity: 3}, ErrProductQuantityMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err = tt.req.validator()
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Errorf("validator() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
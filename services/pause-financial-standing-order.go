@@ -0,0 +1,211 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var pauseFinancialStandingOrderService = achaemenid.Service{
+	ID:                2895430016,
+	IssueDate:         1792294756,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Pause Financial Standing Order",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `pause active standing order of the user until resume it.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: PauseFinancialStandingOrderSRPC,
+	HTTPHandler: PauseFinancialStandingOrderHTTP,
+}
+
+// PauseFinancialStandingOrderSRPC is sRPC handler of PauseFinancialStandingOrder service.
+func PauseFinancialStandingOrderSRPC(st *achaemenid.Stream) {
+	var req = &pauseFinancialStandingOrderReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *pauseFinancialStandingOrderRes
+	res, st.Err = pauseFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// PauseFinancialStandingOrderHTTP is HTTP handler of PauseFinancialStandingOrder service.
+func PauseFinancialStandingOrderHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &pauseFinancialStandingOrderReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *pauseFinancialStandingOrderRes
+	res, st.Err = pauseFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type pauseFinancialStandingOrderReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type pauseFinancialStandingOrderRes struct {
+}
+
+func pauseFinancialStandingOrder(st *achaemenid.Stream, req *pauseFinancialStandingOrderReq) (res *pauseFinancialStandingOrderRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var fso = datastore.FinancialStandingOrder{
+		ID: req.ID,
+	}
+	err = fso.GetLastByID()
+	if err != nil {
+		return
+	}
+	if fso.FromUserID != st.Connection.UserID {
+		err = authorization.ErrUserNotOwnRecord
+		return
+	}
+	if fso.Status != datastore.FinancialStandingOrderActive {
+		err = ErrFinancialStandingOrderBadStatus
+		return
+	}
+
+	fso.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	fso.Status = datastore.FinancialStandingOrderPaused
+	err = fso.Set()
+	if err != nil {
+		return
+	}
+	fso.IndexRecordIDForID()
+
+	res = &pauseFinancialStandingOrderRes{}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *pauseFinancialStandingOrderReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *pauseFinancialStandingOrderReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *pauseFinancialStandingOrderReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *pauseFinancialStandingOrderReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *pauseFinancialStandingOrderReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *pauseFinancialStandingOrderReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *pauseFinancialStandingOrderReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *pauseFinancialStandingOrderReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *pauseFinancialStandingOrderRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *pauseFinancialStandingOrderRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *pauseFinancialStandingOrderRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *pauseFinancialStandingOrderRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *pauseFinancialStandingOrderRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *pauseFinancialStandingOrderRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *pauseFinancialStandingOrderRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *pauseFinancialStandingOrderRes) jsonLen() (ln int) {
+	return
+}
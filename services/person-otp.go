@@ -0,0 +1,51 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"crypto/sha512"
+
+	"../datastore"
+	er "../libgo/error"
+	"../libgo/otp"
+	"../libgo/syllab"
+)
+
+const (
+	personOTPDigits = 6
+	personOTPPeriod = 30 // https://tools.ietf.org/html/rfc6238#section-5.2
+)
+
+// checkPersonOTP check given OTP against person OTPPattern + OTPAdditional that person app generate it.
+// Use it to step-up security of sensitive requests even on authenticated connections.
+func checkPersonOTP(personID [32]byte, otpNumber uint64) (err *er.Error) {
+	if otpNumber == 0 {
+		return otp.ErrOTPWrongNumber
+	}
+
+	var pa = datastore.PersonAuthentication{
+		PersonID: personID,
+	}
+	err = pa.GetLastByPersonID()
+	if err != nil {
+		return
+	}
+
+	var otpReq = otp.GenerateTimeOTPReq{
+		Hasher:     sha512.New512_256(),
+		SecretKey:  pa.OTPPattern[:],
+		Additional: make([]byte, 4),
+		Period:     personOTPPeriod,
+		Digits:     personOTPDigits,
+	}
+	syllab.SetInt32(otpReq.Additional, 0, pa.OTPAdditional)
+	var timeOTP uint64
+	timeOTP, err = otp.GenerateTimeOTP(&otpReq)
+	if err != nil {
+		return
+	}
+	if otpNumber != timeOTP {
+		return otp.ErrOTPWrongNumber
+	}
+	return
+}
@@ -0,0 +1,61 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"time"
+
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+)
+
+// checkProductAuctionAuthorization check buyer can buy by the auction in now time of local society.
+// Zero AllowUserType, AllowWeekdays or AllowDayhours means auction not limit it e.g. custom auctions that not set them.
+func checkProductAuctionAuthorization(pa *getProductAuctionRes, buyerID [32]byte, buyerType authorization.UserType) (err *er.Error) {
+	var auth = &pa.Authorization
+	if auth.LiveUntil != 0 && auth.LiveUntil.Pass(etime.Now()) {
+		return ErrProductAuctionExpired
+	}
+	if auth.AllowUserID != [32]byte{} && auth.AllowUserID != buyerID {
+		return ErrProductAuctionNotAllowUser
+	}
+	if auth.GroupID != [32]byte{} {
+		var member bool
+		member, err = isActiveDelegate(auth.GroupID, buyerID)
+		if err != nil {
+			return
+		}
+		if !member {
+			return ErrProductAuctionNotAllowGroup
+		}
+	}
+	if auth.AllowUserType != 0 && auth.AllowUserType&buyerType == 0 {
+		return ErrProductAuctionNotAllowUserType
+	}
+
+	return checkProductAuctionTime(auth, localSociety.localTime(etime.Now()))
+}
+
+// productAuctionWeekdays is etime weekday of each time.Weekday.
+var productAuctionWeekdays = [7]etime.Weekdays{
+	time.Sunday:    etime.WeekdaysSunday,
+	time.Monday:    etime.WeekdaysMonday,
+	time.Tuesday:   etime.WeekdaysTuesday,
+	time.Wednesday: etime.WeekdaysWednesday,
+	time.Thursday:  etime.WeekdaysThursday,
+	time.Friday:    etime.WeekdaysFriday,
+	time.Saturday:  etime.WeekdaysSaturday,
+}
+
+// checkProductAuctionTime check auction allow weekday and day hour of given society local time.
+func checkProductAuctionTime(auth *authorization.Product, local time.Time) (err *er.Error) {
+	if auth.AllowWeekdays != 0 && !auth.AllowWeekdays.Check(productAuctionWeekdays[local.Weekday()]) {
+		return ErrProductAuctionNotAllowWeekday
+	}
+	// Dayhours bit n is n hour of the day.
+	if auth.AllowDayhours != 0 && !auth.AllowDayhours.Check(etime.Dayhours(1)<<uint(local.Hour())) {
+		return ErrProductAuctionNotAllowDayhour
+	}
+	return
+}
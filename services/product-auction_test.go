@@ -0,0 +1,73 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+	"time"
+
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+)
+
+func TestCheckProductAuctionAuthorization(t *testing.T) {
+	var buyerID, otherID = [32]byte{1}, [32]byte{2}
+	var tests = []struct {
+		name      string
+		auth      authorization.Product
+		buyerType authorization.UserType
+		want      *er.Error
+	}{
+		{"no limit", authorization.Product{}, authorization.UserTypePerson, nil},
+		{"live", authorization.Product{LiveUntil: etime.Now() + 3600}, authorization.UserTypePerson, nil},
+		{"expired", authorization.Product{LiveUntil: etime.Now() - 1}, authorization.UserTypePerson, ErrProductAuctionExpired},
+		{"allow user", authorization.Product{AllowUserID: buyerID}, authorization.UserTypePerson, nil},
+		{"other user", authorization.Product{AllowUserID: otherID}, authorization.UserTypePerson, ErrProductAuctionNotAllowUser},
+		{"allow user type", authorization.Product{AllowUserType: authorization.UserTypePerson}, authorization.UserTypePerson, nil},
+		{"other user type", authorization.Product{AllowUserType: authorization.UserTypePerson}, authorization.UserTypeOrg, ErrProductAuctionNotAllowUserType},
+		{"all days and hours", authorization.Product{AllowWeekdays: etime.WeekdaysAll, AllowDayhours: etime.DayhoursAll}, authorization.UserTypeOrg, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pa = getProductAuctionRes{Authorization: tt.auth}
+			var err = checkProductAuctionAuthorization(&pa, buyerID, tt.buyerType)
+			if (tt.want == nil && err != nil) || (tt.want != nil && !err.Equal(tt.want)) {
+				t.Errorf("checkProductAuctionAuthorization() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckProductAuctionTime(t *testing.T) {
+	// 2020-01-06 is Monday.
+	var monday10 = time.Date(2020, 1, 6, 10, 30, 0, 0, time.UTC)
+	var tests = []struct {
+		name  string
+		auth  authorization.Product
+		local time.Time
+		want  *er.Error
+	}{
+		{"no limit", authorization.Product{}, monday10, nil},
+		{"allow weekday", authorization.Product{AllowWeekdays: etime.WeekdaysMonday}, monday10, nil},
+		{"other weekday", authorization.Product{AllowWeekdays: etime.WeekdaysSunday | etime.WeekdaysTuesday}, monday10, ErrProductAuctionNotAllowWeekday},
+		{"sunday", authorization.Product{AllowWeekdays: etime.WeekdaysSunday}, monday10.AddDate(0, 0, -1), nil},
+		{"saturday", authorization.Product{AllowWeekdays: etime.WeekdaysSaturday}, monday10.AddDate(0, 0, 5), nil},
+		{"allow dayhour", authorization.Product{AllowDayhours: etime.Dayhours(1) << 10}, monday10, nil},
+		{"other dayhour", authorization.Product{AllowDayhours: etime.Dayhours(1) << 11}, monday10, ErrProductAuctionNotAllowDayhour},
+		{"midnight", authorization.Product{AllowDayhours: etime.Dayhours(1)}, time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), nil},
+		{"last hour", authorization.Product{AllowDayhours: etime.Dayhours(1) << 23}, time.Date(2020, 1, 6, 23, 59, 0, 0, time.UTC), nil},
+		{"weekday before dayhour", authorization.Product{AllowWeekdays: etime.WeekdaysSunday, AllowDayhours: etime.Dayhours(1) << 11},
+			monday10, ErrProductAuctionNotAllowWeekday},
+		{"society time zone", authorization.Product{AllowDayhours: etime.Dayhours(1) << 14},
+			monday10.In(time.FixedZone("IRST", 3*3600+1800)), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err = checkProductAuctionTime(&tt.auth, tt.local)
+			if (tt.want == nil && err != nil) || (tt.want != nil && !err.Equal(tt.want)) {
+				t.Errorf("checkProductAuctionTime() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
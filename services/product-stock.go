@@ -0,0 +1,265 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/log"
+	"../libgo/sdk/asanak.com"
+)
+
+// productStockBucket indicate which counter of ProductStock count a product.
+type productStockBucket uint8
+
+// Product stock buckets
+const (
+	productStockNone productStockBucket = iota // Product is void or sold and not count in any stock
+	productStockOnHand
+	productStockInTransit
+	productStockPreSale
+)
+
+// getProductStockBucket return the counter that count given product version.
+func getProductStockBucket(p *datastore.Product) productStockBucket {
+	if p == nil {
+		return productStockNone
+	}
+	switch p.Status {
+	case datastore.ProductVoid:
+		return productStockNone
+	case datastore.ProductInTransit:
+		return productStockInTransit
+	case datastore.ProductPreSale:
+		return productStockPreSale
+	case datastore.ProductChangeOwner:
+		// Product sold by an auction belong to its buyer and not count as stock anymore.
+		if p.ProductAuctionID != [32]byte{} {
+			return productStockNone
+		}
+	}
+	return productStockOnHand
+}
+
+// getProductStockAmount return how much given product add to its stock counter. Measurable products count by their
+// quantity in quiddity base unit and others count as one.
+func getProductStockAmount(p *datastore.Product) int64 {
+	if p.Quantity != 0 {
+		return int64(p.Quantity)
+	}
+	return 1
+}
+
+// moveProductStock update DC and org stocks after a product save. before is last version of the product or nil
+// for a new product and after is the saved version. Failures just log due to product saved successfully.
+func moveProductStock(before, after *datastore.Product) {
+	var beforeBucket, afterBucket = getProductStockBucket(before), getProductStockBucket(after)
+	if beforeBucket == productStockNone && afterBucket == productStockNone {
+		return
+	}
+	if beforeBucket == afterBucket && before.QuiddityID == after.QuiddityID && before.DCID == after.DCID &&
+		before.OwnerID == after.OwnerID && before.Quantity == after.Quantity {
+		return
+	}
+
+	// Product without DC e.g. sold without DC stock just count in its owner stock.
+	if beforeBucket != productStockNone {
+		var amount = getProductStockAmount(before)
+		if before.DCID != [32]byte{} {
+			addProductStock(before.QuiddityID, datastore.ProductStockDC, before.DCID, beforeBucket, -amount)
+		}
+		addProductStock(before.QuiddityID, datastore.ProductStockOrg, before.OwnerID, beforeBucket, -amount)
+	}
+	if afterBucket != productStockNone {
+		var amount = getProductStockAmount(after)
+		if after.DCID != [32]byte{} {
+			addProductStock(after.QuiddityID, datastore.ProductStockDC, after.DCID, afterBucket, amount)
+		}
+		addProductStock(after.QuiddityID, datastore.ProductStockOrg, after.OwnerID, afterBucket, amount)
+	}
+}
+
+// getProductStockScope return stock scope of requested DC or org. Empty both means org of requester user.
+func getProductStockScope(st *achaemenid.Stream, dcID, orgID [32]byte) (scopeID [32]byte, scope datastore.ProductStockScope) {
+	if dcID != [32]byte{} {
+		return dcID, datastore.ProductStockDC
+	}
+	if orgID != [32]byte{} {
+		return orgID, datastore.ProductStockOrg
+	}
+	return st.Connection.UserID, datastore.ProductStockOrg
+}
+
+const (
+	productStockDeltasPage     = 256
+	productStockSnapshotDeltas = 64 // Write new snapshot when reader sum this number of deltas after last one
+)
+
+// addProductStock append delta of given bucket to the stock and notify scope owner if on hand drop below reorder level.
+func addProductStock(quiddityID [32]byte, scope datastore.ProductStockScope, scopeID [32]byte, bucket productStockBucket, delta int64) {
+	var ps = datastore.ProductStock{
+		QuiddityID: quiddityID,
+		ScopeID:    scopeID,
+		Scope:      scope,
+	}
+	var change [32]byte
+	switch bucket {
+	case productStockOnHand:
+		change = datastore.MakeProductStockDelta(delta, 0, 0)
+	case productStockInTransit:
+		change = datastore.MakeProductStockDelta(0, delta, 0)
+	case productStockPreSale:
+		change = datastore.MakeProductStockDelta(0, 0, delta)
+	}
+	var err = ps.IndexDeltaForQuiddityIDScopeID(change)
+	if err != nil {
+		// TODO::: we must retry more due to product wrote successfully!
+		log.Warn("Product stock of", quiddityID, "in", scopeID, "can't write due to:", err)
+		return
+	}
+
+	// Read stock after each change to write its snapshots and list new stocks for recount.
+	err = readProductStock(&ps)
+	if err != nil {
+		log.Warn("Product stock of", quiddityID, "in", scopeID, "can't read due to:", err)
+		return
+	}
+	if bucket != productStockOnHand || delta >= 0 {
+		return
+	}
+	var reorderLevel int64
+	reorderLevel, err = getProductStockReorderLevel(quiddityID, scope, scopeID)
+	if err != nil {
+		log.Warn("Product stock reorder of", quiddityID, "in", scopeID, "can't read due to:", err)
+		return
+	}
+	// Other deltas may sum between our append and read, but just drop that pass the level notify.
+	var lastOnHand = ps.OnHand - delta
+	if reorderLevel != 0 && lastOnHand >= reorderLevel && ps.OnHand < reorderLevel {
+		go notifyProductStockReorder(ps)
+	}
+}
+
+// readProductStock read last snapshot of the stock and sum deltas after it. It write new snapshot when many deltas
+// are after the last one, so next reads stay fast. It return ErrRecordNotFound if stock never changed.
+func readProductStock(ps *datastore.ProductStock) (err *er.Error) {
+	err = ps.GetLastByQuiddityIDScopeID()
+	var isNew = err.Equal(ganjine.ErrRecordNotFound)
+	if err != nil && !isNew {
+		return
+	}
+
+	var snapshotDeltas = ps.DeltaCount
+	var deltas [][32]byte
+	for {
+		deltas, err = ps.FindDeltasByQuiddityIDScopeID(ps.DeltaCount, productStockDeltasPage)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+		for _, delta := range deltas {
+			ps.AddDelta(delta)
+		}
+		if len(deltas) < productStockDeltasPage {
+			break
+		}
+	}
+
+	if isNew && ps.DeltaCount == 0 {
+		return ganjine.ErrRecordNotFound
+	}
+	if isNew || ps.DeltaCount-snapshotDeltas >= productStockSnapshotDeltas {
+		// Snapshots are sum of the first DeltaCount deltas so concurrent writers write same counters for same count.
+		ps.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+		ps.UserConnectionID = [32]byte{}
+		var snapshotErr *er.Error
+		if isNew {
+			snapshotErr = ps.SaveNew()
+		} else {
+			snapshotErr = ps.Set()
+			ps.IndexRecordIDForQuiddityIDScopeID()
+		}
+		if snapshotErr != nil {
+			log.Warn("Product stock snapshot of", ps.QuiddityID, "in", ps.ScopeID, "can't write due to:", snapshotErr)
+		}
+	}
+	return
+}
+
+// getProductStockReorderLevel return reorder level of the stock. Zero means no notify!
+func getProductStockReorderLevel(quiddityID [32]byte, scope datastore.ProductStockScope, scopeID [32]byte) (reorderLevel int64, err *er.Error) {
+	var psr = datastore.ProductStockReorder{
+		QuiddityID: quiddityID,
+		ScopeID:    scopeID,
+		Scope:      scope,
+	}
+	err = psr.GetLastByQuiddityIDScopeID()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return
+	}
+	return psr.ReorderLevel, nil
+}
+
+// notifyProductStockReorder send SMS to leader of the scope org that stock of a quiddity drop below its reorder level.
+func notifyProductStockReorder(ps datastore.ProductStock) {
+	var err = sendProductStockReorderSMS(&ps)
+	if err != nil {
+		log.Warn("Product stock reorder of", ps.QuiddityID, "in", ps.ScopeID, "can't notify due to:", err)
+	}
+}
+
+func sendProductStockReorderSMS(ps *datastore.ProductStock) (err *er.Error) {
+	// Leader of an org is first person that org gave delegate connection to it.
+	var uac = datastore.UserAppConnection{
+		UserID: ps.ScopeID,
+	}
+	var IDs [][32]byte
+	IDs, err = uac.FindIDsByGivenDelegate(0, 1)
+	if err != nil {
+		return
+	}
+	if len(IDs) == 0 {
+		return ErrProductStockNoLeader
+	}
+	uac.ID = IDs[0]
+	err = uac.GetLastByID()
+	if err != nil {
+		return
+	}
+
+	var pn = datastore.PersonNumber{
+		PersonID: uac.DelegateUserID,
+	}
+	err = pn.GetLastByPersonID()
+	if err != nil {
+		return
+	}
+
+	if log.DevMode {
+		log.Debug("Product stock reorder of", ps.QuiddityID, "in", ps.ScopeID, "OnHand:", ps.OnHand)
+		return
+	}
+	var sendSMSReq = asanak.SendSMSReq{
+		Destination: []string{strconv.FormatUint(pn.Number, 10)},
+	}
+	sendSMSReq.Message = append(sendSMSReq.Message, productStockReorderSMSTemplate...)
+	sendSMSReq.Message = append(sendSMSReq.Message, base64.RawURLEncoding.EncodeToString(ps.QuiddityID[:])...)
+	sendSMSReq.Message = append(sendSMSReq.Message, "\nOn hand: "...)
+	sendSMSReq.Message = strconv.AppendInt(sendSMSReq.Message, ps.OnHand, 10)
+	sendSMSReq.Message = append(sendSMSReq.Message, "\n\n@"+achaemenid.Server.Manifest.DomainName...)
+	_, err = smsProvider.SendSMS(&sendSMSReq)
+	return
+}
+
+const productStockReorderSMSTemplate = "SabzCity\n\nLow stock, reorder quiddity\n"
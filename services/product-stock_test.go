@@ -0,0 +1,82 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../datastore"
+)
+
+func TestGetProductStockBucket(t *testing.T) {
+	var auctionID = [32]byte{1}
+	var tests = []struct {
+		name    string
+		product *datastore.Product
+		want    productStockBucket
+	}{
+		{"new product", nil, productStockNone},
+		{"created", &datastore.Product{Status: datastore.ProductCreated}, productStockOnHand},
+		{"change DC", &datastore.Product{Status: datastore.ProductChangeDC}, productStockOnHand},
+		{"split", &datastore.Product{Status: datastore.ProductChangeQuiddity}, productStockOnHand},
+		{"dispatch", &datastore.Product{Status: datastore.ProductInTransit}, productStockInTransit},
+		{"pre sale", &datastore.Product{Status: datastore.ProductPreSale}, productStockPreSale},
+		{"void", &datastore.Product{Status: datastore.ProductVoid}, productStockNone},
+		{"owner transfer", &datastore.Product{Status: datastore.ProductChangeOwner}, productStockOnHand},
+		{"sold by auction", &datastore.Product{Status: datastore.ProductChangeOwner, ProductAuctionID: auctionID}, productStockNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getProductStockBucket(tt.product); got != tt.want {
+				t.Errorf("getProductStockBucket() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProductStockBucket_Transitions(t *testing.T) {
+	var onHand = &datastore.Product{Status: datastore.ProductCreated}
+	var inTransit = &datastore.Product{Status: datastore.ProductInTransit}
+	var received = &datastore.Product{Status: datastore.ProductChangeDC}
+	var preSale = &datastore.Product{Status: datastore.ProductPreSale}
+	var sold = &datastore.Product{Status: datastore.ProductChangeOwner, ProductAuctionID: [32]byte{1}}
+	var tests = []struct {
+		name          string
+		before, after *datastore.Product
+		from, to      productStockBucket
+	}{
+		{"register", nil, onHand, productStockNone, productStockOnHand},
+		{"dispatch", onHand, inTransit, productStockOnHand, productStockInTransit},
+		{"acknowledge", inTransit, received, productStockInTransit, productStockOnHand},
+		{"reject dispatch", inTransit, onHand, productStockInTransit, productStockOnHand},
+		{"pre sale", onHand, preSale, productStockOnHand, productStockPreSale},
+		{"sell", onHand, sold, productStockOnHand, productStockNone},
+		{"sale rollback", sold, onHand, productStockNone, productStockOnHand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to = getProductStockBucket(tt.before), getProductStockBucket(tt.after)
+			if from != tt.from || to != tt.to {
+				t.Errorf("getProductStockBucket() move %v >> %v, want %v >> %v", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestGetProductStockAmount(t *testing.T) {
+	var tests = []struct {
+		name    string
+		product datastore.Product
+		want    int64
+	}{
+		{"countable", datastore.Product{}, 1},
+		{"measurable", datastore.Product{Quantity: 2500}, 2500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getProductStockAmount(&tt.product); got != tt.want {
+				t.Errorf("getProductStockAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
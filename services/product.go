@@ -0,0 +1,172 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"math/bits"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	lang "../libgo/language"
+)
+
+const (
+	productMaxSplitMerge      = 100
+	productHistoryMaxVersions = 256
+	productHistoryMaxDepth    = 16  // How many levels of parent products follow by ProductionID and merge links
+	productHistoryMaxProducts = 256 // Max products in a history due to each merge can has many parents
+)
+
+// checkProductChangeable check connection user own the product and product can change e.g. give away, split, merge, ...
+// Delegate connections has owner UserID and st.Authorize() check their access to the service.
+func checkProductChangeable(st *achaemenid.Stream, p *datastore.Product) (err *er.Error) {
+	if p.OwnerID != st.Connection.UserID {
+		err = authorization.ErrUserNotOwnRecord
+		return
+	}
+	switch p.Status {
+	case datastore.ProductVoid:
+		err = ErrProductVoid
+		return
+	case datastore.ProductInTransit:
+		err = ErrProductInTransit
+		return
+	}
+
+	err = checkProductQuiddity(p.QuiddityID)
+	if err != nil {
+		return
+	}
+
+	// Pre-sale product with held escrow belong to its buyer on release, so owner can't change it.
+	var fe = datastore.FinancialEscrow{
+		ProductID: p.ID,
+	}
+	var escrowIDs [][32]byte
+	escrowIDs, err = fe.FindIDsByProductID(18446744073709551615, 1)
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	fe.ID = escrowIDs[0]
+	err = fe.GetLastByID()
+	if err != nil {
+		return
+	}
+	if fe.Status == datastore.FinancialEscrowHeld {
+		err = ErrFinancialEscrowExist
+	}
+	return
+}
+
+// checkProductQuiddity check quiddity exist and not blocked by justice.
+func checkProductQuiddity(quiddityID [32]byte) (err *er.Error) {
+	_, err = getProductQuiddity(quiddityID)
+	return
+}
+
+// checkProductChangeQuiddity check products of a quiddity can split or merge to products of other quiddity.
+// Both quiddities must belong to same org and count products quantity by same base unit.
+func checkProductChangeQuiddity(fromQuiddityID, toQuiddityID [32]byte) (err *er.Error) {
+	if fromQuiddityID == toQuiddityID {
+		return
+	}
+	var from, to datastore.Quiddity
+	from, err = getProductQuiddity(fromQuiddityID)
+	if err != nil {
+		return
+	}
+	to, err = getProductQuiddity(toQuiddityID)
+	if err != nil {
+		return
+	}
+	if from.OrgID != to.OrgID || from.Unit != to.Unit {
+		err = ErrProductQuiddityMismatch
+	}
+	return
+}
+
+// checkProductQuantities check quantities are more than zero and add up to the total without overflow.
+func checkProductQuantities(total uint64, quantities []uint64) (err *er.Error) {
+	var sum, carry uint64
+	for _, quantity := range quantities {
+		if quantity == 0 {
+			return ErrProductQuantityMismatch
+		}
+		sum, carry = bits.Add64(sum, quantity, 0)
+		if carry != 0 {
+			return ErrProductQuantityMismatch
+		}
+	}
+	if sum != total {
+		return ErrProductQuantityMismatch
+	}
+	return
+}
+
+// checkProductOwner check given user is a registered person or org that can own a product.
+func checkProductOwner(userID [32]byte) (err *er.Error) {
+	var pa = datastore.PersonAuthentication{
+		PersonID: userID,
+	}
+	err = pa.GetLastByPersonID()
+	if !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+
+	var oa = datastore.OrganizationAuthentication{
+		ID: userID,
+	}
+	err = oa.GetLastByID()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		err = ErrProductOwnerNotFound
+	}
+	return
+}
+
+// getProductQuiddity get quiddity in its first language and check it is not blocked by justice.
+func getProductQuiddity(quiddityID [32]byte) (q datastore.Quiddity, err *er.Error) {
+	q = datastore.Quiddity{
+		ID: quiddityID,
+	}
+	var languages []lang.Language
+	languages, err = q.FindLanguagesByID(0, 1)
+	if err != nil {
+		return
+	}
+	q.Language = languages[0]
+	err = q.GetLastByIDLang()
+	if err != nil {
+		return
+	}
+	if q.Status == datastore.QuiddityStatusBlocked {
+		err = ErrBlockedByJustice
+	}
+	return
+}
+
+// voidProduct write void version of the product and remove it from its DC stock.
+// mergedID is the product that given product merged to it if any.
+func voidProduct(st *achaemenid.Stream, p *datastore.Product, mergedID [32]byte) (err *er.Error) {
+	var before = *p
+	p.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	p.UserConnectionID = st.Connection.ID
+	p.Status = datastore.ProductVoid
+	if mergedID != [32]byte{} {
+		p.ProductionID = mergedID
+	}
+	err = p.Set()
+	if err != nil {
+		return
+	}
+	p.IndexRecordIDForID()
+	p.DeleteTempIndexIDForQuiddityIDDCID()
+	moveProductStock(&before, p)
+	return
+}
@@ -0,0 +1,36 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"math"
+	"testing"
+
+	er "../libgo/error"
+)
+
+func TestCheckProductQuantities(t *testing.T) {
+	var tests = []struct {
+		name       string
+		total      uint64
+		quantities []uint64
+		err        *er.Error
+	}{
+		{"add up", 10, []uint64{3, 7}, nil},
+		{"single", 5, []uint64{5}, nil},
+		{"less than total", 10, []uint64{3, 6}, ErrProductQuantityMismatch},
+		{"more than total", 10, []uint64{3, 8}, ErrProductQuantityMismatch},
+		{"zero quantity", 10, []uint64{10, 0}, ErrProductQuantityMismatch},
+		{"no quantity", 10, nil, ErrProductQuantityMismatch},
+		{"overflow wrap to total", 1, []uint64{math.MaxUint64, 2}, ErrProductQuantityMismatch},
+		{"max without overflow", math.MaxUint64, []uint64{math.MaxUint64 - 1, 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err = checkProductQuantities(tt.total, tt.quantities)
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Errorf("checkProductQuantities() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
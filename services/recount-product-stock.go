@@ -0,0 +1,381 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var recountProductStockService = achaemenid.Service{
+	ID:                1820354138,
+	IssueDate:         1792295957,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypePerson,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Recount Product Stock",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `count live products of a quiddity and correct its DCs and orgs stocks e.g. for products that made before stocks.
+Just platform admin can call this service and must call it when products of the quiddity not change.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: RecountProductStockSRPC,
+	HTTPHandler: RecountProductStockHTTP,
+}
+
+// RecountProductStockSRPC is sRPC handler of RecountProductStock service.
+func RecountProductStockSRPC(st *achaemenid.Stream) {
+	var req = &recountProductStockReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *recountProductStockRes
+	res, st.Err = recountProductStock(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// RecountProductStockHTTP is HTTP handler of RecountProductStock service.
+func RecountProductStockHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &recountProductStockReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *recountProductStockRes
+	res, st.Err = recountProductStock(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type recountProductStockReq struct {
+	QuiddityID [32]byte   `json:",string"`
+	FromDay    etime.Time // Day that first product of the quiddity made
+}
+
+type recountProductStockRes struct {
+	Products uint64 // Number of live products that counted
+	Stocks   uint64 // Number of stocks that corrected
+}
+
+// productStockKey indicate a stock of a quiddity.
+type productStockKey struct {
+	Scope   datastore.ProductStockScope
+	ScopeID [32]byte
+}
+
+// productStockCount is counters of a stock that recount from products.
+type productStockCount struct {
+	OnHand    int64
+	InTransit int64
+	PreSale   int64
+}
+
+const productStockRecountPageLimit = 256
+
+func recountProductStock(st *achaemenid.Stream, req *recountProductStockReq) (res *recountProductStockRes, err *er.Error) {
+	if st.Connection.UserID != adminUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	res = &recountProductStockRes{}
+	var counts = make(map[productStockKey]*productStockCount)
+	res.Products, err = countProductStock(req.QuiddityID, req.FromDay, counts)
+	if err != nil {
+		return
+	}
+	// Stocks that has no live product now must recount to zero too.
+	for _, scope := range [...]datastore.ProductStockScope{datastore.ProductStockDC, datastore.ProductStockOrg} {
+		err = findProductStockScopes(req.QuiddityID, scope, counts)
+		if err != nil {
+			return
+		}
+	}
+
+	for key, count := range counts {
+		var ps = datastore.ProductStock{
+			QuiddityID: req.QuiddityID,
+			ScopeID:    key.ScopeID,
+			Scope:      key.Scope,
+		}
+		err = readProductStock(&ps)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+		if ps.OnHand == count.OnHand && ps.InTransit == count.InTransit && ps.PreSale == count.PreSale {
+			continue
+		}
+
+		err = ps.IndexDeltaForQuiddityIDScopeID(datastore.MakeProductStockDelta(count.OnHand-ps.OnHand,
+			count.InTransit-ps.InTransit, count.PreSale-ps.PreSale))
+		if err != nil {
+			return
+		}
+		// Read again to write snapshot and list the stock if it is new.
+		err = readProductStock(&ps)
+		if err != nil {
+			return
+		}
+		res.Stocks++
+	}
+	return
+}
+
+// countProductStock count live products of the quiddity that made from fromDay until now in their DC and owner stocks.
+func countProductStock(quiddityID [32]byte, fromDay etime.Time, counts map[productStockKey]*productStockCount) (products uint64, err *er.Error) {
+	var today = etime.Time(etime.Now().RoundToDay())
+	var day = datastore.Product{
+		QuiddityID: quiddityID,
+		WriteTime:  etime.Time(fromDay.RoundToDay()),
+	}
+	for ; day.WriteTime <= today; day.WriteTime += (24 * 60 * 60) {
+		var offset uint64
+		for {
+			var IDs [][32]byte
+			IDs, err = day.FindIDsByQuiddityIDDaily(offset, productStockRecountPageLimit)
+			if err.Equal(ganjine.ErrRecordNotFound) {
+				err = nil
+				break
+			}
+			if err != nil {
+				return
+			}
+
+			for _, id := range IDs {
+				var p = datastore.Product{
+					ID: id,
+				}
+				err = p.GetLastByID()
+				if err != nil {
+					return
+				}
+				var bucket = getProductStockBucket(&p)
+				if bucket == productStockNone || p.QuiddityID != quiddityID {
+					continue
+				}
+
+				var amount = getProductStockAmount(&p)
+				if p.DCID != [32]byte{} {
+					getProductStockCount(counts, datastore.ProductStockDC, p.DCID).add(bucket, amount)
+				}
+				getProductStockCount(counts, datastore.ProductStockOrg, p.OwnerID).add(bucket, amount)
+				products++
+			}
+
+			if len(IDs) < productStockRecountPageLimit {
+				break
+			}
+			offset += productStockRecountPageLimit
+		}
+	}
+	return
+}
+
+// findProductStockScopes add stocks of the quiddity in given scope to counts if not exist.
+func findProductStockScopes(quiddityID [32]byte, scope datastore.ProductStockScope, counts map[productStockKey]*productStockCount) (err *er.Error) {
+	var ps = datastore.ProductStock{
+		QuiddityID: quiddityID,
+		Scope:      scope,
+	}
+	var offset uint64
+	for {
+		var scopeIDs [][32]byte
+		scopeIDs, err = ps.FindScopeIDsByQuiddityID(offset, productStockRecountPageLimit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		for _, scopeID := range scopeIDs {
+			getProductStockCount(counts, scope, scopeID)
+		}
+
+		if len(scopeIDs) < productStockRecountPageLimit {
+			return
+		}
+		offset += productStockRecountPageLimit
+	}
+}
+
+// getProductStockCount return counters of given stock and add it to counts if not exist.
+func getProductStockCount(counts map[productStockKey]*productStockCount, scope datastore.ProductStockScope, scopeID [32]byte) (count *productStockCount) {
+	var key = productStockKey{
+		Scope:   scope,
+		ScopeID: scopeID,
+	}
+	count = counts[key]
+	if count == nil {
+		count = &productStockCount{}
+		counts[key] = count
+	}
+	return
+}
+
+func (count *productStockCount) add(bucket productStockBucket, amount int64) {
+	switch bucket {
+	case productStockOnHand:
+		count.OnHand += amount
+	case productStockInTransit:
+		count.InTransit += amount
+	case productStockPreSale:
+		count.PreSale += amount
+	}
+}
+
+func (req *recountProductStockReq) validator() (err *er.Error) {
+	if req.FromDay <= 0 || req.FromDay > etime.Now() {
+		err = ErrProductStockBadRecountDay
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *recountProductStockReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.QuiddityID[:], buf[0:])
+	req.FromDay = etime.Time(syllab.GetInt64(buf, 32))
+	return
+}
+
+func (req *recountProductStockReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.QuiddityID[:])
+	syllab.SetInt64(buf, 32, int64(req.FromDay))
+	return
+}
+
+func (req *recountProductStockReq) syllabStackLen() (ln uint32) {
+	return 40
+}
+
+func (req *recountProductStockReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *recountProductStockReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *recountProductStockReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *recountProductStockReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *recountProductStockReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *recountProductStockRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Products = syllab.GetUInt64(buf, 0)
+	res.Stocks = syllab.GetUInt64(buf, 8)
+	return
+}
+
+func (res *recountProductStockRes) syllabEncoder(buf []byte) {
+	syllab.SetUInt64(buf, 0, res.Products)
+	syllab.SetUInt64(buf, 8, res.Stocks)
+	return
+}
+
+func (res *recountProductStockRes) syllabStackLen() (ln uint32) {
+	return 16
+}
+
+func (res *recountProductStockRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *recountProductStockRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *recountProductStockRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *recountProductStockRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *recountProductStockRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,328 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var refundProductInvoiceService = achaemenid.Service{
+	ID:                1862739506,
+	IssueDate:         1792294369,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Refund Product Invoice",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `return a sold product to the selling organization. Buyer get back discounted price and all credits of
+the sale e.g. commissions reverse. Every leg of refund reference to original invoice leg.
+Call it again with same product if it failed in middle to finish the refund.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction", "Product",
+	},
+
+	SRPCHandler: RefundProductInvoiceSRPC,
+	HTTPHandler: RefundProductInvoiceHTTP,
+}
+
+// RefundProductInvoiceSRPC is sRPC handler of RefundProductInvoice service.
+func RefundProductInvoiceSRPC(st *achaemenid.Stream) {
+	var req = &refundProductInvoiceReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *refundProductInvoiceRes
+	res, st.Err = refundProductInvoice(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// RefundProductInvoiceHTTP is HTTP handler of RefundProductInvoice service.
+func RefundProductInvoiceHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &refundProductInvoiceReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *refundProductInvoiceRes
+	res, st.Err = refundProductInvoice(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type refundProductInvoiceReq struct {
+	ProductID [32]byte `json:",string"`
+	BuyerID   [32]byte `json:",string"`
+}
+
+type refundProductInvoiceRes struct {
+	TransactionID [32]byte `json:",string"` // Buyer refund leg
+}
+
+func refundProductInvoice(st *achaemenid.Stream, req *refundProductInvoiceReq) (res *refundProductInvoiceRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var product = datastore.Product{
+		ID: req.ProductID,
+	}
+	err = product.GetLastByID()
+	if err != nil {
+		return
+	}
+	if product.OwnerID != req.BuyerID || product.Status != datastore.ProductChangeOwner {
+		err = ErrProductRefundNotOwned
+		return
+	}
+
+	var pa = datastore.ProductAuction{
+		ID: product.ProductAuctionID,
+	}
+	err = pa.GetLastByID()
+	if err != nil {
+		return
+	}
+	// Just shop side of the sale can accept a return.
+	if st.Connection.UserID != pa.OrgID && st.Connection.UserID != product.DCID && st.Connection.UserID != product.SellerID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var sale, credits []datastore.FinancialTransaction
+	sale, err = findFinancialTransactionsByReferenceID(product.ID)
+	if err != nil {
+		return
+	}
+	var payment, found = findProductSalePayment(sale, req.BuyerID)
+	if !found {
+		err = ErrProductRefundNoPayment
+		return
+	}
+	credits = findProductSaleCredits(sale, &payment)
+
+	// Check balances first to not stop refund in the middle as much as possible.
+	for i := range credits {
+		var last = datastore.FinancialTransaction{
+			UserID:    credits[i].UserID,
+			WriteTime: etime.Now(),
+			Currency:  credits[i].Currency,
+		}
+		err = last.GetLastTransactionByUserID()
+		if err != nil {
+			return
+		}
+		if last.Balance < credits[i].Amount {
+			err = ErrFinancialTransactionBalance
+			return
+		}
+	}
+
+	// Credits reverse before buyer get back money, so a half-done refund never pay buyer more than it take back.
+	for i := range credits {
+		var leg = datastore.FinancialTransaction{
+			AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+			UserID:        credits[i].UserID,
+			ReferenceID:   credits[i].RecordID,
+			ReferenceType: datastore.FinancialTransactionRefund,
+			Amount:        -credits[i].Amount,
+			Currency:      credits[i].Currency,
+		}
+		err = saveFinancialTransactionOnce(&leg)
+		if err != nil {
+			return
+		}
+	}
+
+	var refund = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserID:        req.BuyerID,
+		ReferenceID:   payment.RecordID,
+		ReferenceType: datastore.FinancialTransactionRefund,
+		Amount:        -payment.Amount,
+		Currency:      payment.Currency,
+	}
+	err = saveFinancialTransactionOnce(&refund)
+	if err != nil {
+		return
+	}
+
+	// Returned product is in the shop stock again and can sell by any auction later.
+	var before = product
+	product.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	product.OwnerID = pa.OrgID
+	product.SellerID = [32]byte{}
+	product.ProductAuctionID = [32]byte{}
+	product.Status = datastore.ProductChangeOwner
+	err = product.SaveNew()
+	if err != nil {
+		return
+	}
+	moveProductStock(&before, &product)
+
+	res = &refundProductInvoiceRes{
+		TransactionID: refund.RecordID,
+	}
+	return
+}
+
+// findProductSalePayment return last debit leg of buyer for the product in given product legs.
+func findProductSalePayment(legs []datastore.FinancialTransaction, buyerID [32]byte) (payment datastore.FinancialTransaction, found bool) {
+	for i := len(legs) - 1; i >= 0; i-- {
+		if legs[i].UserID == buyerID && legs[i].Amount < 0 && legs[i].ReferenceType == datastore.FinancialTransactionProductAuctionPrice {
+			return legs[i], true
+		}
+	}
+	return
+}
+
+// findProductSaleCredits return credit legs of the sale that written with or after the payment leg.
+func findProductSaleCredits(legs []datastore.FinancialTransaction, payment *datastore.FinancialTransaction) (credits []datastore.FinancialTransaction) {
+	var after bool
+	for _, leg := range legs {
+		if leg.RecordID == payment.RecordID {
+			after = true
+			continue
+		}
+		if !after || leg.Amount <= 0 {
+			continue
+		}
+		switch leg.ReferenceType {
+		case datastore.FinancialTransactionProductAuctionPrice, datastore.FinancialTransactionProductAuctionCommission:
+			credits = append(credits, leg)
+		}
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *refundProductInvoiceReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ProductID[:], buf[0:])
+	copy(req.BuyerID[:], buf[32:])
+	return
+}
+
+func (req *refundProductInvoiceReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ProductID[:])
+	copy(buf[32:], req.BuyerID[:])
+	return
+}
+
+func (req *refundProductInvoiceReq) syllabStackLen() (ln uint32) {
+	return 64
+}
+
+func (req *refundProductInvoiceReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *refundProductInvoiceReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *refundProductInvoiceReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *refundProductInvoiceReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *refundProductInvoiceReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *refundProductInvoiceRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.TransactionID[:], buf[0:])
+	return
+}
+
+func (res *refundProductInvoiceRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.TransactionID[:])
+	return
+}
+
+func (res *refundProductInvoiceRes) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (res *refundProductInvoiceRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *refundProductInvoiceRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *refundProductInvoiceRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *refundProductInvoiceRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *refundProductInvoiceRes) jsonLen() (ln int) {
+	return
+}
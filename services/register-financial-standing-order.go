@@ -0,0 +1,256 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+var registerFinancialStandingOrderService = achaemenid.Service{
+	ID:                3158264093,
+	IssueDate:         1792294756,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDCreate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Register Financial Standing Order",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `register recurring payment from user to other user e.g. rent, membership or instalments.
+Scheduler transfer amount each period from first run time until end time.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: RegisterFinancialStandingOrderSRPC,
+	HTTPHandler: RegisterFinancialStandingOrderHTTP,
+}
+
+// RegisterFinancialStandingOrderSRPC is sRPC handler of RegisterFinancialStandingOrder service.
+func RegisterFinancialStandingOrderSRPC(st *achaemenid.Stream) {
+	var req = &registerFinancialStandingOrderReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *registerFinancialStandingOrderRes
+	res, st.Err = registerFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// RegisterFinancialStandingOrderHTTP is HTTP handler of RegisterFinancialStandingOrder service.
+func RegisterFinancialStandingOrderHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &registerFinancialStandingOrderReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *registerFinancialStandingOrderRes
+	res, st.Err = registerFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type registerFinancialStandingOrderReq struct {
+	ToUserID     [32]byte `json:",string"`
+	Amount       price.Amount
+	PeriodDays   uint32
+	FirstRunTime etime.Time // Zero means now
+	EndTime      etime.Time // Zero means no end
+}
+
+type registerFinancialStandingOrderRes struct {
+	ID [32]byte `json:",string"`
+}
+
+func registerFinancialStandingOrder(st *achaemenid.Stream, req *registerFinancialStandingOrderReq) (res *registerFinancialStandingOrderRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+	if req.ToUserID == st.Connection.UserID {
+		err = ErrFinancialTransactionSameUser
+		return
+	}
+
+	var fso = datastore.FinancialStandingOrder{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		ID:            uuid.Random32Byte(),
+		FromUserID:    st.Connection.UserID,
+		ToUserID:      req.ToUserID,
+		Amount:        req.Amount,
+		PeriodDays:    req.PeriodDays,
+		ScheduledTime: req.FirstRunTime,
+		NextRunTime:   req.FirstRunTime,
+		EndTime:       req.EndTime,
+		Status:        datastore.FinancialStandingOrderActive,
+	}
+	err = fso.SaveNew()
+	if err != nil {
+		return
+	}
+
+	res = &registerFinancialStandingOrderRes{
+		ID: fso.ID,
+	}
+	return
+}
+
+func (req *registerFinancialStandingOrderReq) validator() (err *er.Error) {
+	if req.Amount <= 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+	var now = etime.Now()
+	if req.FirstRunTime < now {
+		req.FirstRunTime = now
+	}
+	if req.PeriodDays == 0 || req.PeriodDays > 366 || (req.EndTime != 0 && req.EndTime < req.FirstRunTime) {
+		err = ErrFinancialStandingOrderBadPeriod
+		return
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *registerFinancialStandingOrderReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ToUserID[:], buf[0:])
+	req.Amount = price.Amount(syllab.GetInt64(buf, 32))
+	req.PeriodDays = syllab.GetUInt32(buf, 40)
+	req.FirstRunTime = etime.Time(syllab.GetInt64(buf, 44))
+	req.EndTime = etime.Time(syllab.GetInt64(buf, 52))
+	return
+}
+
+func (req *registerFinancialStandingOrderReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ToUserID[:])
+	syllab.SetInt64(buf, 32, int64(req.Amount))
+	syllab.SetUInt32(buf, 40, req.PeriodDays)
+	syllab.SetInt64(buf, 44, int64(req.FirstRunTime))
+	syllab.SetInt64(buf, 52, int64(req.EndTime))
+	return
+}
+
+func (req *registerFinancialStandingOrderReq) syllabStackLen() (ln uint32) {
+	return 60
+}
+
+func (req *registerFinancialStandingOrderReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *registerFinancialStandingOrderReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *registerFinancialStandingOrderReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *registerFinancialStandingOrderReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *registerFinancialStandingOrderReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *registerFinancialStandingOrderRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.ID[:], buf[0:])
+	return
+}
+
+func (res *registerFinancialStandingOrderRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.ID[:])
+	return
+}
+
+func (res *registerFinancialStandingOrderRes) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (res *registerFinancialStandingOrderRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *registerFinancialStandingOrderRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *registerFinancialStandingOrderRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *registerFinancialStandingOrderRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *registerFinancialStandingOrderRes) jsonLen() (ln int) {
+	return
+}
@@ -3,6 +3,8 @@
 package services
 
 import (
+	"crypto/sha512"
+
 	"../datastore"
 	"../libgo/achaemenid"
 	"../libgo/authorization"
@@ -10,6 +12,7 @@ import (
 	"../libgo/http"
 	"../libgo/json"
 	lang "../libgo/language"
+	"../libgo/log"
 	"../libgo/price"
 	sep "../libgo/sdk/sep.ir"
 	"../libgo/srpc"
@@ -96,10 +99,14 @@ type registerFinancialTransactionReq struct {
 
 	ToSocietyID uint32
 	ToUserID    [32]byte `json:",string"`
+
+	IdempotencyKey [32]byte `json:",string,optional"` // Client retry with same key get first response and not move money twice
+	OTP            uint64   `json:",optional"`        // Need when withdraw breach user financial rules that let OTP step-up
 }
 
 type registerFinancialTransactionRes struct {
-	ID [32]byte `json:",string"`
+	ID          [32]byte `json:",string"`
+	RedirectURL string   `json:",optional"` // Web payment gateway URL that user must redirect to it to pay
 }
 
 func registerFinancialTransaction(st *achaemenid.Stream, req *registerFinancialTransactionReq) (res *registerFinancialTransactionRes, err *er.Error) {
@@ -113,6 +120,34 @@ func registerFinancialTransaction(st *achaemenid.Stream, req *registerFinancialT
 		return
 	}
 
+	if req.IdempotencyKey != [32]byte{} {
+		var idempotency datastore.FinancialIdempotency
+		var replayed bool
+		idempotency, replayed, err = claimFinancialIdempotency(st, req.IdempotencyKey, req.idempotencyHash())
+		if err != nil {
+			return
+		}
+		if replayed {
+			res = &registerFinancialTransactionRes{
+				ID: idempotency.ResponseID,
+			}
+			if len(idempotency.Response) != 0 {
+				err = res.syllabDecoder(idempotency.Response)
+			}
+			return
+		}
+		defer func() {
+			var responseID [32]byte
+			var response []byte
+			if res != nil {
+				responseID = res.ID
+				response = make([]byte, res.syllabLen())
+				res.syllabEncoder(response)
+			}
+			finishFinancialIdempotency(&idempotency, responseID, response, err)
+		}()
+	}
+
 	if req.FromSocietyID != achaemenid.Server.Manifest.SocietyID && req.ToSocietyID != achaemenid.Server.Manifest.SocietyID {
 		err = ErrFinancialTransactionBadSociety
 		return
@@ -145,97 +180,128 @@ func registerFinancialTransaction(st *achaemenid.Stream, req *registerFinancialT
 					}
 
 					ft = datastore.FinancialTransaction{
-						UserID: req.ToUserID,
-					}
-					err = ft.Lock()
-					if err != nil {
-						return
-					}
-
-					ft = datastore.FinancialTransaction{
-						AppInstanceID:         achaemenid.Server.Nodes.LocalNode.InstanceID,
-						UserConnectionID:      st.Connection.ID,
-						UserID:                req.ToUserID,
-						ReferenceType:         datastore.FinancialTransactionPOSTransfer,
-						PreviousTransactionID: ft.RecordID,
-						Amount:                req.Amount,
-						Balance:               ft.Balance + req.Amount,
+						AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+						UserConnectionID: st.Connection.ID,
+						UserID:           req.ToUserID,
+						ReferenceType:    datastore.FinancialTransactionPOSTransfer,
+						Amount:           req.Amount,
 					}
 					copy(ft.ReferenceID[:], posSendOrderRes.TraceNumber)
 					copy(ft.ReferenceID[len(posSendOrderRes.TraceNumber):], "/")
 					copy(ft.ReferenceID[len(posSendOrderRes.TraceNumber)+1:], posSendOrderRes.RRN)
-					err = ft.UnLock()
+					err = saveFinancialTransaction(&ft)
 					if err != nil {
 						return
 					}
 				}
 			case '1': // web interface
-				// TODO:::
-				err = ErrNotImplemented
+				if len(req.PosID) < 2 {
+					err = price.ErrInvalidTerminalID
+					return
+				}
+				var gw, ok = webPaymentGateways[req.PosID[1]]
+				if !ok {
+					err = price.ErrInvalidTerminalID
+					return
+				}
+
+				var fwp = datastore.FinancialWebPayment{
+					AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+					UserConnectionID: st.Connection.ID,
+					ID:               uuid.Random32Byte(),
+					UserID:           req.ToUserID,
+					Amount:           req.Amount,
+					GatewayID:        req.PosID[1],
+					Status:           datastore.FinancialWebPaymentPending,
+				}
+				// Save payment before gateway know it, so gateway callback always find the payment.
+				err = fwp.SaveNew()
+				if err != nil {
+					return
+				}
+				res = &registerFinancialTransactionRes{
+					ID: fwp.ID,
+				}
+				res.RedirectURL, err = gw.RequestPayment(&fwp, webPaymentCallbackURL())
+				if err != nil {
+					// Return gateway error to client and just log if payment can't mark as failed.
+					fwp.Status = datastore.FinancialWebPaymentFailed
+					var setErr = fwp.Set()
+					if setErr != nil {
+						log.Warn("Financial web payment", fwp.ID, "can't mark as failed due to:", setErr)
+						return
+					}
+					fwp.IndexRecordIDForID()
+					return
+				}
+				// FinancialTransaction will register in VerifyFinancialWebPayment service after gateway verify the payment.
 				return
 			default:
 				err = price.ErrInvalidTerminalID
 				return
 			}
 		} else {
-			ft = datastore.FinancialTransaction{
-				UserID: req.FromUserID,
-			}
-			err = ft.Lock()
-			if err != nil {
+			if req.FromUserID != st.Connection.UserID {
+				err = ErrFinancialTransactionBadUser
 				return
 			}
-
-			if ft.Balance < req.Amount {
-				err = ErrFinancialTransactionBalance
+			if req.FromUserID == req.ToUserID {
+				err = ErrFinancialTransactionSameUser
 				return
 			}
-
-			ft = datastore.FinancialTransaction{
-				AppInstanceID:         achaemenid.Server.Nodes.LocalNode.InstanceID,
-				UserConnectionID:      st.Connection.ID,
-				UserID:                req.FromUserID,
-				ReferenceType:         datastore.FinancialTransactionDonate,
-				PreviousTransactionID: ft.RecordID,
-				Amount:                -req.Amount,
-				Balance:               ft.Balance - req.Amount,
-			}
-			err = ft.UnLock()
+			err = checkFinancialFreeze(req.FromUserID)
 			if err != nil {
 				return
 			}
-			res = &registerFinancialTransactionRes{
-				ID: ft.RecordID,
+			err = checkFinancialRule(st, req.FromUserID, req.Amount, req.OTP)
+			if err != nil {
+				return
 			}
 
-			ft = datastore.FinancialTransaction{
-				UserID: req.ToUserID,
+			var ftr = datastore.FinancialTransfer{
+				AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+				UserConnectionID: st.Connection.ID,
+				FromUserID:       req.FromUserID,
+				ToUserID:         req.ToUserID,
+				Amount:           req.Amount,
 			}
-			err = ft.Lock()
+			ft, err = registerFinancialTransfer(&ftr)
 			if err != nil {
-				// TODO::: reverse fromUserID balance
 				return
 			}
-
-			ft = datastore.FinancialTransaction{
-				AppInstanceID:         achaemenid.Server.Nodes.LocalNode.InstanceID,
-				UserConnectionID:      st.Connection.ID,
-				UserID:                req.ToUserID,
-				ReferenceType:         datastore.FinancialTransactionDonate,
-				PreviousTransactionID: ft.RecordID,
-				Amount:                req.Amount,
-				Balance:               ft.Balance + req.Amount,
-			}
-			err = ft.UnLock()
-			return
 		}
 	} else if req.FromSocietyID != achaemenid.Server.Manifest.SocietyID {
-		// TODO::: send request to other society
-		err = ErrNotImplemented
+		// Payer society must send the transfer. We receive it in SettleSocietyFinancialTransfer service.
+		err = ErrFinancialTransactionBadSociety
 		return
 	} else if req.ToSocietyID != achaemenid.Server.Manifest.SocietyID {
-		// TODO::: send request to other society
-		err = ErrNotImplemented
+		if req.FromUserID != st.Connection.UserID {
+			err = ErrFinancialTransactionBadUser
+			return
+		}
+		err = checkFinancialFreeze(req.FromUserID)
+		if err != nil {
+			return
+		}
+		err = checkFinancialRule(st, req.FromUserID, req.Amount, req.OTP)
+		if err != nil {
+			return
+		}
+
+		var msg = societyTransferMessage{
+			FromUserID:  req.FromUserID,
+			ToSocietyID: req.ToSocietyID,
+			ToUserID:    req.ToUserID,
+			Amount:      int64(req.Amount),
+		}
+		err = localSociety.sendTransfer(&msg)
+		if err != nil {
+			return
+		}
+		// Transfer may still wait for other society acknowledgement, so return transfer ID not a transaction ID.
+		res = &registerFinancialTransactionRes{
+			ID: msg.TransferID,
+		}
 		return
 	}
 
@@ -247,10 +313,22 @@ func registerFinancialTransaction(st *achaemenid.Stream, req *registerFinancialT
 }
 
 func (req *registerFinancialTransactionReq) validator() (err *er.Error) {
+	if req.Amount <= 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
 	err = validators.ValidateText(req.Description, 0, 150)
 	return
 }
 
+// idempotencyHash return hash of request payload without IdempotencyKey and OTP that change in each retry.
+func (req *registerFinancialTransactionReq) idempotencyHash() (hash [32]byte) {
+	var buf = make([]byte, req.syllabLen())
+	req.syllabEncoder(buf)
+	copy(buf[96:], make([]byte, 40))
+	return sha512.Sum512_256(buf)
+}
+
 /*
 	Request Encoders & Decoders
 */
@@ -268,6 +346,8 @@ func (req *registerFinancialTransactionReq) syllabDecoder(buf []byte) (err *er.E
 	req.Description = syllab.UnsafeGetString(buf, 52)
 	req.ToSocietyID = syllab.GetUInt32(buf, 60)
 	copy(req.ToUserID[:], buf[64:])
+	copy(req.IdempotencyKey[:], buf[96:])
+	req.OTP = syllab.GetUInt64(buf, 128)
 	return
 }
 
@@ -281,11 +361,13 @@ func (req *registerFinancialTransactionReq) syllabEncoder(buf []byte) {
 	hsi = syllab.SetString(buf, req.Description, 52, hsi)
 	syllab.SetUInt32(buf, 60, req.ToSocietyID)
 	copy(buf[64:], req.ToUserID[:])
+	copy(buf[96:], req.IdempotencyKey[:])
+	syllab.SetUInt64(buf, 128, req.OTP)
 	return
 }
 
 func (req *registerFinancialTransactionReq) syllabStackLen() (ln uint32) {
-	return 96
+	return 136
 }
 
 func (req *registerFinancialTransactionReq) syllabHeapLen() (ln uint32) {
@@ -321,6 +403,10 @@ func (req *registerFinancialTransactionReq) jsonDecoder(buf []byte) (err *er.Err
 			req.ToSocietyID, err = decoder.DecodeUInt32()
 		case "ToUserID":
 			err = decoder.DecodeByteArrayAsBase64(req.ToUserID[:])
+		case "IdempotencyKey":
+			err = decoder.DecodeByteArrayAsBase64(req.IdempotencyKey[:])
+		case "OTP":
+			req.OTP, err = decoder.DecodeUInt64()
 		default:
 			err = decoder.NotFoundKeyStrict()
 		}
@@ -358,13 +444,19 @@ func (req *registerFinancialTransactionReq) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`,"ToUserID":"`)
 	encoder.EncodeByteSliceAsBase64(req.ToUserID[:])
 
-	encoder.EncodeString(`"}`)
+	encoder.EncodeString(`","IdempotencyKey":"`)
+	encoder.EncodeByteSliceAsBase64(req.IdempotencyKey[:])
+
+	encoder.EncodeString(`","OTP":`)
+	encoder.EncodeUInt64(req.OTP)
+
+	encoder.EncodeString(`}`)
 	return encoder.Buf
 }
 
 func (req *registerFinancialTransactionReq) jsonLen() (ln int) {
 	ln = len(req.PosID) + len(req.Description)
-	ln += 247
+	ln += 337
 	return
 }
 
@@ -379,19 +471,24 @@ func (res *registerFinancialTransactionRes) syllabDecoder(buf []byte) (err *er.E
 	}
 
 	copy(res.ID[:], buf[0:])
+	res.RedirectURL = syllab.UnsafeGetString(buf, 32)
 	return
 }
 
 func (res *registerFinancialTransactionRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
 	copy(buf[0:], res.ID[:])
+	hsi = syllab.SetString(buf, res.RedirectURL, 32, hsi)
 	return
 }
 
 func (res *registerFinancialTransactionRes) syllabStackLen() (ln uint32) {
-	return 32
+	return 40
 }
 
 func (res *registerFinancialTransactionRes) syllabHeapLen() (ln uint32) {
+	ln = uint32(len(res.RedirectURL))
 	return
 }
 
@@ -408,6 +505,8 @@ func (res *registerFinancialTransactionRes) jsonDecoder(buf []byte) (err *er.Err
 		switch keyName {
 		case "ID":
 			err = decoder.DecodeByteArrayAsBase64(res.ID[:])
+		case "RedirectURL":
+			res.RedirectURL, err = decoder.DecodeString()
 		default:
 			err = decoder.NotFoundKeyStrict()
 		}
@@ -427,11 +526,15 @@ func (res *registerFinancialTransactionRes) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`{"ID":"`)
 	encoder.EncodeByteSliceAsBase64(res.ID[:])
 
+	encoder.EncodeString(`","RedirectURL":"`)
+	encoder.EncodeString(res.RedirectURL)
+
 	encoder.EncodeString(`"}`)
 	return encoder.Buf
 }
 
 func (res *registerFinancialTransactionRes) jsonLen() (ln int) {
-	ln = 52
+	ln = len(res.RedirectURL)
+	ln += 69
 	return
 }
@@ -3,15 +3,20 @@
 package services
 
 import (
+	"crypto/sha512"
+
 	"../datastore"
 	"../libgo/achaemenid"
 	"../libgo/authorization"
 	er "../libgo/error"
+	"../libgo/ganjine"
 	"../libgo/http"
 	"../libgo/json"
 	lang "../libgo/language"
+	"../libgo/log"
 	"../libgo/price"
 	"../libgo/srpc"
+	"../libgo/syllab"
 	"../libgo/uuid"
 )
 
@@ -85,14 +90,18 @@ func RegisterProductInvoiceHTTP(st *achaemenid.Stream, httpReq *http.Request, ht
 }
 
 type registerProductInvoiceReq struct {
-	UserID            [32]byte `json:",string"` // Due to a seller can register invoice for any user
-	UserOTP           uint64
-	UserTransactionID [32]byte `json:",string"`
-	Products          []registerProductInvoiceDetail
+	UserID         [32]byte `json:",string"` // Due to a seller can register invoice for any user
+	UserOTP        uint64
+	Products       []registerProductInvoiceDetail
+	IdempotencyKey [32]byte      `json:",string,optional"` // Client retry with same key get first response and not pay twice
+	BestEffort     bool          `json:",optional"`        // Register valid lines even if some lines failed. Default is all or nothing!
+	Language       lang.Language `json:",optional"`        // Language of receipt that send to buyer
 }
 
 type registerProductInvoiceRes struct {
-	NotRegistred []registerProductInvoiceDetail
+	InvoiceID      [32]byte                       `json:",string"` // Empty if no line registered e.g. a line failed in strict mode
+	TransactionIDs [][32]byte                     `json:",string"` // Buyer debit leg of each registered product in order of request products
+	NotRegistred   []registerProductInvoiceDetail // Failed lines with the reason in their Status
 }
 
 type registerProductInvoiceDetail struct {
@@ -101,119 +110,571 @@ type registerProductInvoiceDetail struct {
 	ProductAuctionID     [32]byte            `json:",string"`
 	getProductAuctionRes *getProductAuctionRes
 	DistributionCenterID [32]byte `json:",string"`
-	Number               uint64
-	Status               uint8
+	Number               uint64   // Each line sell one product, so it must be 1. Send more lines to sell more products.
+	Status               productInvoiceLineStatus
 }
 
+// productInvoiceLineStatus indicate register result of an invoice line.
+type productInvoiceLineStatus uint8
+
+// Product invoice line statuses
+const (
+	productInvoiceLineRegistered productInvoiceLineStatus = iota
+	productInvoiceLinePriceNotFound
+	productInvoiceLineAuctionNotFound
+	productInvoiceLineAuctionMismatch // Auction is not for the line quiddity
+	productInvoiceLineAuctionClosed   // Auction expired or blocked
+	productInvoiceLineBadCommission
+	productInvoiceLineBalance // Buyer balance not enough for the line
+	productInvoiceLineWriteFailed
+	productInvoiceLineNotAllowUser     // Auction is just for other user
+	productInvoiceLineNotAllowGroup    // Buyer is not member of the auction group
+	productInvoiceLineNotAllowUserType // Auction is not for buyer user type
+	productInvoiceLineNotAllowWeekday  // Auction is not active in this day of week
+	productInvoiceLineNotAllowDayhour  // Auction is not active in this hour
+	productInvoiceLineCheckFailed      // Line can't check due to platform error, retry later
+	productInvoiceLineNotAllowSeller   // Seller is not the auction org or its active delegate
+	productInvoiceLineNoStock          // DC has no product of the auction org for the line
+	productInvoiceLineBadNumber        // Line number is not 1
+)
+
+// productInvoiceStockPageLimit is number of DC stock products that read in each page to find a product for a line.
+const productInvoiceStockPageLimit = 64
+
 func registerProductInvoice(st *achaemenid.Stream, req *registerProductInvoiceReq) (res *registerProductInvoiceRes, err *er.Error) {
 	err = st.Authorize()
 	if err != nil {
 		return
 	}
 
-	if req.UserID != [32]byte{} && (req.UserOTP == 0 || req.UserTransactionID == [32]byte{}) {
-		err = ErrProductInvoiceDelegate
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
 		return
 	}
 
+	if req.IdempotencyKey != [32]byte{} {
+		var idempotency datastore.FinancialIdempotency
+		var replayed bool
+		idempotency, replayed, err = claimFinancialIdempotency(st, req.IdempotencyKey, req.idempotencyHash())
+		if err != nil {
+			return
+		}
+		if replayed {
+			res = &registerProductInvoiceRes{
+				InvoiceID: idempotency.ResponseID,
+			}
+			if len(idempotency.Response) != 0 {
+				err = res.syllabDecoder(idempotency.Response)
+			}
+			return
+		}
+		defer func() {
+			var responseID [32]byte
+			var response []byte
+			if res != nil {
+				responseID = res.InvoiceID
+				response = make([]byte, res.syllabLen())
+				res.syllabEncoder(response)
+			}
+			if err == nil && responseID == [32]byte{} {
+				// No line registered and nothing charged, so client can fix lines and retry with same key.
+				updateFinancialIdempotency(&idempotency, datastore.FinancialIdempotencyFailed, [32]byte{}, nil)
+				return
+			}
+			finishFinancialIdempotency(&idempotency, responseID, response, err)
+		}()
+	}
+
 	var (
 		sellerID [32]byte
 
-		totalPriceAmount      price.Amount
-		totalDCCommission     price.Amount
-		totalSellerCommission price.Amount
-
-		ft      datastore.FinancialTransaction
-		product datastore.Product
+		totalPriceAmount price.Amount
 
 		// notRegisteredPriceAmount price.Amount
 	)
 
-	for _, pro := range req.Products {
-		var getProductPriceReq = getProductPriceReq{
-			QuiddityID: pro.QuiddityID,
+	var buyerID, buyerType = req.UserID, st.Connection.UserType
+	if buyerID == [32]byte{} {
+		buyerID = st.Connection.UserID
+	} else {
+		// Seller commission pay to the connection user, so it must not be the buyer itself.
+		sellerID = st.Connection.UserID
+		if sellerID == buyerID {
+			err = ErrProductInvoiceSelfSeller
+			return
 		}
-		pro.getProductPriceRes, err = getProductPrice(st, &getProductPriceReq)
-
-		var getProductAuctionReq = getProductAuctionReq{
-			ID: pro.ProductAuctionID,
+		buyerType, err = getProductInvoiceBuyerType(buyerID)
+		if err != nil {
+			return
 		}
-		pro.getProductAuctionRes, err = getProductAuction(st, &getProductAuctionReq)
-		// TODO::: authorize product auction e.g. allowUserID, ...
+	}
+
+	res = &registerProductInvoiceRes{}
 
-		totalPriceAmount += pro.getProductPriceRes.Price - pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.Discount)
-		totalDCCommission += pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.DCCommission)
-		totalSellerCommission += pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.SellerCommission)
+	// Check all lines before charge anything, so strict invoice with a bad line never write any leg.
+	for i := range req.Products {
+		var pro = &req.Products[i]
+		pro.Status = pro.check(st, buyerID, buyerType, sellerID)
+		if pro.Status != productInvoiceLineRegistered {
+			res.NotRegistred = append(res.NotRegistred, *pro)
+			continue
+		}
+		totalPriceAmount += pro.payablePrice()
+	}
+	if len(res.NotRegistred) == len(req.Products) || (!req.BestEffort && len(res.NotRegistred) != 0) {
+		return
 	}
 
 	if req.UserID == [32]byte{} {
 		req.UserID = st.Connection.UserID
 	} else {
-		sellerID = st.Connection.UserID
+		// Seller charge buyer balance directly, so buyer must approve each invoice by its OTP.
+		err = checkPersonOTP(req.UserID, req.UserOTP)
+		if err != nil {
+			res = nil
+			return
+		}
+	}
 
-		if req.UserTransactionID != [32]byte{} {
-			ft = datastore.FinancialTransaction{
-				RecordID: req.UserTransactionID,
+	err = checkFinancialFreeze(req.UserID)
+	if err != nil {
+		res = nil
+		return
+	}
+	err = checkFinancialRule(st, req.UserID, totalPriceAmount, req.UserOTP)
+	if err != nil {
+		res = nil
+		return
+	}
+
+	res.InvoiceID = uuid.Random32Byte()
+	res.TransactionIDs = make([][32]byte, 0, len(req.Products))
+
+	var invoice = datastore.Invoice{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		ID:       res.InvoiceID,
+		BuyerID:  req.UserID,
+		SellerID: sellerID,
+		Status:   datastore.InvoiceRegistered,
+		Lines:    make([]datastore.InvoiceLine, 0, len(req.Products)),
+	}
+	if len(writeProductInvoiceLines(productInvoiceLines, req, res, &invoice)) == 0 {
+		res.InvoiceID = [32]byte{}
+		return
+	}
+
+	// Money and products wrote successfully, so failed invoice record must not fail the request and charge again in retry!
+	var invoiceErr = invoice.SaveNew()
+	if invoiceErr != nil {
+		log.Warn("Product invoice", invoice.ID, "can't write due to:", invoiceErr)
+	} else {
+		go sendInvoiceReceipt(invoice, req.Language)
+	}
+	return
+}
+
+// writeProductInvoiceLines write checked lines of the request by the ledger, add registered ones to the invoice and
+// return their sales.
+// Each product has its own debit and credit legs with ProductID as ReferenceID, so a product can refund alone later.
+// All legs and products write as a saga. In strict mode if any line failed, all written lines reverse and
+// in best-effort mode just written legs of the failed line reverse.
+func writeProductInvoiceLines(ledger productInvoiceLedger, req *registerProductInvoiceReq, res *registerProductInvoiceRes, invoice *datastore.Invoice) (sales []productInvoiceSale) {
+	var legs = make([]datastore.FinancialTransaction, 0, len(req.Products))
+	sales = make([]productInvoiceSale, 0, len(req.Products))
+	for i := range req.Products {
+		var pro = &req.Products[i]
+		if pro.Status != productInvoiceLineRegistered {
+			continue
+		}
+
+		var sale productInvoiceSale
+		var lineLegs []datastore.FinancialTransaction
+		sale, lineLegs, pro.Status = ledger.Register(pro, invoice.BuyerID, invoice.SellerID)
+		if pro.Status == productInvoiceLineRegistered {
+			legs = append(legs, lineLegs...)
+			sales = append(sales, sale)
+			res.TransactionIDs = append(res.TransactionIDs, lineLegs[0].RecordID)
+			invoice.AddLine(pro.invoiceLine(sale.product.ID, lineLegs[0].RecordID, invoice.SellerID))
+			continue
+		}
+
+		res.NotRegistred = append(res.NotRegistred, *pro)
+		ledger.Rollback(lineLegs, nil)
+		if !req.BestEffort {
+			ledger.Rollback(legs, sales)
+			res.InvoiceID = [32]byte{}
+			res.TransactionIDs = nil
+			return nil
+		}
+	}
+	return
+}
+
+// getProductInvoiceBuyerType return type of the buyer that seller register the invoice for it.
+func getProductInvoiceBuyerType(buyerID [32]byte) (buyerType authorization.UserType, err *er.Error) {
+	var pa = datastore.PersonAuthentication{
+		PersonID: buyerID,
+	}
+	err = pa.GetLastByPersonID()
+	if err.Equal(ganjine.ErrRecordNotFound) {
+		return authorization.UserTypeOrg, nil
+	}
+	if err != nil {
+		return
+	}
+	return authorization.UserTypePerson, nil
+}
+
+// check get price and auction of the line and return the reason that line can't register for the buyer by the seller.
+// Commissions pay just to the auction org delegates as seller and to the DC that really has the product in its stock.
+func (pro *registerProductInvoiceDetail) check(st *achaemenid.Stream, buyerID [32]byte, buyerType authorization.UserType, sellerID [32]byte) (status productInvoiceLineStatus) {
+	// Line register one product and charge its payable price once.
+	if pro.Number != 1 {
+		return productInvoiceLineBadNumber
+	}
+
+	var err *er.Error
+	var getProductPriceReq = getProductPriceReq{
+		QuiddityID: pro.QuiddityID,
+	}
+	pro.getProductPriceRes, err = getProductPrice(st, &getProductPriceReq)
+	if err != nil {
+		return productInvoiceLinePriceNotFound
+	}
+
+	var getProductAuctionReq = getProductAuctionReq{
+		ID: pro.ProductAuctionID,
+	}
+	pro.getProductAuctionRes, err = getProductAuction(st, &getProductAuctionReq)
+	if err != nil {
+		return productInvoiceLineAuctionNotFound
+	}
+	if pro.getProductAuctionRes.QuiddityID != pro.QuiddityID {
+		return productInvoiceLineAuctionMismatch
+	}
+	switch pro.getProductAuctionRes.Status {
+	case datastore.ProductAuctionExpired, datastore.ProductAuctionBlocked:
+		return productInvoiceLineAuctionClosed
+	}
+	err = checkProductAuctionAuthorization(pro.getProductAuctionRes, buyerID, buyerType)
+	switch {
+	case err == nil:
+	case err.Equal(ErrProductAuctionExpired):
+		return productInvoiceLineAuctionClosed
+	case err.Equal(ErrProductAuctionNotAllowUser):
+		return productInvoiceLineNotAllowUser
+	case err.Equal(ErrProductAuctionNotAllowGroup):
+		return productInvoiceLineNotAllowGroup
+	case err.Equal(ErrProductAuctionNotAllowUserType):
+		return productInvoiceLineNotAllowUserType
+	case err.Equal(ErrProductAuctionNotAllowWeekday):
+		return productInvoiceLineNotAllowWeekday
+	case err.Equal(ErrProductAuctionNotAllowDayhour):
+		return productInvoiceLineNotAllowDayhour
+	default:
+		return productInvoiceLineCheckFailed
+	}
+
+	if pro.orgShare() < 0 {
+		return productInvoiceLineBadCommission
+	}
+
+	if sellerID != [32]byte{} && sellerID != pro.getProductAuctionRes.OrgID {
+		var delegate bool
+		delegate, err = isActiveDelegate(pro.getProductAuctionRes.OrgID, sellerID)
+		if err != nil {
+			return productInvoiceLineCheckFailed
+		}
+		if !delegate {
+			return productInvoiceLineNotAllowSeller
+		}
+	}
+
+	if pro.DistributionCenterID != [32]byte{} {
+		var found bool
+		_, found, err = pro.findStockProduct()
+		if err != nil {
+			return productInvoiceLineCheckFailed
+		}
+		if !found {
+			return productInvoiceLineNoStock
+		}
+	}
+	return productInvoiceLineRegistered
+}
+
+// findStockProduct find an on hand product of the line quiddity in the line DC that belong to the auction org.
+func (pro *registerProductInvoiceDetail) findStockProduct() (product datastore.Product, found bool, err *er.Error) {
+	var stock = datastore.Product{
+		QuiddityID: pro.QuiddityID,
+		DCID:       pro.DistributionCenterID,
+	}
+	var IDs [][32]byte
+	for offset := uint64(0); ; offset += productInvoiceStockPageLimit {
+		IDs, err = stock.FindIDsByQuiddityIDDCID(offset, productInvoiceStockPageLimit)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			return product, false, nil
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			product = datastore.Product{
+				ID: id,
 			}
-			err = ft.GetByRecordID()
+			err = product.GetLastByID()
 			if err != nil {
 				return
 			}
-			if ft.UserID != req.UserID || ft.Amount < totalPriceAmount {
-				err = ErrProductInvoiceDelegate
-				return
+			if product.OwnerID == pro.getProductAuctionRes.OrgID && product.DCID == pro.DistributionCenterID &&
+				getProductStockBucket(&product) == productStockOnHand {
+				return product, true, nil
 			}
-		} else {
-			// TODO::: check given OTP
 		}
+		if len(IDs) < productInvoiceStockPageLimit {
+			return datastore.Product{}, false, nil
+		}
+	}
+}
+
+// productInvoiceLedger write and reverse lines of product invoices.
+type productInvoiceLedger interface {
+	// Register write legs and sold product of the line. Written legs return even if the line failed.
+	Register(pro *registerProductInvoiceDetail, buyerID, sellerID [32]byte) (sale productInvoiceSale, legs []datastore.FinancialTransaction, status productInvoiceLineStatus)
+	// Rollback reverse written legs and rollback sold products.
+	Rollback(legs []datastore.FinancialTransaction, sales []productInvoiceSale)
+}
+
+var productInvoiceLines productInvoiceLedger = datastoreProductInvoiceLedger{}
+
+// datastoreProductInvoiceLedger keep legs in FinancialTransaction chains and sold products in Product records.
+type datastoreProductInvoiceLedger struct{}
+
+func (datastoreProductInvoiceLedger) Register(pro *registerProductInvoiceDetail, buyerID, sellerID [32]byte) (sale productInvoiceSale, legs []datastore.FinancialTransaction, status productInvoiceLineStatus) {
+	return pro.register(buyerID, sellerID)
+}
+
+func (datastoreProductInvoiceLedger) Rollback(legs []datastore.FinancialTransaction, sales []productInvoiceSale) {
+	rollbackProductInvoice(legs, sales)
+}
+
+// productInvoiceSale is the sold product of a registered line.
+type productInvoiceSale struct {
+	product   datastore.Product
+	before    datastore.Product // Last version of the product in the DC stock if fromStock
+	fromStock bool              // Product take from the line DC stock, otherwise it is a new product without any DC
+}
+
+// register write buyer debit leg, credit legs and the sold product of the line. Written legs return even if
+// the line failed, so caller can reverse them.
+// Line with a DC sell a real product of the auction org from the DC stock, so the DC stock move to the buyer.
+func (pro *registerProductInvoiceDetail) register(buyerID, sellerID [32]byte) (sale productInvoiceSale, legs []datastore.FinancialTransaction, status productInvoiceLineStatus) {
+	var err *er.Error
+	if pro.DistributionCenterID == [32]byte{} {
+		sale.product = datastore.Product{
+			ID:         uuid.Random32Byte(),
+			QuiddityID: pro.QuiddityID,
+		}
+	} else {
+		sale.before, sale.fromStock, err = pro.findStockProduct()
+		if err != nil {
+			return sale, legs, productInvoiceLineCheckFailed
+		}
+		if !sale.fromStock {
+			return sale, legs, productInvoiceLineNoStock
+		}
+		sale.product = sale.before
+	}
+	sale.product.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	// sale.product.UserConnectionID = st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+	sale.product.OwnerID = buyerID
+	sale.product.SellerID = sellerID
+	sale.product.ProductAuctionID = pro.ProductAuctionID
+	sale.product.Status = datastore.ProductChangeOwner
+
+	var ft = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		UserID:        buyerID,
+		ReferenceID:   sale.product.ID,
+		ReferenceType: datastore.FinancialTransactionProductAuctionPrice,
+		Amount:        -pro.payablePrice(),
 	}
+	err = saveFinancialTransaction(&ft)
+	if err.Equal(ErrFinancialTransactionBalance) {
+		return sale, legs, productInvoiceLineBalance
+	}
+	if err != nil {
+		return sale, legs, productInvoiceLineWriteFailed
+	}
+	legs = append(legs, ft)
 
-	ft = datastore.FinancialTransaction{
-		UserID: req.UserID,
+	for _, credit := range pro.credits(sellerID) {
+		ft = datastore.FinancialTransaction{
+			AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+			UserID:        credit.userID,
+			ReferenceID:   sale.product.ID,
+			ReferenceType: credit.referenceType,
+			Amount:        credit.amount,
+		}
+		err = saveFinancialTransaction(&ft)
+		if err != nil {
+			return sale, legs, productInvoiceLineWriteFailed
+		}
+		legs = append(legs, ft)
 	}
-	err = ft.Lock()
+
+	err = sale.save()
 	if err != nil {
-		return
+		return sale, legs, productInvoiceLineWriteFailed
+	}
+	return sale, legs, productInvoiceLineRegistered
+}
+
+// save write sold product. Product from DC stock leave the DC with its buyer.
+func (sale *productInvoiceSale) save() (err *er.Error) {
+	if !sale.fromStock {
+		return sale.product.SaveNew()
 	}
-	if ft.Balance < totalPriceAmount {
-		err = ErrFinancialTransactionBalance
+
+	err = sale.product.Set()
+	if err != nil {
 		return
 	}
-	ft = datastore.FinancialTransaction{
-		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
-		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
-		UserID:                req.UserID,
-		ReferenceType:         datastore.FinancialTransactionProductAuctionPrice,
-		PreviousTransactionID: ft.RecordID,
-		Amount:                -totalPriceAmount,
-		Balance:               ft.Balance - totalPriceAmount,
+	sale.product.IndexRecordIDForID()
+	sale.product.IndexIDForOwnerIDDaily()
+	if sale.product.SellerID != [32]byte{} {
+		sale.product.IndexIDForSellerIDDaily()
 	}
-	err = ft.UnLock()
+	sale.product.IndexIDForProductAuctionID()
+	sale.product.DeleteTempIndexIDForQuiddityIDDCID()
+	moveProductStock(&sale.before, &sale.product)
+	return
+}
+
+// rollback void created product or return product to its DC stock as it was before the sale.
+func (sale *productInvoiceSale) rollback() (err *er.Error) {
+	var product = sale.product
+	if sale.fromStock {
+		product = sale.before
+	} else {
+		product.Status = datastore.ProductVoid
+	}
+	product.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	err = product.Set()
 	if err != nil {
 		return
 	}
+	product.IndexRecordIDForID()
+	if sale.fromStock {
+		product.TempIndexIDForQuiddityIDDCID()
+		moveProductStock(&sale.product, &product)
+	}
+	return
+}
 
-	for _, pro := range req.Products {
-		product = datastore.Product{
-			AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
-			// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
-			ID:         uuid.Random32Byte(),
-			OwnerID:    req.UserID,
-			SellerID:   sellerID,
-			QuiddityID: pro.QuiddityID,
-			// ProductionID       :
-			DCID:             pro.DistributionCenterID,
-			ProductAuctionID: pro.ProductAuctionID,
-			Status:           datastore.ProductChangeOwner,
+// rollbackProductInvoice reverse written legs in reverse order and rollback sold products of a failed invoice.
+func rollbackProductInvoice(legs []datastore.FinancialTransaction, sales []productInvoiceSale) {
+	for i := len(legs) - 1; i >= 0; i-- {
+		var err = reverseFinancialTransaction(&legs[i])
+		if err != nil {
+			log.Warn("Product invoice leg", legs[i].RecordID, "can't reverse due to:", err)
+		}
+	}
+	for i := range sales {
+		var err = sales[i].rollback()
+		if err != nil {
+			log.Warn("Product", sales[i].product.ID, "of failed invoice can't rollback due to:", err)
 		}
-		product.SaveNew()
 	}
+}
 
-	res = &registerProductInvoiceRes{}
+// payablePrice return product price after auction discount.
+func (pro *registerProductInvoiceDetail) payablePrice() (amount price.Amount) {
+	return pro.getProductPriceRes.Price - pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.Discount)
+}
+
+func (pro *registerProductInvoiceDetail) dcCommission() (amount price.Amount) {
+	if pro.DistributionCenterID == [32]byte{} {
+		return
+	}
+	return pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.DCCommission)
+}
 
+func (pro *registerProductInvoiceDetail) sellerCommission(sellerID [32]byte) (amount price.Amount) {
+	if sellerID == [32]byte{} {
+		return
+	}
+	return pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.SellerCommission)
+}
+
+// orgShare return remaining of payable price for producer organization when commissions paid.
+// It use max commissions due to seller is not known before authorize the request!
+func (pro *registerProductInvoiceDetail) orgShare() (amount price.Amount) {
+	return pro.payablePrice() - pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.DCCommission) -
+		pro.getProductPriceRes.Price.PerMyriad(pro.getProductAuctionRes.SellerCommission)
+}
+
+// invoiceLine return invoice line of the registered product with given buyer debit leg.
+func (pro *registerProductInvoiceDetail) invoiceLine(productID, transactionID, sellerID [32]byte) (line datastore.InvoiceLine) {
+	return datastore.InvoiceLine{
+		ProductID:        productID,
+		QuiddityID:       pro.QuiddityID,
+		ProductAuctionID: pro.ProductAuctionID,
+		DCID:             pro.DistributionCenterID,
+		TransactionID:    transactionID,
+		Price:            pro.getProductPriceRes.Price,
+		Discount:         pro.getProductPriceRes.Price - pro.payablePrice(),
+		DCCommission:     pro.dcCommission(),
+		SellerCommission: pro.sellerCommission(sellerID),
+		Tax:              pro.getProductPriceRes.Tax,
+		Payable:          pro.payablePrice(),
+	}
+}
+
+type productInvoiceCredit struct {
+	userID        [32]byte
+	referenceType datastore.FinancialTransactionType
+	amount        price.Amount
+}
+
+// credits split payable price of the product between DC, seller and producer organization.
+// Commission of not exist DC or seller remain for producer organization.
+func (pro *registerProductInvoiceDetail) credits(sellerID [32]byte) (credits []productInvoiceCredit) {
+	var dcCommission = pro.dcCommission()
+	var sellerCommission = pro.sellerCommission(sellerID)
+	if dcCommission > 0 {
+		credits = append(credits, productInvoiceCredit{pro.DistributionCenterID, datastore.FinancialTransactionProductAuctionCommission, dcCommission})
+	}
+	if sellerCommission > 0 {
+		credits = append(credits, productInvoiceCredit{sellerID, datastore.FinancialTransactionProductAuctionCommission, sellerCommission})
+	}
+	var orgShare = pro.payablePrice() - dcCommission - sellerCommission
+	if orgShare > 0 {
+		credits = append(credits, productInvoiceCredit{pro.getProductAuctionRes.OrgID, datastore.FinancialTransactionProductAuctionPrice, orgShare})
+	}
 	return
 }
 
+// validator check seller that register invoice for a user has the user OTP to charge the user balance.
+func (req *registerProductInvoiceReq) validator() (err *er.Error) {
+	if req.UserID != [32]byte{} && req.UserOTP == 0 {
+		err = ErrProductInvoiceDelegate
+	}
+	return
+}
+
+// idempotencyHash return hash of request payload without IdempotencyKey and UserOTP that change in each retry.
+func (req *registerProductInvoiceReq) idempotencyHash() (hash [32]byte) {
+	var payload = *req
+	payload.IdempotencyKey = [32]byte{}
+	payload.UserOTP = 0
+	return sha512.Sum512_256(payload.jsonEncoder())
+}
+
 // CalculatePrices method set prices by given price data
 // func (pa *ProductAuction) CalculatePrices() {
 // 	pa.PayablePrice =
@@ -226,20 +687,43 @@ func registerProductInvoice(st *achaemenid.Stream, req *registerProductInvoiceRe
 */
 
 func (req *registerProductInvoiceReq) syllabDecoder(buf []byte) (err *er.Error) {
-	// TODO::: Use syllab generator to generate needed codes
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.UserID[:], buf[0:])
+	req.UserOTP = syllab.GetUInt64(buf, 32)
+	req.Products, err = decodeRegisterProductInvoiceDetails(buf, 40)
+	if err != nil {
+		return
+	}
+	copy(req.IdempotencyKey[:], buf[48:])
+	req.BestEffort = buf[80] == 1
+	req.Language = lang.Language(syllab.GetUInt32(buf, 81))
 	return
 }
 
 func (req *registerProductInvoiceReq) syllabEncoder(buf []byte) {
-	// TODO::: Use syllab generator to generate needed codes
+	var hsi uint32 = req.syllabStackLen() // Heap start index || Stack size!
+
+	copy(buf[0:], req.UserID[:])
+	syllab.SetUInt64(buf, 32, req.UserOTP)
+	encodeRegisterProductInvoiceDetails(buf, req.Products, 40, hsi)
+	copy(buf[48:], req.IdempotencyKey[:])
+	if req.BestEffort {
+		buf[80] = 1
+	}
+	syllab.SetUInt32(buf, 81, uint32(req.Language))
+	return
 }
 
 func (req *registerProductInvoiceReq) syllabStackLen() (ln uint32) {
-	return 0 // fixed size data + variables data add&&len
+	return 85 // fixed size data + variables data add&&len
 }
 
 func (req *registerProductInvoiceReq) syllabHeapLen() (ln uint32) {
-	// TODO::: Use syllab generator to generate needed codes
+	ln = uint32(len(req.Products)) * registerProductInvoiceDetailSyllabLen
 	return
 }
 
@@ -268,20 +752,48 @@ func (req *registerProductInvoiceReq) jsonLen() (ln int) {
 */
 
 func (res *registerProductInvoiceRes) syllabDecoder(buf []byte) (err *er.Error) {
-	// TODO::: Use syllab generator to generate needed codes
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.InvoiceID[:], buf[0:])
+	var add uint32 = syllab.GetUInt32(buf, 32)
+	var ln uint32 = syllab.GetUInt32(buf, 32+4)
+	if uint64(add)+uint64(ln)*32 > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+	res.TransactionIDs = make([][32]byte, ln)
+	for i := range res.TransactionIDs {
+		copy(res.TransactionIDs[i][:], buf[add+uint32(i)*32:])
+	}
+	res.NotRegistred, err = decodeRegisterProductInvoiceDetails(buf, 40)
 	return
 }
 
 func (res *registerProductInvoiceRes) syllabEncoder(buf []byte) {
-	// TODO::: Use syllab generator to generate needed codes
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	copy(buf[0:], res.InvoiceID[:])
+	var ln = uint32(len(res.TransactionIDs))
+	syllab.SetUInt32(buf, 32, hsi)
+	syllab.SetUInt32(buf, 32+4, ln)
+	for i := range res.TransactionIDs {
+		copy(buf[hsi:], res.TransactionIDs[i][:])
+		hsi += 32
+	}
+	encodeRegisterProductInvoiceDetails(buf, res.NotRegistred, 40, hsi)
+	return
 }
 
 func (res *registerProductInvoiceRes) syllabStackLen() (ln uint32) {
-	return 0 // fixed size data + variables data add&&len
+	return 48 // fixed size data + variables data add&&len
 }
 
 func (res *registerProductInvoiceRes) syllabHeapLen() (ln uint32) {
-	// TODO::: Use syllab generator to generate needed codes
+	ln = uint32(len(res.TransactionIDs)) * 32
+	ln += uint32(len(res.NotRegistred)) * registerProductInvoiceDetailSyllabLen
 	return
 }
 
@@ -304,3 +816,47 @@ func (res *registerProductInvoiceRes) jsonEncoder() (buf []byte) {
 func (res *registerProductInvoiceRes) jsonLen() (ln int) {
 	return
 }
+
+/*
+	Detail Encoders & Decoders
+*/
+
+// registerProductInvoiceDetailSyllabLen is fixed size of each detail in heap. Internal fields not encode!
+const registerProductInvoiceDetailSyllabLen uint32 = 105
+
+// decodeRegisterProductInvoiceDetails decode details slice that its add&&len store in given stack index.
+func decodeRegisterProductInvoiceDetails(buf []byte, stackIndex uint32) (details []registerProductInvoiceDetail, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(registerProductInvoiceDetailSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	details = make([]registerProductInvoiceDetail, ln)
+	for i := range details {
+		var detail = &details[i]
+		var dbuf = buf[add+uint32(i)*registerProductInvoiceDetailSyllabLen:]
+		copy(detail.QuiddityID[:], dbuf[0:])
+		copy(detail.ProductAuctionID[:], dbuf[32:])
+		copy(detail.DistributionCenterID[:], dbuf[64:])
+		detail.Number = syllab.GetUInt64(dbuf, 96)
+		detail.Status = productInvoiceLineStatus(syllab.GetUInt8(dbuf, 104))
+	}
+	return
+}
+
+// encodeRegisterProductInvoiceDetails encode details in heap from given heap index and its add&&len in given stack index.
+func encodeRegisterProductInvoiceDetails(buf []byte, details []registerProductInvoiceDetail, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(details)))
+	for i := range details {
+		var detail = &details[i]
+		var dbuf = buf[hsi+uint32(i)*registerProductInvoiceDetailSyllabLen:]
+		copy(dbuf[0:], detail.QuiddityID[:])
+		copy(dbuf[32:], detail.ProductAuctionID[:])
+		copy(dbuf[64:], detail.DistributionCenterID[:])
+		syllab.SetUInt64(dbuf, 96, detail.Number)
+		syllab.SetUInt8(dbuf, 104, uint8(detail.Status))
+	}
+}
@@ -0,0 +1,147 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"../datastore"
+	er "../libgo/error"
+)
+
+func TestRegisterProductInvoiceReq_Validator(t *testing.T) {
+	var tests = []struct {
+		name string
+		req  registerProductInvoiceReq
+		err  *er.Error
+	}{
+		{"buyer", registerProductInvoiceReq{}, nil},
+		{"seller with buyer OTP", registerProductInvoiceReq{UserID: [32]byte{1}, UserOTP: 123456}, nil},
+		{"seller without buyer OTP", registerProductInvoiceReq{UserID: [32]byte{1}}, ErrProductInvoiceDelegate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err = tt.req.validator()
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Errorf("validator() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// memoryProductInvoiceLedger is in-memory productInvoiceLedger that fail lines of given quiddities after write one leg.
+type memoryProductInvoiceLedger struct {
+	fail     map[[32]byte]bool
+	written  int
+	reversed [][32]byte
+	voided   int
+}
+
+func (l *memoryProductInvoiceLedger) Register(pro *registerProductInvoiceDetail, buyerID, sellerID [32]byte) (sale productInvoiceSale, legs []datastore.FinancialTransaction, status productInvoiceLineStatus) {
+	l.written++
+	sale.product = datastore.Product{ID: [32]byte{byte(l.written)}, QuiddityID: pro.QuiddityID}
+	legs = append(legs, datastore.FinancialTransaction{RecordID: [32]byte{byte(l.written), 1}, UserID: buyerID})
+	if l.fail[pro.QuiddityID] {
+		return sale, legs, productInvoiceLineWriteFailed
+	}
+	legs = append(legs, datastore.FinancialTransaction{RecordID: [32]byte{byte(l.written), 2}, UserID: sellerID})
+	return sale, legs, productInvoiceLineRegistered
+}
+
+func (l *memoryProductInvoiceLedger) Rollback(legs []datastore.FinancialTransaction, sales []productInvoiceSale) {
+	for i := len(legs) - 1; i >= 0; i-- {
+		l.reversed = append(l.reversed, legs[i].RecordID)
+	}
+	l.voided += len(sales)
+}
+
+func TestWriteProductInvoiceLines(t *testing.T) {
+	var good, bad = [32]byte{1}, [32]byte{2}
+	var tests = []struct {
+		name         string
+		bestEffort   bool
+		lines        [][32]byte
+		checkFailed  int // Index of line that failed in check, -1 for none
+		wantSales    int
+		wantIDs      [][32]byte
+		wantReversed [][32]byte
+		wantVoided   int
+		wantNot      []productInvoiceLineStatus
+	}{
+		{"strict all registered", false, [][32]byte{good, good}, -1, 2, [][32]byte{{1, 1}, {2, 1}}, nil, 0, nil},
+		{"strict reverse all written lines", false, [][32]byte{good, bad, good}, -1, 0, nil,
+			[][32]byte{{2, 1}, {1, 2}, {1, 1}}, 1, []productInvoiceLineStatus{productInvoiceLineWriteFailed}},
+		{"best effort reverse failed line", true, [][32]byte{good, bad, good}, -1, 2, [][32]byte{{1, 1}, {3, 1}},
+			[][32]byte{{2, 1}}, 0, []productInvoiceLineStatus{productInvoiceLineWriteFailed}},
+		{"best effort all failed", true, [][32]byte{bad}, -1, 0, [][32]byte{},
+			[][32]byte{{1, 1}}, 0, []productInvoiceLineStatus{productInvoiceLineWriteFailed}},
+		{"skip not checked line", true, [][32]byte{good, good}, 0, 1, [][32]byte{{1, 1}}, nil, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ledger = &memoryProductInvoiceLedger{fail: map[[32]byte]bool{bad: true}}
+			var req = registerProductInvoiceReq{BestEffort: tt.bestEffort}
+			for i, quiddityID := range tt.lines {
+				var pro = registerProductInvoiceDetail{
+					QuiddityID:           quiddityID,
+					getProductPriceRes:   &getProductPriceRes{},
+					getProductAuctionRes: &getProductAuctionRes{},
+				}
+				if i == tt.checkFailed {
+					pro.Status = productInvoiceLineNoStock
+				}
+				req.Products = append(req.Products, pro)
+			}
+			var res = registerProductInvoiceRes{InvoiceID: [32]byte{9}, TransactionIDs: [][32]byte{}}
+			var invoice = datastore.Invoice{ID: res.InvoiceID, BuyerID: [32]byte{7}, SellerID: [32]byte{8}}
+
+			var sales = writeProductInvoiceLines(ledger, &req, &res, &invoice)
+			if len(sales) != tt.wantSales {
+				t.Errorf("writeProductInvoiceLines() sales = %d, want %d", len(sales), tt.wantSales)
+			}
+			if !reflect.DeepEqual(res.TransactionIDs, tt.wantIDs) {
+				t.Errorf("writeProductInvoiceLines() TransactionIDs = %v, want %v", res.TransactionIDs, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(ledger.reversed, tt.wantReversed) || ledger.voided != tt.wantVoided {
+				t.Errorf("writeProductInvoiceLines() reversed = %v, %d, want %v, %d", ledger.reversed, ledger.voided, tt.wantReversed, tt.wantVoided)
+			}
+			var not []productInvoiceLineStatus
+			for _, pro := range res.NotRegistred {
+				not = append(not, pro.Status)
+			}
+			if !reflect.DeepEqual(not, tt.wantNot) {
+				t.Errorf("writeProductInvoiceLines() NotRegistred = %v, want %v", not, tt.wantNot)
+			}
+			if tt.wantSales == 0 && !tt.bestEffort && res.InvoiceID != [32]byte{} {
+				t.Errorf("writeProductInvoiceLines() strict failed invoice must clear InvoiceID")
+			}
+			if res.InvoiceID != [32]byte{} && len(invoice.Lines) != tt.wantSales {
+				t.Errorf("writeProductInvoiceLines() invoice lines = %d, want %d", len(invoice.Lines), tt.wantSales)
+			}
+		})
+	}
+}
+
+func TestRegisterProductInvoiceDetail_CheckNumber(t *testing.T) {
+	var tests = []struct {
+		name   string
+		number uint64
+	}{
+		{"zero", 0},
+		{"more than one", 2},
+		{"max", 18446744073709551615},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Number check before any read, so no stream need.
+			var pro = registerProductInvoiceDetail{
+				QuiddityID: [32]byte{1},
+				Number:     tt.number,
+			}
+			if status := pro.check(nil, [32]byte{2}, 0, [32]byte{}); status != productInvoiceLineBadNumber {
+				t.Errorf("check() = %v, want %v", status, productInvoiceLineBadNumber)
+			}
+		})
+	}
+}
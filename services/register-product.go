@@ -89,6 +89,7 @@ type registerProductReq struct {
 	Language     lang.Language // Just use to check quiddity exist and belong to requested org
 	ProductionID [32]byte      `json:",string"`
 	Number       uint64
+	Quantity     uint64 // Amount of quiddity base unit in each product e.g. gram. Zero means products are not measurable
 }
 
 type registerProductRes struct {
@@ -137,7 +138,8 @@ func registerProduct(st *achaemenid.Stream, req *registerProductReq) (res *regis
 			ProductionID: req.ProductionID,
 			DCID:         st.Connection.UserID, // product must register first in product owner OrgID and update it later.
 			// ProductAuctionID: req.ProductAuctionID,
-			Status: datastore.ProductCreated,
+			Status:   datastore.ProductCreated,
+			Quantity: req.Quantity,
 		}
 
 		res.IDs[i] = p.ID
@@ -146,6 +148,7 @@ func registerProduct(st *achaemenid.Stream, req *registerProductReq) (res *regis
 		if err != nil {
 			return
 		}
+		moveProductStock(nil, &p)
 	}
 	return
 }
@@ -164,6 +167,7 @@ func (req *registerProductReq) syllabDecoder(buf []byte) (err *er.Error) {
 	req.Language = lang.Language(syllab.GetUInt32(buf, 32))
 	copy(req.ProductionID[:], buf[36:])
 	req.Number = syllab.GetUInt64(buf, 68)
+	req.Quantity = syllab.GetUInt64(buf, 76)
 	return
 }
 
@@ -172,11 +176,12 @@ func (req *registerProductReq) syllabEncoder(buf []byte) {
 	syllab.SetUInt32(buf, 32, uint32(req.Language))
 	copy(buf[36:], req.ProductionID[:])
 	syllab.SetUInt64(buf, 68, req.Number)
+	syllab.SetUInt64(buf, 76, req.Quantity)
 	return
 }
 
 func (req *registerProductReq) syllabStackLen() (ln uint32) {
-	return 76
+	return 84
 }
 
 func (req *registerProductReq) syllabHeapLen() (ln uint32) {
@@ -204,6 +209,8 @@ func (req *registerProductReq) jsonDecoder(buf []byte) (err *er.Error) {
 			err = decoder.DecodeByteArrayAsBase64(req.ProductionID[:])
 		case "Number":
 			req.Number, err = decoder.DecodeUInt64()
+		case "Quantity":
+			req.Quantity, err = decoder.DecodeUInt64()
 		default:
 			err = decoder.NotFoundKeyStrict()
 		}
@@ -232,12 +239,15 @@ func (req *registerProductReq) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`","Number":`)
 	encoder.EncodeUInt64(req.Number)
 
+	encoder.EncodeString(`,"Quantity":`)
+	encoder.EncodeUInt64(req.Quantity)
+
 	encoder.EncodeByte('}')
 	return encoder.Buf
 }
 
 func (req *registerProductReq) jsonLen() (ln int) {
-	ln = 169
+	ln = 201
 	return
 }
 
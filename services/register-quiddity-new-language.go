@@ -129,6 +129,7 @@ func registerQuiddityNewLanguage(st *achaemenid.Stream, req *registerQuiddityNew
 		URI:      req.URI,
 		Title:    req.Title,
 		Status:   datastore.QuiddityStatusRegister,
+		Unit:     q.Unit,
 	}
 	err = q.Set()
 	if err != nil {
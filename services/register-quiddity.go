@@ -87,8 +87,9 @@ func RegisterQuiddityHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes
 
 type registerQuiddityReq struct {
 	Language lang.Language
-	URI      string `valid:"text[0:100]"`
-	Title    string `valid:"text[0:100]"`
+	URI      string                 `valid:"text[0:100]"`
+	Title    string                 `valid:"text[0:100]"`
+	Unit     datastore.QuiddityUnit // Base unit of products quantity. Can't change later!
 }
 
 type registerQuiddityRes struct {
@@ -130,6 +131,7 @@ func registerQuiddity(st *achaemenid.Stream, req *registerQuiddityReq) (res *reg
 		URI:      req.URI,
 		Title:    req.Title,
 		Status:   datastore.QuiddityStatusRegister,
+		Unit:     req.Unit,
 	}
 	err = q.SaveNew()
 	if err != nil {
@@ -196,6 +198,7 @@ func (req *registerQuiddityReq) syllabDecoder(buf []byte) (err *er.Error) {
 	req.Language = lang.Language(syllab.GetUInt32(buf, 0))
 	req.URI = syllab.UnsafeGetString(buf, 4)
 	req.Title = syllab.UnsafeGetString(buf, 12)
+	req.Unit = datastore.QuiddityUnit(syllab.GetUInt8(buf, 20))
 	return
 }
 
@@ -205,11 +208,12 @@ func (req *registerQuiddityReq) syllabEncoder(buf []byte) {
 	syllab.SetUInt32(buf, 0, uint32(req.Language))
 	hsi = syllab.SetString(buf, req.URI, 4, hsi)
 	hsi = syllab.SetString(buf, req.Title, 12, hsi)
+	syllab.SetUInt8(buf, 20, uint8(req.Unit))
 	return
 }
 
 func (req *registerQuiddityReq) syllabStackLen() (ln uint32) {
-	return 20
+	return 21
 }
 
 func (req *registerQuiddityReq) syllabHeapLen() (ln uint32) {
@@ -237,6 +241,10 @@ func (req *registerQuiddityReq) jsonDecoder(buf []byte) (err *er.Error) {
 			req.URI, err = decoder.DecodeString()
 		case "Title":
 			req.Title, err = decoder.DecodeString()
+		case "Unit":
+			var num uint8
+			num, err = decoder.DecodeUInt8()
+			req.Unit = datastore.QuiddityUnit(num)
 		default:
 			err = decoder.NotFoundKeyStrict()
 		}
@@ -262,13 +270,16 @@ func (req *registerQuiddityReq) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`","Title":"`)
 	encoder.EncodeString(req.Title)
 
-	encoder.EncodeString(`"}`)
+	encoder.EncodeString(`","Unit":`)
+	encoder.EncodeUInt8(uint8(req.Unit))
+
+	encoder.EncodeByte('}')
 	return encoder.Buf
 }
 
 func (req *registerQuiddityReq) jsonLen() (ln int) {
 	ln = len(req.URI) + len(req.Title)
-	ln += 43
+	ln += 53
 	return
 }
 
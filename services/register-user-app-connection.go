@@ -6,6 +6,7 @@ import (
 	"../datastore"
 	"../libgo/achaemenid"
 	"../libgo/authorization"
+	etime "../libgo/earth-time"
 	er "../libgo/error"
 	"../libgo/http"
 	"../libgo/json"
@@ -94,6 +95,7 @@ type registerUserAppConnectionReq struct {
 
 	PublicKey     [32]byte `json:",string"`
 	AccessControl authorization.AccessControl
+	ExpireTime    etime.Time // Zero means connection never expire
 }
 
 type registerUserAppConnectionRes struct {
@@ -147,6 +149,7 @@ func registerUserAppConnection(st *achaemenid.Stream, req *registerUserAppConnec
 
 		PeerPublicKey: req.PublicKey,
 		AccessControl: req.AccessControl,
+		ExpireTime:    req.ExpireTime,
 	}
 	err = uac.SaveNew()
 	if err != nil {
@@ -162,6 +165,12 @@ func registerUserAppConnection(st *achaemenid.Stream, req *registerUserAppConnec
 
 func (req *registerUserAppConnectionReq) validator() (err *er.Error) {
 	err = validators.ValidateText(req.Description, 0, 50)
+	if err != nil {
+		return
+	}
+	if req.ExpireTime != 0 && req.ExpireTime.Pass(etime.Now()) {
+		err = ErrUserAppConnectionBadExpireTime
+	}
 	return
 }
 
@@ -170,7 +179,8 @@ func (req *registerUserAppConnectionReq) validator() (err *er.Error) {
 */
 
 func (req *registerUserAppConnectionReq) syllabDecoder(buf []byte) (err *er.Error) {
-	if uint32(len(buf)) < req.syllabStackLen() {
+	// Requests of old clients has no expire time, so must accept them too.
+	if uint32(len(buf)) < 106+req.AccessControl.SyllabStackLen() {
 		err = syllab.ErrSyllabDecodeSmallSlice
 		return
 	}
@@ -182,6 +192,9 @@ func (req *registerUserAppConnectionReq) syllabDecoder(buf []byte) (err *er.Erro
 	req.DelegateUserType = authorization.UserType(syllab.GetUInt8(buf, 73))
 	copy(req.PublicKey[:], buf[74:])
 	req.AccessControl.SyllabDecoder(buf, 106)
+	if syllab.GetUInt32(buf, 0) >= req.syllabStackLen() {
+		req.ExpireTime = etime.Time(syllab.GetInt64(buf, 106+req.AccessControl.SyllabStackLen()))
+	}
 	return
 }
 
@@ -195,11 +208,12 @@ func (req *registerUserAppConnectionReq) syllabEncoder(buf []byte) {
 	syllab.SetUInt8(buf, 73, uint8(req.DelegateUserType))
 	copy(buf[74:], req.PublicKey[:])
 	req.AccessControl.SyllabEncoder(buf, 106, hsi)
+	syllab.SetInt64(buf, 106+req.AccessControl.SyllabStackLen(), int64(req.ExpireTime))
 	return
 }
 
 func (req *registerUserAppConnectionReq) syllabStackLen() (ln uint32) {
-	return 106 + req.AccessControl.SyllabStackLen()
+	return 114 + req.AccessControl.SyllabStackLen()
 }
 
 func (req *registerUserAppConnectionReq) syllabHeapLen() (ln uint32) {
@@ -238,6 +252,10 @@ func (req *registerUserAppConnectionReq) jsonDecoder(buf []byte) (err *er.Error)
 
 		case "AccessControl":
 			err = req.AccessControl.JSONDecoder(decoder)
+		case "ExpireTime":
+			var num int64
+			num, err = decoder.DecodeInt64()
+			req.ExpireTime = etime.Time(num)
 
 		default:
 			err = decoder.NotFoundKeyStrict()
@@ -276,6 +294,9 @@ func (req *registerUserAppConnectionReq) jsonEncoder() (buf []byte) {
 	encoder.EncodeString(`","AccessControl":`)
 	req.AccessControl.JSONEncoder(encoder)
 
+	encoder.EncodeString(`,"ExpireTime":`)
+	encoder.EncodeInt64(int64(req.ExpireTime))
+
 	encoder.EncodeByte('}')
 	return encoder.Buf
 }
@@ -283,7 +304,7 @@ func (req *registerUserAppConnectionReq) jsonEncoder() (buf []byte) {
 func (req *registerUserAppConnectionReq) jsonLen() (ln int) {
 	ln = len(req.Description)
 	ln += req.AccessControl.JSONLen()
-	ln += 251
+	ln += 285
 	return
 }
 
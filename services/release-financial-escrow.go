@@ -0,0 +1,225 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var releaseFinancialEscrowService = achaemenid.Service{
+	ID:                3924170558,
+	IssueDate:         1792294530,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Release Financial Escrow",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `release held escrow amount to the seller. Just product DC can confirm delivery and release the escrow.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction", "Product",
+	},
+
+	SRPCHandler: ReleaseFinancialEscrowSRPC,
+	HTTPHandler: ReleaseFinancialEscrowHTTP,
+}
+
+// ReleaseFinancialEscrowSRPC is sRPC handler of ReleaseFinancialEscrow service.
+func ReleaseFinancialEscrowSRPC(st *achaemenid.Stream) {
+	var req = &releaseFinancialEscrowReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *releaseFinancialEscrowRes
+	res, st.Err = releaseFinancialEscrow(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// ReleaseFinancialEscrowHTTP is HTTP handler of ReleaseFinancialEscrow service.
+func ReleaseFinancialEscrowHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &releaseFinancialEscrowReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *releaseFinancialEscrowRes
+	res, st.Err = releaseFinancialEscrow(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type releaseFinancialEscrowReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type releaseFinancialEscrowRes struct {
+}
+
+func releaseFinancialEscrow(st *achaemenid.Stream, req *releaseFinancialEscrowReq) (res *releaseFinancialEscrowRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var fe = datastore.FinancialEscrow{
+		ID: req.ID,
+	}
+	err = fe.GetLastByID()
+	if err != nil {
+		return
+	}
+	if st.Connection.UserID != fe.DCID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+	// Just the escrow that hold the product can pay the seller, other concurrent holds of the product refund.
+	if fe.Status == datastore.FinancialEscrowHeld {
+		var holderID [32]byte
+		holderID, _, err = findFinancialEscrowHolder(fe.ProductID)
+		if err != nil {
+			return
+		}
+		if holderID != fe.ID {
+			err = ErrFinancialEscrowExist
+			return
+		}
+	}
+
+	var decision datastore.FinancialEscrow
+	decision, err = decideFinancialEscrow(&fe, datastore.FinancialEscrowReleased)
+	if err != nil {
+		return
+	}
+	if decision.Status != datastore.FinancialEscrowReleased {
+		err = ErrFinancialEscrowDecided
+		return
+	}
+	err = settleFinancialEscrow(&decision)
+	if err != nil {
+		return
+	}
+
+	res = &releaseFinancialEscrowRes{}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *releaseFinancialEscrowReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *releaseFinancialEscrowReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *releaseFinancialEscrowReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *releaseFinancialEscrowReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *releaseFinancialEscrowReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *releaseFinancialEscrowReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *releaseFinancialEscrowReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *releaseFinancialEscrowReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *releaseFinancialEscrowRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *releaseFinancialEscrowRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *releaseFinancialEscrowRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *releaseFinancialEscrowRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *releaseFinancialEscrowRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *releaseFinancialEscrowRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *releaseFinancialEscrowRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *releaseFinancialEscrowRes) jsonLen() (ln int) {
+	return
+}
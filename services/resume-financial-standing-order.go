@@ -0,0 +1,220 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var resumeFinancialStandingOrderService = achaemenid.Service{
+	ID:                1096673524,
+	IssueDate:         1792294756,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Resume Financial Standing Order",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `resume paused standing order of the user. Periods that passed in pause time skip.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: ResumeFinancialStandingOrderSRPC,
+	HTTPHandler: ResumeFinancialStandingOrderHTTP,
+}
+
+// ResumeFinancialStandingOrderSRPC is sRPC handler of ResumeFinancialStandingOrder service.
+func ResumeFinancialStandingOrderSRPC(st *achaemenid.Stream) {
+	var req = &resumeFinancialStandingOrderReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *resumeFinancialStandingOrderRes
+	res, st.Err = resumeFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// ResumeFinancialStandingOrderHTTP is HTTP handler of ResumeFinancialStandingOrder service.
+func ResumeFinancialStandingOrderHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &resumeFinancialStandingOrderReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *resumeFinancialStandingOrderRes
+	res, st.Err = resumeFinancialStandingOrder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type resumeFinancialStandingOrderReq struct {
+	ID [32]byte `json:",string"`
+}
+
+type resumeFinancialStandingOrderRes struct {
+}
+
+func resumeFinancialStandingOrder(st *achaemenid.Stream, req *resumeFinancialStandingOrderReq) (res *resumeFinancialStandingOrderRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var fso = datastore.FinancialStandingOrder{
+		ID: req.ID,
+	}
+	err = fso.GetLastByID()
+	if err != nil {
+		return
+	}
+	if fso.FromUserID != st.Connection.UserID {
+		err = authorization.ErrUserNotOwnRecord
+		return
+	}
+	if fso.Status != datastore.FinancialStandingOrderPaused {
+		err = ErrFinancialStandingOrderBadStatus
+		return
+	}
+
+	// Periods that passed in pause time skip.
+	var now = etime.Now()
+	for fso.ScheduledTime < now && fso.Status != datastore.FinancialStandingOrderFinished {
+		nextFinancialStandingOrderPeriod(&fso)
+	}
+	if fso.Status != datastore.FinancialStandingOrderFinished {
+		fso.Status = datastore.FinancialStandingOrderActive
+	}
+	fso.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	err = fso.Set()
+	if err != nil {
+		return
+	}
+	fso.IndexRecordIDForID()
+	fso.IndexIDForNextRunTimeDaily()
+
+	res = &resumeFinancialStandingOrderRes{}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *resumeFinancialStandingOrderReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	return
+}
+
+func (req *resumeFinancialStandingOrderReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	return
+}
+
+func (req *resumeFinancialStandingOrderReq) syllabStackLen() (ln uint32) {
+	return 32
+}
+
+func (req *resumeFinancialStandingOrderReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *resumeFinancialStandingOrderReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *resumeFinancialStandingOrderReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *resumeFinancialStandingOrderReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *resumeFinancialStandingOrderReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *resumeFinancialStandingOrderRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *resumeFinancialStandingOrderRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *resumeFinancialStandingOrderRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *resumeFinancialStandingOrderRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *resumeFinancialStandingOrderRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *resumeFinancialStandingOrderRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *resumeFinancialStandingOrderRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *resumeFinancialStandingOrderRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,271 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var setFinancialRuleService = achaemenid.Service{
+	ID:                2739016485,
+	IssueDate:         1792294891,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Set Financial Rule",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `set spending limits of the user e.g. daily cap, per transaction cap and cooling-off for new delegate connections.
+Transfer and invoice services check these rules before withdraw from user balance.
+Loosen active rule need valid OTP of the person.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: SetFinancialRuleSRPC,
+	HTTPHandler: SetFinancialRuleHTTP,
+}
+
+// SetFinancialRuleSRPC is sRPC handler of SetFinancialRule service.
+func SetFinancialRuleSRPC(st *achaemenid.Stream) {
+	var req = &setFinancialRuleReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *setFinancialRuleRes
+	res, st.Err = setFinancialRule(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// SetFinancialRuleHTTP is HTTP handler of SetFinancialRule service.
+func SetFinancialRuleHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &setFinancialRuleReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *setFinancialRuleRes
+	res, st.Err = setFinancialRule(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type setFinancialRuleReq struct {
+	DailyLimit         price.Amount // Zero means no limit
+	TransactionLimit   price.Amount // Zero means no limit
+	DailyCountLimit    uint32       // Zero means no limit
+	DelegateCoolingOff uint32       // Second
+	OTPStepUp          bool
+	OTP                uint64 `json:",optional"` // Need just to loosen active rule
+}
+
+type setFinancialRuleRes struct {
+}
+
+func setFinancialRule(st *achaemenid.Stream, req *setFinancialRuleReq) (res *setFinancialRuleRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	// Delegate connections must not change rules that limit them!
+	if st.Connection.DelegateUserID != [32]byte{} {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var fr = datastore.FinancialRule{
+		UserID: st.Connection.UserID,
+	}
+	err = fr.GetLastByUserID()
+	if err == nil {
+		if req.looserThan(&fr) && st.Connection.UserType == authorization.UserTypePerson {
+			err = checkPersonOTP(st.Connection.UserID, req.OTP)
+			if err != nil {
+				return
+			}
+		}
+	} else if !err.Equal(ganjine.ErrRecordNotFound) {
+		return
+	}
+
+	fr = datastore.FinancialRule{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		// UserConnectionID:      st.Connection.ID, can't uncomment this line due to HTTP use connectionID as authentication proccess!
+		UserID:             st.Connection.UserID,
+		DailyLimit:         req.DailyLimit,
+		TransactionLimit:   req.TransactionLimit,
+		DailyCountLimit:    req.DailyCountLimit,
+		DelegateCoolingOff: req.DelegateCoolingOff,
+		OTPStepUp:          req.OTPStepUp,
+	}
+	err = fr.SaveNew()
+	if err != nil {
+		return
+	}
+
+	res = &setFinancialRuleRes{}
+	return
+}
+
+func (req *setFinancialRuleReq) validator() (err *er.Error) {
+	if req.DailyLimit < 0 || req.TransactionLimit < 0 {
+		err = ErrFinancialTransactionBadAmount
+		return
+	}
+	return
+}
+
+// looserThan report if request rule let more withdraw than given active rule in any way.
+func (req *setFinancialRuleReq) looserThan(fr *datastore.FinancialRule) bool {
+	return looserFinancialLimit(uint64(fr.DailyLimit), uint64(req.DailyLimit)) ||
+		looserFinancialLimit(uint64(fr.TransactionLimit), uint64(req.TransactionLimit)) ||
+		looserFinancialLimit(uint64(fr.DailyCountLimit), uint64(req.DailyCountLimit)) ||
+		req.DelegateCoolingOff < fr.DelegateCoolingOff ||
+		(req.OTPStepUp && !fr.OTPStepUp)
+}
+
+// looserFinancialLimit report if new limit is more than old one. Zero means no limit.
+func looserFinancialLimit(active, next uint64) bool {
+	return active != 0 && (next == 0 || next > active)
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *setFinancialRuleReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	req.DailyLimit = price.Amount(syllab.GetInt64(buf, 0))
+	req.TransactionLimit = price.Amount(syllab.GetInt64(buf, 8))
+	req.DailyCountLimit = syllab.GetUInt32(buf, 16)
+	req.DelegateCoolingOff = syllab.GetUInt32(buf, 20)
+	req.OTPStepUp = buf[24] == 1
+	req.OTP = syllab.GetUInt64(buf, 25)
+	return
+}
+
+func (req *setFinancialRuleReq) syllabEncoder(buf []byte) {
+	syllab.SetInt64(buf, 0, int64(req.DailyLimit))
+	syllab.SetInt64(buf, 8, int64(req.TransactionLimit))
+	syllab.SetUInt32(buf, 16, req.DailyCountLimit)
+	syllab.SetUInt32(buf, 20, req.DelegateCoolingOff)
+	if req.OTPStepUp {
+		buf[24] = 1
+	}
+	syllab.SetUInt64(buf, 25, req.OTP)
+	return
+}
+
+func (req *setFinancialRuleReq) syllabStackLen() (ln uint32) {
+	return 33
+}
+
+func (req *setFinancialRuleReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *setFinancialRuleReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *setFinancialRuleReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *setFinancialRuleReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *setFinancialRuleReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *setFinancialRuleRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *setFinancialRuleRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *setFinancialRuleRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *setFinancialRuleRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *setFinancialRuleRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *setFinancialRuleRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *setFinancialRuleRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *setFinancialRuleRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,222 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var setProductStockReorderService = achaemenid.Service{
+	ID:                1183094726,
+	IssueDate:         1792295957,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Set Product Stock Reorder",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `set reorder level of a quiddity stock in a distribution center or in requester org. Leader of the org get SMS when on hand number drop below it.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: SetProductStockReorderSRPC,
+	HTTPHandler: SetProductStockReorderHTTP,
+}
+
+// SetProductStockReorderSRPC is sRPC handler of SetProductStockReorder service.
+func SetProductStockReorderSRPC(st *achaemenid.Stream) {
+	var req = &setProductStockReorderReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *setProductStockReorderRes
+	res, st.Err = setProductStockReorder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// SetProductStockReorderHTTP is HTTP handler of SetProductStockReorder service.
+func SetProductStockReorderHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &setProductStockReorderReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *setProductStockReorderRes
+	res, st.Err = setProductStockReorder(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type setProductStockReorderReq struct {
+	QuiddityID   [32]byte `json:",string"`
+	DCID         [32]byte `json:",string,optional"` // Empty means stock of requester org in all its DCs
+	ReorderLevel int64    // Zero means no notify!
+}
+
+type setProductStockReorderRes struct {
+}
+
+func setProductStockReorder(st *achaemenid.Stream, req *setProductStockReorderReq) (res *setProductStockReorderRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	var psr = datastore.ProductStockReorder{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: st.Connection.ID,
+		QuiddityID:       req.QuiddityID,
+		ReorderLevel:     req.ReorderLevel,
+	}
+	psr.ScopeID, psr.Scope = getProductStockScope(st, req.DCID, [32]byte{})
+	if st.Connection.UserID != psr.ScopeID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	err = psr.SaveNew()
+	if err != nil {
+		return
+	}
+
+	res = &setProductStockReorderRes{}
+	return
+}
+
+func (req *setProductStockReorderReq) validator() (err *er.Error) {
+	if req.ReorderLevel < 0 {
+		err = ErrProductStockBadReorderLevel
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *setProductStockReorderReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.QuiddityID[:], buf[0:])
+	copy(req.DCID[:], buf[32:])
+	req.ReorderLevel = syllab.GetInt64(buf, 64)
+	return
+}
+
+func (req *setProductStockReorderReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.QuiddityID[:])
+	copy(buf[32:], req.DCID[:])
+	syllab.SetInt64(buf, 64, req.ReorderLevel)
+	return
+}
+
+func (req *setProductStockReorderReq) syllabStackLen() (ln uint32) {
+	return 72
+}
+
+func (req *setProductStockReorderReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *setProductStockReorderReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *setProductStockReorderReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *setProductStockReorderReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *setProductStockReorderReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *setProductStockReorderRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *setProductStockReorderRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *setProductStockReorderRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *setProductStockReorderRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *setProductStockReorderRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *setProductStockReorderRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *setProductStockReorderRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *setProductStockReorderRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,154 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var settleSocietyFinancialTransferService = achaemenid.Service{
+	ID:                1292317097,
+	IssueDate:         1792293989,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDCreate,
+		UserType: authorization.UserTypeAll,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Settle Society Financial Transfer",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `receive signed transfer request of other society, deposit it to the user and answer with signed
+acknowledgement. Other societies can send same request more than once.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: SettleSocietyFinancialTransferSRPC,
+	HTTPHandler: SettleSocietyFinancialTransferHTTP,
+}
+
+// SettleSocietyFinancialTransferSRPC is sRPC handler of SettleSocietyFinancialTransfer service.
+func SettleSocietyFinancialTransferSRPC(st *achaemenid.Stream) {
+	var req = &societyTransferMessage{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res societyTransferMessage
+	res, st.Err = settleSocietyFinancialTransfer(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// SettleSocietyFinancialTransferHTTP is HTTP handler of SettleSocietyFinancialTransfer service.
+func SettleSocietyFinancialTransferHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &societyTransferMessage{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res societyTransferMessage
+	res, st.Err = settleSocietyFinancialTransfer(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+func settleSocietyFinancialTransfer(st *achaemenid.Stream, req *societyTransferMessage) (res societyTransferMessage, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	res, err = localSociety.receiveTransfer(req)
+	return
+}
+
+/*
+	Request & Response Encoders & Decoders
+*/
+
+func (msg *societyTransferMessage) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < msg.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	msg.Kind = societyTransferMessageKind(syllab.GetUInt8(buf, 0))
+	copy(msg.TransferID[:], buf[1:])
+	msg.FromSocietyID = syllab.GetUInt32(buf, 33)
+	copy(msg.FromUserID[:], buf[37:])
+	msg.ToSocietyID = syllab.GetUInt32(buf, 69)
+	copy(msg.ToUserID[:], buf[73:])
+	msg.Amount = syllab.GetInt64(buf, 105)
+	copy(msg.Signature[:], buf[113:])
+	return
+}
+
+func (msg *societyTransferMessage) syllabEncoder(buf []byte) {
+	syllab.SetUInt8(buf, 0, uint8(msg.Kind))
+	copy(buf[1:], msg.TransferID[:])
+	syllab.SetUInt32(buf, 33, msg.FromSocietyID)
+	copy(buf[37:], msg.FromUserID[:])
+	syllab.SetUInt32(buf, 69, msg.ToSocietyID)
+	copy(buf[73:], msg.ToUserID[:])
+	syllab.SetInt64(buf, 105, msg.Amount)
+	copy(buf[113:], msg.Signature[:])
+	return
+}
+
+func (msg *societyTransferMessage) syllabStackLen() (ln uint32) {
+	return 145
+}
+
+func (msg *societyTransferMessage) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (msg *societyTransferMessage) syllabLen() (ln int) {
+	return int(msg.syllabStackLen() + msg.syllabHeapLen())
+}
+
+func (msg *societyTransferMessage) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, msg)
+	return
+}
+
+func (msg *societyTransferMessage) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(msg)
+	return
+}
+
+func (msg *societyTransferMessage) jsonLen() (ln int) {
+	return
+}
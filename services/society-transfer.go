@@ -0,0 +1,380 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	goHTTP "net/http"
+	"strconv"
+	"time"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/json"
+	"../libgo/price"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+/*
+	Inter-society settlement protocol:
+	1. Payer society withdraw FromUserID and hold transfer in FinancialTransferDebited state.
+	2. Payer society send signed societyTransferRequest to payee society.
+	3. Payee society deposit ToUserID once per TransferID and answer with signed societyTransferAck,
+		or societyTransferNack if it never can deposit the transfer.
+	4. Payer society finish transfer on ack or reverse withdraw leg on nack.
+	If payer can't get valid answer, transfer stay held and recovery job send request again.
+	Each message sign by HMAC-SHA256 with the key that two societies share.
+*/
+
+// societyTransferMessageKind indicate kind of societyTransferMessage
+type societyTransferMessageKind uint8
+
+// societyTransferMessage kinds
+const (
+	societyTransferUnset societyTransferMessageKind = iota
+	societyTransferRequest
+	societyTransferAck
+	societyTransferNack
+)
+
+// societyTransferMessage is signed message that societies exchange to settle a transfer.
+type societyTransferMessage struct {
+	Kind          societyTransferMessageKind
+	TransferID    [32]byte `json:",string"`
+	FromSocietyID uint32
+	FromUserID    [32]byte `json:",string"`
+	ToSocietyID   uint32
+	ToUserID      [32]byte `json:",string"`
+	Amount        int64
+	Signature     [32]byte `json:",string"`
+}
+
+func (msg *societyTransferMessage) sign(key []byte) (signature [32]byte) {
+	var buf = make([]byte, 113) // 1+32+4+32+4+32+8
+	buf[0] = byte(msg.Kind)
+	copy(buf[1:], msg.TransferID[:])
+	syllab.SetUInt32(buf, 33, msg.FromSocietyID)
+	copy(buf[37:], msg.FromUserID[:])
+	syllab.SetUInt32(buf, 69, msg.ToSocietyID)
+	copy(buf[73:], msg.ToUserID[:])
+	syllab.SetInt64(buf, 105, msg.Amount)
+
+	var mac = hmac.New(sha256.New, key)
+	mac.Write(buf)
+	copy(signature[:], mac.Sum(nil))
+	return
+}
+
+func (msg *societyTransferMessage) verify(key []byte) bool {
+	var signature = msg.sign(key)
+	return hmac.Equal(signature[:], msg.Signature[:])
+}
+
+// sameTransfer report if two messages are about same transfer regardless of their kind.
+func (msg *societyTransferMessage) sameTransfer(other *societyTransferMessage) bool {
+	return msg.TransferID == other.TransferID && msg.FromSocietyID == other.FromSocietyID &&
+		msg.FromUserID == other.FromUserID && msg.ToSocietyID == other.ToSocietyID &&
+		msg.ToUserID == other.ToUserID && msg.Amount == other.Amount
+}
+
+// societyTransport deliver request to peer society and return its answer.
+type societyTransport interface {
+	Send(msg *societyTransferMessage) (answer societyTransferMessage, err *er.Error)
+}
+
+// societyLedger keep local legs of inter-society transfers.
+type societyLedger interface {
+	// Hold withdraw FromUserID and keep transfer pending until peer answer.
+	Hold(msg *societyTransferMessage) (err *er.Error)
+	// Finish mark held transfer as done after peer deposit ToUserID.
+	Finish(msg *societyTransferMessage) (err *er.Error)
+	// Rollback restore FromUserID balance after peer reject the transfer.
+	Rollback(msg *societyTransferMessage) (err *er.Error)
+	// Deposit credit ToUserID for incoming transfer just once per TransferID.
+	Deposit(msg *societyTransferMessage) (err *er.Error)
+}
+
+type societyPeer struct {
+	key       []byte // Shared key with the peer society
+	transport societyTransport
+}
+
+type society struct {
+	ID               uint32         // Zero means achaemenid.Server.Manifest.SocietyID
+	location         *time.Location // Nil means UTC
+	currencyDecimals uint8          // Number of minor unit digits of society default currency
+	peers            map[uint32]*societyPeer
+	ledger           societyLedger
+}
+
+var localSociety = society{
+	peers:  map[uint32]*societyPeer{},
+	ledger: datastoreSocietyLedger{},
+}
+
+func (s *society) id() uint32 {
+	if s.ID != 0 {
+		return s.ID
+	}
+	return achaemenid.Server.Manifest.SocietyID
+}
+
+// localTime return given time in the society time zone e.g. to check local weekdays and day hours.
+func (s *society) localTime(t etime.Time) time.Time {
+	var location = s.location
+	if location == nil {
+		location = time.UTC
+	}
+	return time.Unix(int64(t), 0).In(location)
+}
+
+// sendTransfer hold msg.Amount from msg.FromUserID and settle it with msg.ToSocietyID society.
+// It return nil error if peer not answer yet and transfer stay held for recovery job!
+func (s *society) sendTransfer(msg *societyTransferMessage) (err *er.Error) {
+	if _, ok := s.peers[msg.ToSocietyID]; !ok {
+		err = ErrFinancialTransactionBadSociety
+		return
+	}
+
+	msg.Kind = societyTransferRequest
+	msg.FromSocietyID = s.id()
+	if msg.TransferID == [32]byte{} {
+		msg.TransferID = uuid.Random32Byte()
+	}
+	err = s.ledger.Hold(msg)
+	if err != nil {
+		return
+	}
+
+	err = s.settleTransfer(msg)
+	if err != nil && !err.Equal(ErrSocietyTransferRejected) {
+		// Transfer held and recovery job will send it again.
+		err = nil
+	}
+	return
+}
+
+// settleTransfer send held transfer to peer society and finish or rollback it by peer answer.
+func (s *society) settleTransfer(msg *societyTransferMessage) (err *er.Error) {
+	var peer, ok = s.peers[msg.ToSocietyID]
+	if !ok {
+		err = ErrFinancialTransactionBadSociety
+		return
+	}
+
+	msg.Kind = societyTransferRequest
+	msg.Signature = msg.sign(peer.key)
+	var answer societyTransferMessage
+	answer, err = peer.transport.Send(msg)
+	if err != nil {
+		return
+	}
+	if !answer.verify(peer.key) || !answer.sameTransfer(msg) {
+		err = ErrSocietyTransferBadSignature
+		return
+	}
+
+	switch answer.Kind {
+	case societyTransferAck:
+		err = s.ledger.Finish(msg)
+	case societyTransferNack:
+		err = s.ledger.Rollback(msg)
+		if err == nil {
+			err = ErrSocietyTransferRejected
+		}
+	default:
+		err = ErrSocietyTransferBadSignature
+	}
+	return
+}
+
+// receiveTransfer verify request of peer society, deposit it and return signed answer.
+// Error return when answer can't make now and peer must send request again later.
+func (s *society) receiveTransfer(msg *societyTransferMessage) (answer societyTransferMessage, err *er.Error) {
+	var peer, ok = s.peers[msg.FromSocietyID]
+	if !ok || msg.Kind != societyTransferRequest || !msg.verify(peer.key) {
+		err = ErrSocietyTransferBadSignature
+		return
+	}
+
+	answer = *msg
+	if msg.ToSocietyID != s.id() || msg.ToUserID == [32]byte{} || msg.Amount <= 0 {
+		answer.Kind = societyTransferNack
+	} else {
+		err = s.ledger.Deposit(msg)
+		if err != nil {
+			return
+		}
+		answer.Kind = societyTransferAck
+	}
+	answer.Signature = answer.sign(peer.key)
+	return
+}
+
+/*
+	Production transport & ledger
+*/
+
+const societyTransportTimeout = 10 * time.Second
+
+// httpSocietyTransport send message to settleSocietyFinancialTransferService of peer society.
+type httpSocietyTransport struct {
+	URL string
+}
+
+func (t httpSocietyTransport) Send(msg *societyTransferMessage) (answer societyTransferMessage, err *er.Error) {
+	var body = msg.jsonEncoder()
+	var client = goHTTP.Client{Timeout: societyTransportTimeout}
+	var res, goErr = client.Post(t.URL, "application/json", bytes.NewReader(body))
+	if goErr != nil {
+		err = ErrSocietyTransferUnreachable
+		return
+	}
+	defer res.Body.Close()
+	body, goErr = ioutil.ReadAll(res.Body)
+	if goErr != nil || res.StatusCode != goHTTP.StatusOK {
+		err = ErrSocietyTransferUnreachable
+		return
+	}
+	err = answer.jsonDecoder(body)
+	return
+}
+
+// societiesConfig is structure of secret/societies.json file.
+type societiesConfig struct {
+	Peers []struct {
+		SocietyID  uint32
+		DomainName string
+		SecretKey  string // Base64 encoded shared key
+	}
+}
+
+// initSocieties register peer societies from given json config.
+func initSocieties(configJSON []byte) (err *er.Error) {
+	var config societiesConfig
+	err = json.UnMarshal(configJSON, &config)
+	if err != nil {
+		return
+	}
+	for _, p := range config.Peers {
+		var key, goErr = base64.StdEncoding.DecodeString(p.SecretKey)
+		if goErr != nil || len(key) == 0 {
+			return ErrSocietyTransferConfig
+		}
+		localSociety.peers[p.SocietyID] = &societyPeer{
+			key: key,
+			transport: httpSocietyTransport{
+				URL: "https://" + p.DomainName + "/apis?" + strconv.FormatUint(uint64(settleSocietyFinancialTransferService.ID), 10),
+			},
+		}
+	}
+	return
+}
+
+// datastoreSocietyLedger keep legs in FinancialTransaction chains and state in FinancialTransfer.
+type datastoreSocietyLedger struct{}
+
+func (datastoreSocietyLedger) Hold(msg *societyTransferMessage) (err *er.Error) {
+	var ftr = financialTransferOfSocietyMessage(msg)
+	ftr.Status = datastore.FinancialTransferRegistered
+	err = ftr.SaveNew()
+	if err != nil {
+		return
+	}
+
+	var withdraw = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserID:        msg.FromUserID,
+		ReferenceID:   msg.TransferID,
+		ReferenceType: datastore.FinancialTransactionSocietyTransfer,
+		Amount:        -price.Amount(msg.Amount),
+	}
+	err = saveFinancialTransaction(&withdraw)
+	if err != nil {
+		updateFinancialTransferStatus(&ftr, datastore.FinancialTransferFailed)
+		return
+	}
+	updateFinancialTransferStatus(&ftr, datastore.FinancialTransferDebited)
+	return
+}
+
+func (datastoreSocietyLedger) Finish(msg *societyTransferMessage) (err *er.Error) {
+	var ftr = financialTransferOfSocietyMessage(msg)
+	err = ftr.GetLastByID()
+	if err != nil {
+		return
+	}
+	if ftr.Status == datastore.FinancialTransferDebited {
+		updateFinancialTransferStatus(&ftr, datastore.FinancialTransferCredited)
+	}
+	return
+}
+
+func (datastoreSocietyLedger) Rollback(msg *societyTransferMessage) (err *er.Error) {
+	var ftr = financialTransferOfSocietyMessage(msg)
+	err = ftr.GetLastByID()
+	if err != nil {
+		return
+	}
+	err = reverseFinancialTransfer(&ftr)
+	return
+}
+
+func (datastoreSocietyLedger) Deposit(msg *societyTransferMessage) (err *er.Error) {
+	var ftr = financialTransferOfSocietyMessage(msg)
+	err = ftr.GetLastByID()
+	if err == nil && ftr.Status == datastore.FinancialTransferCredited {
+		return
+	}
+	ftr = financialTransferOfSocietyMessage(msg)
+	ftr.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	ftr.Status = datastore.FinancialTransferDebited
+	err = ftr.SaveNew()
+	if err != nil {
+		return
+	}
+
+	var deposit = datastore.FinancialTransaction{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserID:        msg.ToUserID,
+		ReferenceID:   msg.TransferID,
+		ReferenceType: datastore.FinancialTransactionSocietyTransfer,
+		Amount:        price.Amount(msg.Amount),
+	}
+	err = saveFinancialTransactionOnce(&deposit)
+	if err != nil {
+		return
+	}
+	updateFinancialTransferStatus(&ftr, datastore.FinancialTransferCredited)
+	return
+}
+
+func financialTransferOfSocietyMessage(msg *societyTransferMessage) datastore.FinancialTransfer {
+	return datastore.FinancialTransfer{
+		AppInstanceID: achaemenid.Server.Nodes.LocalNode.InstanceID,
+		ID:            msg.TransferID,
+		FromUserID:    msg.FromUserID,
+		ToUserID:      msg.ToUserID,
+		Amount:        price.Amount(msg.Amount),
+		FromSocietyID: msg.FromSocietyID,
+		ToSocietyID:   msg.ToSocietyID,
+	}
+}
+
+func societyMessageOfFinancialTransfer(ftr *datastore.FinancialTransfer) *societyTransferMessage {
+	return &societyTransferMessage{
+		TransferID:    ftr.ID,
+		FromSocietyID: ftr.FromSocietyID,
+		FromUserID:    ftr.FromUserID,
+		ToSocietyID:   ftr.ToSocietyID,
+		ToUserID:      ftr.ToUserID,
+		Amount:        int64(ftr.Amount),
+	}
+}
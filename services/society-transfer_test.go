@@ -0,0 +1,151 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	er "../libgo/error"
+)
+
+// memorySocietyLedger is in-memory societyLedger to run societies in one process.
+type memorySocietyLedger struct {
+	balances  map[[32]byte]int64
+	held      map[[32]byte]bool
+	deposited map[[32]byte]bool
+}
+
+func newMemorySocietyLedger() *memorySocietyLedger {
+	return &memorySocietyLedger{
+		balances:  map[[32]byte]int64{},
+		held:      map[[32]byte]bool{},
+		deposited: map[[32]byte]bool{},
+	}
+}
+
+func (l *memorySocietyLedger) Hold(msg *societyTransferMessage) (err *er.Error) {
+	if l.balances[msg.FromUserID] < msg.Amount {
+		return ErrFinancialTransactionBalance
+	}
+	l.balances[msg.FromUserID] -= msg.Amount
+	l.held[msg.TransferID] = true
+	return
+}
+
+func (l *memorySocietyLedger) Finish(msg *societyTransferMessage) (err *er.Error) {
+	delete(l.held, msg.TransferID)
+	return
+}
+
+func (l *memorySocietyLedger) Rollback(msg *societyTransferMessage) (err *er.Error) {
+	if l.held[msg.TransferID] {
+		delete(l.held, msg.TransferID)
+		l.balances[msg.FromUserID] += msg.Amount
+	}
+	return
+}
+
+func (l *memorySocietyLedger) Deposit(msg *societyTransferMessage) (err *er.Error) {
+	if !l.deposited[msg.TransferID] {
+		l.deposited[msg.TransferID] = true
+		l.balances[msg.ToUserID] += msg.Amount
+	}
+	return
+}
+
+// directSocietyTransport deliver messages to other society in same process.
+type directSocietyTransport struct {
+	to   *society
+	down bool
+}
+
+func (t *directSocietyTransport) Send(msg *societyTransferMessage) (answer societyTransferMessage, err *er.Error) {
+	if t.down {
+		return answer, ErrSocietyTransferUnreachable
+	}
+	var req = *msg
+	return t.to.receiveTransfer(&req)
+}
+
+func newTestSocieties(key []byte) (a, b *society, aLedger, bLedger *memorySocietyLedger, aToB *directSocietyTransport) {
+	aLedger, bLedger = newMemorySocietyLedger(), newMemorySocietyLedger()
+	a = &society{ID: 1, peers: map[uint32]*societyPeer{}, ledger: aLedger}
+	b = &society{ID: 2, peers: map[uint32]*societyPeer{}, ledger: bLedger}
+	aToB = &directSocietyTransport{to: b}
+	a.peers[2] = &societyPeer{key: key, transport: aToB}
+	b.peers[1] = &societyPeer{key: key, transport: &directSocietyTransport{to: a}}
+	return
+}
+
+func TestSocietyTransfer(t *testing.T) {
+	var alice, bob = [32]byte{1}, [32]byte{2}
+
+	t.Run("settle", func(t *testing.T) {
+		var a, _, aLedger, bLedger, _ = newTestSocieties([]byte("shared"))
+		aLedger.balances[alice] = 100
+
+		var msg = societyTransferMessage{FromUserID: alice, ToSocietyID: 2, ToUserID: bob, Amount: 40}
+		var err = a.sendTransfer(&msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aLedger.balances[alice] != 60 || bLedger.balances[bob] != 40 || aLedger.held[msg.TransferID] {
+			t.Errorf("bad balances after settle: alice=%d bob=%d held=%v", aLedger.balances[alice], bLedger.balances[bob], aLedger.held[msg.TransferID])
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		var a, _, aLedger, bLedger, _ = newTestSocieties([]byte("shared"))
+		aLedger.balances[alice] = 100
+
+		var msg = societyTransferMessage{FromUserID: alice, ToSocietyID: 2, Amount: 40} // No ToUserID
+		var err = a.sendTransfer(&msg)
+		if !err.Equal(ErrSocietyTransferRejected) {
+			t.Fatal("want rejected error, got:", err)
+		}
+		if aLedger.balances[alice] != 100 || len(bLedger.deposited) != 0 {
+			t.Errorf("rejected transfer not rolled back: alice=%d", aLedger.balances[alice])
+		}
+	})
+
+	t.Run("retry after peer down", func(t *testing.T) {
+		var a, _, aLedger, bLedger, aToB = newTestSocieties([]byte("shared"))
+		aLedger.balances[alice] = 100
+		aToB.down = true
+
+		var msg = societyTransferMessage{FromUserID: alice, ToSocietyID: 2, ToUserID: bob, Amount: 40}
+		var err = a.sendTransfer(&msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !aLedger.held[msg.TransferID] || bLedger.balances[bob] != 0 {
+			t.Fatal("transfer must stay held while peer is down")
+		}
+
+		aToB.down = false
+		for i := 0; i < 2; i++ {
+			err = a.settleTransfer(&msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if aLedger.held[msg.TransferID] || bLedger.balances[bob] != 40 {
+			t.Errorf("retry not settle once: bob=%d", bLedger.balances[bob])
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		var a, b, aLedger, bLedger, _ = newTestSocieties([]byte("shared"))
+		b.peers[1].key = []byte("other")
+		aLedger.balances[alice] = 100
+
+		var msg = societyTransferMessage{FromUserID: alice, ToSocietyID: 2, ToUserID: bob, Amount: 40}
+		var err = a.sendTransfer(&msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !aLedger.held[msg.TransferID] || bLedger.balances[bob] != 0 {
+			t.Error("peer must not deposit message with bad signature")
+		}
+	})
+}
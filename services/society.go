@@ -0,0 +1,28 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"time"
+
+	er "../libgo/error"
+)
+
+// SocietyManifest is local society settings that services need in addition to achaemenid.Manifest.
+type SocietyManifest struct {
+	TimeZone         string // IANA time zone name of the society e.g. "Asia/Tehran". Empty means UTC
+	CurrencyDecimals uint8  // Number of minor unit digits of society default currency e.g. 2 for 8099 >> 80.99
+}
+
+// InitSociety set local society settings by given manifest. Call it before server start to serve requests!
+func InitSociety(manifest SocietyManifest) (err *er.Error) {
+	if manifest.TimeZone != "" {
+		var location, goErr = time.LoadLocation(manifest.TimeZone)
+		if goErr != nil {
+			return ErrSocietyBadTimeZone
+		}
+		localSociety.location = location
+	}
+	localSociety.currencyDecimals = manifest.CurrencyDecimals
+	return
+}
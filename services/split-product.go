@@ -0,0 +1,290 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
+)
+
+var splitProductService = achaemenid.Service{
+	ID:                1377542980,
+	IssueDate:         1792295704,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Split Product",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `split a measurable product e.g. a 50kg sack to smaller products of given quiddity. Product void and split products refer to it by ProductionID.`,
+	},
+	TAGS: []string{
+		"Product",
+	},
+
+	SRPCHandler: SplitProductSRPC,
+	HTTPHandler: SplitProductHTTP,
+}
+
+// SplitProductSRPC is sRPC handler of SplitProduct service.
+func SplitProductSRPC(st *achaemenid.Stream) {
+	var req = &splitProductReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *splitProductRes
+	res, st.Err = splitProduct(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// SplitProductHTTP is HTTP handler of SplitProduct service.
+func SplitProductHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &splitProductReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *splitProductRes
+	res, st.Err = splitProduct(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type splitProductReq struct {
+	ID         [32]byte `json:",string"`
+	QuiddityID [32]byte `json:",string"` // Quiddity of split products. Must have same base unit as the product quiddity
+	Quantities []uint64 // Quantity of each split product that must add up to the product quantity
+}
+
+type splitProductRes struct {
+	IDs [][32]byte `json:",string"`
+}
+
+func splitProduct(st *achaemenid.Stream, req *splitProductReq) (res *splitProductRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	var parent = datastore.Product{
+		ID: req.ID,
+	}
+	err = parent.GetLastByID()
+	if err != nil {
+		return
+	}
+	err = checkProductChangeable(st, &parent)
+	if err != nil {
+		return
+	}
+	err = checkProductSplit(&parent, req.Quantities)
+	if err != nil {
+		return
+	}
+	err = checkProductChangeQuiddity(parent.QuiddityID, req.QuiddityID)
+	if err != nil {
+		return
+	}
+
+	// Void parent first, so failure in middle of the split never add stock to the DC.
+	err = voidProduct(st, &parent, [32]byte{})
+	if err != nil {
+		return
+	}
+
+	res = &splitProductRes{
+		IDs: make([][32]byte, len(req.Quantities)),
+	}
+	for i, quantity := range req.Quantities {
+		var child = datastore.Product{
+			AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+			UserConnectionID: st.Connection.ID,
+			ID:               uuid.Random32Byte(),
+			OwnerID:          parent.OwnerID,
+			QuiddityID:       req.QuiddityID,
+			ProductionID:     parent.ID,
+			DCID:             parent.DCID,
+			Status:           datastore.ProductChangeQuiddity,
+			Quantity:         quantity,
+		}
+		err = child.SaveNew()
+		if err != nil {
+			return
+		}
+		moveProductStock(nil, &child)
+		res.IDs[i] = child.ID
+	}
+	return
+}
+
+func (req *splitProductReq) validator() (err *er.Error) {
+	if len(req.Quantities) < 2 || len(req.Quantities) > productMaxSplitMerge {
+		err = ErrProductQuantityMismatch
+		return
+	}
+	return
+}
+
+// checkProductSplit check measurable parent can split to products with given quantities.
+func checkProductSplit(parent *datastore.Product, quantities []uint64) (err *er.Error) {
+	if parent.Quantity == 0 {
+		return ErrProductNotMeasurable
+	}
+	return checkProductQuantities(parent.Quantity, quantities)
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *splitProductReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	copy(req.QuiddityID[:], buf[32:])
+	var add uint32 = syllab.GetUInt32(buf, 64)
+	var ln uint32 = syllab.GetUInt32(buf, 68)
+	if uint64(add)+uint64(ln)*8 > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+	req.Quantities = make([]uint64, ln)
+	for i := range req.Quantities {
+		req.Quantities[i] = syllab.GetUInt64(buf, add+uint32(i)*8)
+	}
+	return
+}
+
+func (req *splitProductReq) syllabEncoder(buf []byte) {
+	var hsi uint32 = req.syllabStackLen() // Heap start index || Stack size!
+
+	copy(buf[0:], req.ID[:])
+	copy(buf[32:], req.QuiddityID[:])
+	syllab.SetUInt32(buf, 64, hsi)
+	syllab.SetUInt32(buf, 68, uint32(len(req.Quantities)))
+	for i := range req.Quantities {
+		syllab.SetUInt64(buf, hsi, req.Quantities[i])
+		hsi += 8
+	}
+	return
+}
+
+func (req *splitProductReq) syllabStackLen() (ln uint32) {
+	return 72 // fixed size data + variables data add&&len
+}
+
+func (req *splitProductReq) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(req.Quantities) * 8)
+	return
+}
+
+func (req *splitProductReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *splitProductReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *splitProductReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *splitProductReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *splitProductRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.IDs = syllab.UnsafeGet32ByteArraySlice(buf, 0)
+	return
+}
+
+func (res *splitProductRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	syllab.Set32ByteArrayArray(buf, res.IDs, 0, hsi)
+	return
+}
+
+func (res *splitProductRes) syllabStackLen() (ln uint32) {
+	return 8 // fixed size data + variables data add&&len
+}
+
+func (res *splitProductRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.IDs) * 32)
+	return
+}
+
+func (res *splitProductRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *splitProductRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *splitProductRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *splitProductRes) jsonLen() (ln int) {
+	return
+}
@@ -0,0 +1,56 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../datastore"
+	er "../libgo/error"
+)
+
+func TestCheckProductSplit(t *testing.T) {
+	var tests = []struct {
+		name       string
+		quantity   uint64
+		quantities []uint64
+		err        *er.Error
+	}{
+		{"split", 10, []uint64{4, 6}, nil},
+		{"not measurable", 0, []uint64{1, 1}, ErrProductNotMeasurable},
+		{"less than parent", 10, []uint64{4, 5}, ErrProductQuantityMismatch},
+		{"more than parent", 10, []uint64{4, 7}, ErrProductQuantityMismatch},
+		{"zero child", 10, []uint64{10, 0}, ErrProductQuantityMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parent = datastore.Product{DCID: [32]byte{1}, Quantity: tt.quantity}
+			var err = checkProductSplit(&parent, tt.quantities)
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Errorf("checkProductSplit() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSplitProductReq_Validator(t *testing.T) {
+	var tests = []struct {
+		name       string
+		quantities []uint64
+		err        *er.Error
+	}{
+		{"two products", []uint64{1, 1}, nil},
+		{"max products", make([]uint64, productMaxSplitMerge), nil},
+		{"one product", []uint64{1}, ErrProductQuantityMismatch},
+		{"too many products", make([]uint64, productMaxSplitMerge+1), ErrProductQuantityMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req = splitProductReq{Quantities: tt.quantities}
+			var err = req.validator()
+			if (tt.err == nil && err != nil) || (tt.err != nil && !err.Equal(tt.err)) {
+				t.Errorf("validator() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
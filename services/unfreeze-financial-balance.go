@@ -0,0 +1,227 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var unfreezeFinancialBalanceService = achaemenid.Service{
+	ID:                1950782246,
+	IssueDate:         1792295516,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Unfreeze Financial Balance",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `Just justice department can unfreeze a frozen balance by the justice case that order the freeze. Frozen amount deposit back to user balance.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: UnfreezeFinancialBalanceSRPC,
+	HTTPHandler: UnfreezeFinancialBalanceHTTP,
+}
+
+// UnfreezeFinancialBalanceSRPC is sRPC handler of UnfreezeFinancialBalance service.
+func UnfreezeFinancialBalanceSRPC(st *achaemenid.Stream) {
+	var req = &unfreezeFinancialBalanceReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *unfreezeFinancialBalanceRes
+	res, st.Err = unfreezeFinancialBalance(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// UnfreezeFinancialBalanceHTTP is HTTP handler of UnfreezeFinancialBalance service.
+func UnfreezeFinancialBalanceHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &unfreezeFinancialBalanceReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *unfreezeFinancialBalanceRes
+	res, st.Err = unfreezeFinancialBalance(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type unfreezeFinancialBalanceReq struct {
+	ID     [32]byte `json:",string"`
+	CaseID [32]byte `json:",string"` // Must be same as the case that order the freeze
+}
+
+type unfreezeFinancialBalanceRes struct {
+}
+
+func unfreezeFinancialBalance(st *achaemenid.Stream, req *unfreezeFinancialBalanceReq) (res *unfreezeFinancialBalanceRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	if st.Connection.UserID != justiceUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	var ff = datastore.FinancialFreeze{
+		ID: req.ID,
+	}
+	err = ff.GetLastByID()
+	if err != nil {
+		return
+	}
+	if ff.CaseID != req.CaseID {
+		err = ErrFinancialFreezeNoCase
+		return
+	}
+
+	switch ff.Status {
+	case datastore.FinancialFreezeFrozen:
+		ff.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+		ff.Status = datastore.FinancialFreezeUnfrozen
+		err = ff.Set()
+		if err != nil {
+			return
+		}
+		ff.IndexRecordIDForID()
+	case datastore.FinancialFreezeUnfrozen:
+		// Unfrozen before. Deposit again to finish half-done ones, leg write once.
+	default:
+		err = ErrFinancialFreezeBadStatus
+		return
+	}
+
+	err = depositFinancialFreeze(&ff)
+	if err != nil {
+		return
+	}
+
+	res = &unfreezeFinancialBalanceRes{}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *unfreezeFinancialBalanceReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	copy(req.CaseID[:], buf[32:])
+	return
+}
+
+func (req *unfreezeFinancialBalanceReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.ID[:])
+	copy(buf[32:], req.CaseID[:])
+	return
+}
+
+func (req *unfreezeFinancialBalanceReq) syllabStackLen() (ln uint32) {
+	return 64
+}
+
+func (req *unfreezeFinancialBalanceReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *unfreezeFinancialBalanceReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *unfreezeFinancialBalanceReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *unfreezeFinancialBalanceReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *unfreezeFinancialBalanceReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *unfreezeFinancialBalanceRes) syllabDecoder(buf []byte) (err *er.Error) {
+	return
+}
+
+func (res *unfreezeFinancialBalanceRes) syllabEncoder(buf []byte) {
+	return
+}
+
+func (res *unfreezeFinancialBalanceRes) syllabStackLen() (ln uint32) {
+	return 0
+}
+
+func (res *unfreezeFinancialBalanceRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *unfreezeFinancialBalanceRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *unfreezeFinancialBalanceRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *unfreezeFinancialBalanceRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *unfreezeFinancialBalanceRes) jsonLen() (ln int) {
+	return
+}
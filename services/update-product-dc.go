@@ -11,6 +11,8 @@ import (
 	"../libgo/json"
 	lang "../libgo/language"
 	"../libgo/srpc"
+	"../libgo/syllab"
+	"../libgo/uuid"
 )
 
 var updateProductDcService = achaemenid.Service{
@@ -23,15 +25,16 @@ var updateProductDcService = achaemenid.Service{
 	Status:            achaemenid.ServiceStatePreAlpha,
 
 	Authorization: authorization.Service{
-		CRUD:     authorization.CRUDNone,
-		UserType: authorization.UserTypeAll,
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
 	},
 
 	Name: map[lang.Language]string{
 		lang.LanguageEnglish: "Update Product Dc",
 	},
 	Description: map[lang.Language]string{
-		lang.LanguageEnglish: "",
+		lang.LanguageEnglish: `dispatch a product from its DC to other DC. Product stay in transit until receiving DC acknowledge it
+by AcknowledgeProductDc service.`,
 	},
 	TAGS: []string{
 		"Product",
@@ -78,10 +81,8 @@ func UpdateProductDcHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *
 }
 
 type updateProductDcReq struct {
-	ID               [32]byte `json:",string"`
-	DCID             [32]byte `json:",string"`
-	SellerID         [32]byte `json:",string"`
-	ProductAuctionID [32]byte `json:",string"`
+	ID   [32]byte `json:",string"`
+	DCID [32]byte `json:",string"` // Receiving DC
 }
 
 func updateProductDc(st *achaemenid.Stream, req *updateProductDcReq) (err *er.Error) {
@@ -90,15 +91,82 @@ func updateProductDc(st *achaemenid.Stream, req *updateProductDcReq) (err *er.Er
 		return
 	}
 
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
 	var p = datastore.Product{
 		ID: req.ID,
 	}
-	p.GetLastByID()
+	err = p.GetLastByID()
+	if err != nil {
+		return
+	}
+
+	// Just DC that hold the product can dispatch it.
+	if p.DCID != st.Connection.UserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+	switch p.Status {
+	case datastore.ProductVoid:
+		err = ErrProductVoid
+		return
+	case datastore.ProductInTransit:
+		err = ErrProductInTransit
+		return
+	}
+	if p.DCID == req.DCID {
+		err = ErrProductSameDC
+		return
+	}
+	// Buyer paid for the product in this DC, so it can't leave the DC until the escrow decide.
+	var held bool
+	held, err = isFinancialEscrowHeld(p.ID)
+	if err != nil {
+		return
+	}
+	if held {
+		err = ErrProductEscrowHeld
+		return
+	}
 
-	// TODO::: Service logic: Authenticate & Authorizing request first by service policy.
+	var pt = datastore.ProductTransit{
+		AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+		UserConnectionID: st.Connection.ID,
+		ID:               uuid.Random32Byte(),
+		ProductID:        p.ID,
+		FromDCID:         p.DCID,
+		ToDCID:           req.DCID,
+		Status:           datastore.ProductTransitDispatched,
+		ProductStatus:    p.Status,
+	}
+	err = pt.SaveNew()
+	if err != nil {
+		return
+	}
 
-	// TODO::: Remove me and write some code e.g. save a record and related indexes if you need!
+	var before = p
+	p.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	p.UserConnectionID = st.Connection.ID
+	p.Status = datastore.ProductInTransit
+	err = p.Set()
+	if err != nil {
+		return
+	}
+	p.IndexRecordIDForID()
+	// Product leave sending DC stock until receiving DC acknowledge it.
+	p.DeleteTempIndexIDForQuiddityIDDCID()
+	moveProductStock(&before, &p)
+	return
+}
 
+func (req *updateProductDcReq) validator() (err *er.Error) {
+	if req.DCID == [32]byte{} {
+		err = ErrProductSameDC
+	}
 	return
 }
 
@@ -107,20 +175,27 @@ func updateProductDc(st *achaemenid.Stream, req *updateProductDcReq) (err *er.Er
 */
 
 func (req *updateProductDcReq) syllabDecoder(buf []byte) (err *er.Error) {
-	// TODO::: Use syllab generator to generate needed codes
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	copy(req.DCID[:], buf[32:])
 	return
 }
 
 func (req *updateProductDcReq) syllabEncoder(buf []byte) {
-	// TODO::: Use syllab generator to generate needed codes
+	copy(buf[0:], req.ID[:])
+	copy(buf[32:], req.DCID[:])
+	return
 }
 
 func (req *updateProductDcReq) syllabStackLen() (ln uint32) {
-	return 0 // fixed size data + variables data add&&len
+	return 64
 }
 
 func (req *updateProductDcReq) syllabHeapLen() (ln uint32) {
-	// TODO::: Use syllab generator to generate needed codes
 	return
 }
 
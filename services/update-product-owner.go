@@ -11,6 +11,7 @@ import (
 	"../libgo/json"
 	lang "../libgo/language"
 	"../libgo/srpc"
+	"../libgo/syllab"
 )
 
 var updateProductOwnerService = achaemenid.Service{
@@ -23,18 +24,19 @@ var updateProductOwnerService = achaemenid.Service{
 	Status:            achaemenid.ServiceStatePreAlpha,
 
 	Authorization: authorization.Service{
-		CRUD:     authorization.CRUDNone,
-		UserType: authorization.UserTypeAll,
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll ^ authorization.UserTypeGuest,
 	},
 
 	Name: map[lang.Language]string{
 		lang.LanguageEnglish: "Update Product Owner",
 	},
 	Description: map[lang.Language]string{
-		lang.LanguageEnglish: "",
+		lang.LanguageEnglish: `give away or resell a product to other user without any auction.
+Just product owner or its allowed delegate can change the owner.`,
 	},
 	TAGS: []string{
-		"",
+		"Product",
 	},
 
 	SRPCHandler: UpdateProductOwnerSRPC,
@@ -78,10 +80,8 @@ func UpdateProductOwnerHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRe
 }
 
 type updateProductOwnerReq struct {
-	ID               [32]byte `json:",string"`
-	DCID             [32]byte `json:",string"`
-	SellerID         [32]byte `json:",string"`
-	ProductAuctionID [32]byte `json:",string"`
+	ID      [32]byte `json:",string"`
+	OwnerID [32]byte `json:",string"` // New owner
 }
 
 func updateProductOwner(st *achaemenid.Stream, req *updateProductOwnerReq) (err *er.Error) {
@@ -90,15 +90,60 @@ func updateProductOwner(st *achaemenid.Stream, req *updateProductOwnerReq) (err
 		return
 	}
 
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
 	var p = datastore.Product{
 		ID: req.ID,
 	}
-	p.GetLastByID()
+	err = p.GetLastByID()
+	if err != nil {
+		return
+	}
 
-	// TODO::: Service logic: Authenticate & Authorizing request first by service policy.
+	err = checkProductChangeable(st, &p)
+	if err != nil {
+		return
+	}
+	if p.OwnerID == req.OwnerID {
+		err = ErrProductSameOwner
+		return
+	}
+	err = checkProductOwner(req.OwnerID)
+	if err != nil {
+		return
+	}
 
-	// TODO::: Remove me and write some code e.g. save a record and related indexes if you need!
+	var before = p
+	p.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+	p.UserConnectionID = st.Connection.ID
+	changeProductOwner(&p, req.OwnerID)
+	err = p.Set()
+	if err != nil {
+		return
+	}
+	p.IndexRecordIDForID()
+	p.IndexIDForOwnerIDDaily()
+	moveProductStock(&before, &p)
+	return
+}
 
+// changeProductOwner give the product to new owner without any sale.
+func changeProductOwner(p *datastore.Product, ownerID [32]byte) {
+	p.OwnerID = ownerID
+	// Sales by seller or auction register just by invoice, so clear them from last sale.
+	p.SellerID = [32]byte{}
+	p.ProductAuctionID = [32]byte{}
+	p.Status = datastore.ProductChangeOwner
+}
+
+func (req *updateProductOwnerReq) validator() (err *er.Error) {
+	if req.OwnerID == [32]byte{} {
+		err = ErrProductSameOwner
+	}
 	return
 }
 
@@ -107,20 +152,27 @@ func updateProductOwner(st *achaemenid.Stream, req *updateProductOwnerReq) (err
 */
 
 func (req *updateProductOwnerReq) syllabDecoder(buf []byte) (err *er.Error) {
-	// TODO::: Use syllab generator to generate needed codes
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.ID[:], buf[0:])
+	copy(req.OwnerID[:], buf[32:])
 	return
 }
 
 func (req *updateProductOwnerReq) syllabEncoder(buf []byte) {
-	// TODO::: Use syllab generator to generate needed codes
+	copy(buf[0:], req.ID[:])
+	copy(buf[32:], req.OwnerID[:])
+	return
 }
 
 func (req *updateProductOwnerReq) syllabStackLen() (ln uint32) {
-	return 0 // fixed size data + variables data add&&len
+	return 64
 }
 
 func (req *updateProductOwnerReq) syllabHeapLen() (ln uint32) {
-	// TODO::: Use syllab generator to generate needed codes
 	return
 }
 
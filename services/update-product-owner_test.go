@@ -0,0 +1,42 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"testing"
+
+	"../datastore"
+)
+
+func TestChangeProductOwner(t *testing.T) {
+	var owner = [32]byte{9}
+	var tests = []struct {
+		name string
+		p    datastore.Product
+	}{
+		{"on hand", datastore.Product{OwnerID: [32]byte{1}, DCID: [32]byte{2}, Status: datastore.ProductChangeDC, Quantity: 5}},
+		{"sold by seller", datastore.Product{OwnerID: [32]byte{1}, DCID: [32]byte{2}, SellerID: [32]byte{3},
+			Status: datastore.ProductChangeOwner}},
+		{"sold by auction", datastore.Product{OwnerID: [32]byte{1}, DCID: [32]byte{2}, ProductAuctionID: [32]byte{4},
+			Status: datastore.ProductChangeOwner, Quantity: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p = tt.p
+			changeProductOwner(&p, owner)
+			if p.OwnerID != owner || p.Status != datastore.ProductChangeOwner {
+				t.Errorf("changeProductOwner() owner, status = %v, %v, want %v, %v", p.OwnerID, p.Status, owner, datastore.ProductChangeOwner)
+			}
+			if p.SellerID != [32]byte{} || p.ProductAuctionID != [32]byte{} {
+				t.Errorf("changeProductOwner() must clear last sale, got seller %v, auction %v", p.SellerID, p.ProductAuctionID)
+			}
+			if p.DCID != tt.p.DCID || p.Quantity != tt.p.Quantity {
+				t.Errorf("changeProductOwner() DC, quantity = %v, %v, want %v, %v", p.DCID, p.Quantity, tt.p.DCID, tt.p.Quantity)
+			}
+			// Given away product stay in its DC stock even if last owner bought it by an auction.
+			if bucket := getProductStockBucket(&p); bucket != productStockOnHand {
+				t.Errorf("getProductStockBucket() = %v, want %v", bucket, productStockOnHand)
+			}
+		})
+	}
+}
@@ -131,6 +131,7 @@ func updateQuiddity(st *achaemenid.Stream, req *updateQuiddityReq) (err *er.Erro
 		URI:      req.URI,
 		Title:    req.Title,
 		Status:   datastore.QuiddityStatusRegister,
+		Unit:     q.Unit,
 	}
 	err = q.Set()
 	if err != nil {
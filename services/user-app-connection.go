@@ -0,0 +1,59 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+)
+
+// userAppConnectionDelegatesPage is number of connections that a user gave to other user read in each page to check for delegation.
+const userAppConnectionDelegatesPage = 64
+
+// isActiveDelegate report if user gave an active and not expired delegate connection to the delegate user e.g. group membership or org staff.
+func isActiveDelegate(userID, delegateUserID [32]byte) (active bool, err *er.Error) {
+	var uac = datastore.UserAppConnection{
+		UserID:         userID,
+		DelegateUserID: delegateUserID,
+	}
+	var now = etime.Now()
+	var offset uint64
+	for {
+		var IDs [][32]byte
+		IDs, err = uac.FindIDsByUserIDDelegateUserID(offset, userAppConnectionDelegatesPage)
+		if err.Equal(ganjine.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return
+		}
+
+		for _, id := range IDs {
+			var conn = datastore.UserAppConnection{
+				ID: id,
+			}
+			err = conn.GetLastByID()
+			if err != nil {
+				return
+			}
+			if isActiveUserAppConnection(&conn, now) {
+				return true, nil
+			}
+		}
+		if uint64(len(IDs)) < userAppConnectionDelegatesPage {
+			return
+		}
+		offset += userAppConnectionDelegatesPage
+	}
+}
+
+// isActiveUserAppConnection report if connection status is active and not expired in given time.
+func isActiveUserAppConnection(uac *datastore.UserAppConnection, now etime.Time) bool {
+	switch uac.Status {
+	case datastore.UserAppConnectionIssued, datastore.UserAppConnectionUpdate:
+		return uac.ExpireTime == 0 || !uac.ExpireTime.Pass(now)
+	}
+	return false
+}
@@ -0,0 +1,383 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	etime "../libgo/earth-time"
+	er "../libgo/error"
+	"../libgo/ganjine"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/price"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var verifyFinancialTransactionChainService = achaemenid.Service{
+	ID:                3379485258,
+	IssueDate:         1792293747,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDRead,
+		UserType: authorization.UserTypePerson,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Verify Financial Transaction Chain",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `verify financial transactions chain of a user or sweep all users that have transaction in the period
+and make reconciliation report. Just platform admin can call this service.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: VerifyFinancialTransactionChainSRPC,
+	HTTPHandler: VerifyFinancialTransactionChainHTTP,
+}
+
+// VerifyFinancialTransactionChainSRPC is sRPC handler of VerifyFinancialTransactionChain service.
+func VerifyFinancialTransactionChainSRPC(st *achaemenid.Stream) {
+	var req = &verifyFinancialTransactionChainReq{}
+	st.Err = req.syllabDecoder(srpc.GetPayload(st.IncomePayload))
+	if st.Err != nil {
+		return
+	}
+
+	var res *verifyFinancialTransactionChainRes
+	res, st.Err = verifyFinancialTransactionChain(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// VerifyFinancialTransactionChainHTTP is HTTP handler of VerifyFinancialTransactionChain service.
+func VerifyFinancialTransactionChainHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var req = &verifyFinancialTransactionChainReq{}
+	st.Err = req.jsonDecoder(httpReq.Body)
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	var res *verifyFinancialTransactionChainRes
+	res, st.Err = verifyFinancialTransactionChain(st, req)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type verifyFinancialTransactionChainReq struct {
+	UserID    [32]byte   `json:",string,optional"` // Empty UserID means sweep all users that have transaction in the period!
+	WriteTime etime.Time // Last day of the period
+	Days      uint16     `valid:"Days[0:366]"` // Number of days before WriteTime to verify
+	Offset    uint64     // Sweep mode: users offset
+	Limit     uint64     `valid:"Limit[1:1000]"` // Sweep mode: max users to verify
+	Currency  uint16     `json:",optional"`      // Chain currency. Zero is society default currency
+}
+
+type verifyFinancialTransactionChainRes struct {
+	Reports      []datastore.FinancialTransactionChainReport
+	Users        uint64       // Number of verified users
+	Broken       uint64       // Number of users with broken chain
+	TotalBalance price.Amount // Sum of last healthy balance of verified users
+}
+
+func verifyFinancialTransactionChain(st *achaemenid.Stream, req *verifyFinancialTransactionChainReq) (res *verifyFinancialTransactionChainRes, err *er.Error) {
+	if st.Connection.UserID != adminUserID {
+		err = authorization.ErrUserNotAllow
+		return
+	}
+
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+	// Validate data here due to service use internally by other services!
+	err = req.validator()
+	if err != nil {
+		return
+	}
+
+	var userIDs [][32]byte
+	if req.UserID != [32]byte{} {
+		userIDs = [][32]byte{req.UserID}
+	} else {
+		userIDs, err = findFinancialTransactionUsers(req.WriteTime, req.Days, req.Offset, req.Limit)
+		if err != nil {
+			return
+		}
+	}
+
+	res = &verifyFinancialTransactionChainRes{
+		Reports: make([]datastore.FinancialTransactionChainReport, 0, len(userIDs)),
+	}
+	for _, userID := range userIDs {
+		var ft = datastore.FinancialTransaction{
+			UserID:    userID,
+			WriteTime: req.WriteTime,
+			Currency:  req.Currency,
+		}
+		var report datastore.FinancialTransactionChainReport
+		report, err = ft.VerifyChain(req.Days)
+		if err != nil {
+			return
+		}
+
+		res.Reports = append(res.Reports, report)
+		res.Users++
+		res.TotalBalance += report.Balance
+		if report.Problem != datastore.FinancialTransactionChainHealthy {
+			res.Broken++
+		}
+	}
+	return
+}
+
+// findFinancialTransactionUsers return distinct users that have transaction in (writeTime - days) to writeTime period.
+func findFinancialTransactionUsers(writeTime etime.Time, days uint16, offset, limit uint64) (userIDs [][32]byte, err *er.Error) {
+	const pageLimit = 100
+
+	var seen = make(map[[32]byte]struct{})
+	var skipped uint64
+	var ft = datastore.FinancialTransaction{
+		WriteTime: writeTime - etime.Time(days)*(24*60*60),
+	}
+	for i := uint16(0); i <= days; i++ {
+		var pageOffset uint64
+		for {
+			var IDs [][32]byte
+			IDs, err = ft.FindUserIDsByWriteTimeDaily(pageOffset, pageLimit)
+			if err.Equal(ganjine.ErrRecordNotFound) {
+				err = nil
+				break
+			}
+			if err != nil {
+				return
+			}
+
+			for _, userID := range IDs {
+				if _, ok := seen[userID]; ok {
+					continue
+				}
+				seen[userID] = struct{}{}
+				if skipped < offset {
+					skipped++
+					continue
+				}
+				userIDs = append(userIDs, userID)
+				if uint64(len(userIDs)) == limit {
+					return
+				}
+			}
+
+			if len(IDs) < pageLimit {
+				break
+			}
+			pageOffset += pageLimit
+		}
+		ft.WriteTime += (24 * 60 * 60)
+	}
+	return
+}
+
+func (req *verifyFinancialTransactionChainReq) validator() (err *er.Error) {
+	if req.Days > 366 {
+		err = ErrFinancialTransactionBadPeriod
+		return
+	}
+	if req.WriteTime == 0 {
+		req.WriteTime = etime.Now()
+	}
+	if req.Limit == 0 || req.Limit > 1000 {
+		req.Limit = 1000
+	}
+	return
+}
+
+/*
+	Request Encoders & Decoders
+*/
+
+func (req *verifyFinancialTransactionChainReq) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < req.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(req.UserID[:], buf[0:])
+	req.WriteTime = etime.Time(syllab.GetInt64(buf, 32))
+	req.Days = syllab.GetUInt16(buf, 40)
+	req.Offset = syllab.GetUInt64(buf, 42)
+	req.Limit = syllab.GetUInt64(buf, 50)
+	req.Currency = syllab.GetUInt16(buf, 58)
+	return
+}
+
+func (req *verifyFinancialTransactionChainReq) syllabEncoder(buf []byte) {
+	copy(buf[0:], req.UserID[:])
+	syllab.SetInt64(buf, 32, int64(req.WriteTime))
+	syllab.SetUInt16(buf, 40, req.Days)
+	syllab.SetUInt64(buf, 42, req.Offset)
+	syllab.SetUInt64(buf, 50, req.Limit)
+	syllab.SetUInt16(buf, 58, req.Currency)
+	return
+}
+
+func (req *verifyFinancialTransactionChainReq) syllabStackLen() (ln uint32) {
+	return 60
+}
+
+func (req *verifyFinancialTransactionChainReq) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (req *verifyFinancialTransactionChainReq) syllabLen() (ln int) {
+	return int(req.syllabStackLen() + req.syllabHeapLen())
+}
+
+func (req *verifyFinancialTransactionChainReq) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, req)
+	return
+}
+
+func (req *verifyFinancialTransactionChainReq) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(req)
+	return
+}
+
+func (req *verifyFinancialTransactionChainReq) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *verifyFinancialTransactionChainRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	res.Reports, err = decodeFinancialTransactionChainReports(buf, 0)
+	if err != nil {
+		return
+	}
+	res.Users = syllab.GetUInt64(buf, 8)
+	res.Broken = syllab.GetUInt64(buf, 16)
+	res.TotalBalance = price.Amount(syllab.GetInt64(buf, 24))
+	return
+}
+
+func (res *verifyFinancialTransactionChainRes) syllabEncoder(buf []byte) {
+	var hsi uint32 = res.syllabStackLen() // Heap start index || Stack size!
+
+	encodeFinancialTransactionChainReports(buf, res.Reports, 0, hsi)
+	syllab.SetUInt64(buf, 8, res.Users)
+	syllab.SetUInt64(buf, 16, res.Broken)
+	syllab.SetInt64(buf, 24, int64(res.TotalBalance))
+	return
+}
+
+func (res *verifyFinancialTransactionChainRes) syllabStackLen() (ln uint32) {
+	return 32 // fixed size data + variables data add&&len
+}
+
+func (res *verifyFinancialTransactionChainRes) syllabHeapLen() (ln uint32) {
+	ln += uint32(len(res.Reports)) * financialTransactionChainReportSyllabLen
+	return
+}
+
+func (res *verifyFinancialTransactionChainRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *verifyFinancialTransactionChainRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *verifyFinancialTransactionChainRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *verifyFinancialTransactionChainRes) jsonLen() (ln int) {
+	return
+}
+
+/*
+	Report Encoders & Decoders
+*/
+
+// financialTransactionChainReportSyllabLen is fixed size of each report in heap.
+const financialTransactionChainReportSyllabLen uint32 = 155
+
+// decodeFinancialTransactionChainReports decode reports slice that its add&&len store in given stack index.
+func decodeFinancialTransactionChainReports(buf []byte, stackIndex uint32) (reports []datastore.FinancialTransactionChainReport, err *er.Error) {
+	var add uint32 = syllab.GetUInt32(buf, stackIndex)
+	var ln uint32 = syllab.GetUInt32(buf, stackIndex+4)
+	if uint64(add)+uint64(ln)*uint64(financialTransactionChainReportSyllabLen) > uint64(len(buf)) {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	reports = make([]datastore.FinancialTransactionChainReport, ln)
+	for i := range reports {
+		var report = &reports[i]
+		var dbuf = buf[add+uint32(i)*financialTransactionChainReportSyllabLen:]
+		copy(report.UserID[:], dbuf[0:])
+		report.Currency = syllab.GetUInt16(dbuf, 32)
+		copy(report.FirstRecordID[:], dbuf[34:])
+		copy(report.LastRecordID[:], dbuf[66:])
+		report.Checked = syllab.GetUInt64(dbuf, 98)
+		report.OpeningBalance = price.Amount(syllab.GetInt64(dbuf, 106))
+		report.Balance = price.Amount(syllab.GetInt64(dbuf, 114))
+		copy(report.BrokenRecordID[:], dbuf[122:])
+		report.Problem = datastore.FinancialTransactionChainProblem(syllab.GetUInt8(dbuf, 154))
+	}
+	return
+}
+
+// encodeFinancialTransactionChainReports encode reports in heap from given heap index and its add&&len in given stack index.
+func encodeFinancialTransactionChainReports(buf []byte, reports []datastore.FinancialTransactionChainReport, stackIndex, hsi uint32) {
+	syllab.SetUInt32(buf, stackIndex, hsi)
+	syllab.SetUInt32(buf, stackIndex+4, uint32(len(reports)))
+	for i := range reports {
+		var report = &reports[i]
+		var dbuf = buf[hsi+uint32(i)*financialTransactionChainReportSyllabLen:]
+		copy(dbuf[0:], report.UserID[:])
+		syllab.SetUInt16(dbuf, 32, report.Currency)
+		copy(dbuf[34:], report.FirstRecordID[:])
+		copy(dbuf[66:], report.LastRecordID[:])
+		syllab.SetUInt64(dbuf, 98, report.Checked)
+		syllab.SetInt64(dbuf, 106, int64(report.OpeningBalance))
+		syllab.SetInt64(dbuf, 114, int64(report.Balance))
+		copy(dbuf[122:], report.BrokenRecordID[:])
+		syllab.SetUInt8(dbuf, 154, uint8(report.Problem))
+	}
+}
@@ -0,0 +1,200 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"strconv"
+
+	"../datastore"
+	"../libgo/achaemenid"
+	"../libgo/authorization"
+	er "../libgo/error"
+	"../libgo/http"
+	"../libgo/json"
+	lang "../libgo/language"
+	"../libgo/srpc"
+	"../libgo/syllab"
+)
+
+var verifyFinancialWebPaymentService = achaemenid.Service{
+	ID:                1490659082,
+	IssueDate:         1792293860,
+	ExpiryDate:        0,
+	ExpireInFavorOf:   "", // English name of favor service just to show off!
+	ExpireInFavorOfID: 0,
+	Status:            achaemenid.ServiceStatePreAlpha,
+
+	Authorization: authorization.Service{
+		CRUD:     authorization.CRUDUpdate,
+		UserType: authorization.UserTypeAll,
+	},
+
+	Name: map[lang.Language]string{
+		lang.LanguageEnglish: "Verify Financial Web Payment",
+	},
+	Description: map[lang.Language]string{
+		lang.LanguageEnglish: `callback of web payment gateways. It check gateway signature and register FinancialTransaction
+just if gateway confirm the payment. Gateways can call it more than once for same payment.`,
+	},
+	TAGS: []string{
+		"FinancialTransaction",
+	},
+
+	SRPCHandler: VerifyFinancialWebPaymentSRPC,
+	HTTPHandler: VerifyFinancialWebPaymentHTTP,
+}
+
+// VerifyFinancialWebPaymentSRPC is sRPC handler of VerifyFinancialWebPayment service.
+func VerifyFinancialWebPaymentSRPC(st *achaemenid.Stream) {
+	var res *verifyFinancialWebPaymentRes
+	res, st.Err = verifyFinancialWebPayment(st, srpc.GetPayload(st.IncomePayload))
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		return
+	}
+
+	st.OutcomePayload = make([]byte, res.syllabLen()+4)
+	res.syllabEncoder(srpc.GetPayload(st.OutcomePayload))
+}
+
+// VerifyFinancialWebPaymentHTTP is HTTP handler of VerifyFinancialWebPayment service.
+// Gateways send their own callback format, so body pass as is to gateway to decode and verify it.
+func VerifyFinancialWebPaymentHTTP(st *achaemenid.Stream, httpReq *http.Request, httpRes *http.Response) {
+	var res *verifyFinancialWebPaymentRes
+	res, st.Err = verifyFinancialWebPayment(st, httpReq.Body)
+	// Check if any error occur in bussiness logic
+	if st.Err != nil {
+		httpRes.SetStatus(http.StatusBadRequestCode, http.StatusBadRequestPhrase)
+		return
+	}
+
+	httpRes.SetStatus(http.StatusOKCode, http.StatusOKPhrase)
+	httpRes.Header.Set(http.HeaderKeyContentType, "application/json")
+	httpRes.Body = res.jsonEncoder()
+}
+
+type verifyFinancialWebPaymentRes struct {
+	ID            [32]byte `json:",string"`
+	TransactionID [32]byte `json:",string"`
+	Status        datastore.FinancialWebPaymentStatus
+}
+
+func verifyFinancialWebPayment(st *achaemenid.Stream, payload []byte) (res *verifyFinancialWebPaymentRes, err *er.Error) {
+	err = st.Authorize()
+	if err != nil {
+		return
+	}
+
+	var cb webPaymentCallback
+	var gatewayID byte
+	cb, gatewayID, err = verifyWebPaymentCallback(payload)
+	if err != nil {
+		return
+	}
+
+	var fwp = datastore.FinancialWebPayment{
+		ID: cb.PaymentID,
+	}
+	err = fwp.GetLastByID()
+	if err != nil {
+		return
+	}
+
+	var status datastore.FinancialWebPaymentStatus
+	status, err = decideFinancialWebPayment(&fwp, gatewayID, &cb)
+	if err != nil {
+		return
+	}
+	if status != fwp.Status {
+		fwp.AppInstanceID = achaemenid.Server.Nodes.LocalNode.InstanceID
+		fwp.UserConnectionID = st.Connection.ID
+		fwp.GatewayReference = cb.Reference
+
+		if status == datastore.FinancialWebPaymentVerified {
+			var ft = datastore.FinancialTransaction{
+				AppInstanceID:    achaemenid.Server.Nodes.LocalNode.InstanceID,
+				UserConnectionID: st.Connection.ID,
+				UserID:           fwp.UserID,
+				ReferenceID:      fwp.ID,
+				ReferenceType:    datastore.FinancialTransactionWebTransfer,
+				Amount:           fwp.Amount,
+			}
+			// Gateway will call again if we return error here, so payment stay pending!
+			err = saveFinancialTransactionOnce(&ft)
+			if err != nil {
+				return
+			}
+			fwp.TransactionID = ft.RecordID
+		}
+		fwp.Status = status
+
+		err = fwp.Set()
+		if err != nil {
+			return
+		}
+		fwp.IndexRecordIDForID()
+	}
+
+	res = &verifyFinancialWebPaymentRes{
+		ID:            fwp.ID,
+		TransactionID: fwp.TransactionID,
+		Status:        fwp.Status,
+	}
+	return
+}
+
+// webPaymentCallbackURL return URL that gateways must call after user pay||cancel the payment.
+func webPaymentCallbackURL() string {
+	return "https://" + achaemenid.Server.Manifest.DomainName + "/apis?" + strconv.FormatUint(uint64(verifyFinancialWebPaymentService.ID), 10)
+}
+
+/*
+	Response Encoders & Decoders
+*/
+
+func (res *verifyFinancialWebPaymentRes) syllabDecoder(buf []byte) (err *er.Error) {
+	if uint32(len(buf)) < res.syllabStackLen() {
+		err = syllab.ErrSyllabDecodeSmallSlice
+		return
+	}
+
+	copy(res.ID[:], buf[0:])
+	copy(res.TransactionID[:], buf[32:])
+	res.Status = datastore.FinancialWebPaymentStatus(syllab.GetUInt8(buf, 64))
+	return
+}
+
+func (res *verifyFinancialWebPaymentRes) syllabEncoder(buf []byte) {
+	copy(buf[0:], res.ID[:])
+	copy(buf[32:], res.TransactionID[:])
+	syllab.SetUInt8(buf, 64, uint8(res.Status))
+	return
+}
+
+func (res *verifyFinancialWebPaymentRes) syllabStackLen() (ln uint32) {
+	return 65
+}
+
+func (res *verifyFinancialWebPaymentRes) syllabHeapLen() (ln uint32) {
+	return
+}
+
+func (res *verifyFinancialWebPaymentRes) syllabLen() (ln int) {
+	return int(res.syllabStackLen() + res.syllabHeapLen())
+}
+
+func (res *verifyFinancialWebPaymentRes) jsonDecoder(buf []byte) (err *er.Error) {
+	// TODO::: Use json generator to have better performance!
+	err = json.UnMarshal(buf, res)
+	return
+}
+
+func (res *verifyFinancialWebPaymentRes) jsonEncoder() (buf []byte) {
+	// TODO::: Use json generator to have better performance!
+	buf, _ = json.Marshal(res)
+	return
+}
+
+func (res *verifyFinancialWebPaymentRes) jsonLen() (ln int) {
+	return
+}
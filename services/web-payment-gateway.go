@@ -0,0 +1,136 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+
+	"../datastore"
+	er "../libgo/error"
+	"../libgo/json"
+	"../libgo/price"
+	"../libgo/syllab"
+)
+
+// webPaymentGateway is the interface that each web payment provider must implement.
+// Implementations register in webPaymentGateways by second byte of PosID e.g. PosID "10" use webPaymentGateways['0'].
+type webPaymentGateway interface {
+	// RequestPayment register the payment in gateway and return URL that user must redirect to pay the payment.
+	RequestPayment(fwp *datastore.FinancialWebPayment, callbackURL string) (redirectURL string, err *er.Error)
+	// VerifyCallback check gateway signature on callback payload and return the callback data.
+	VerifyCallback(payload []byte) (cb webPaymentCallback, err *er.Error)
+}
+
+// webPaymentCallback is data that gateway send to callback service after user pay||cancel the payment.
+type webPaymentCallback struct {
+	PaymentID [32]byte `json:",string"`
+	Reference [32]byte `json:",string"` // Gateway tracking number
+	Amount    int64
+	Succeed   bool
+	Signature string // Hex encoded HMAC-SHA256 of above fields
+}
+
+var webPaymentGateways = map[byte]webPaymentGateway{}
+
+// verifyWebPaymentCallback find the gateway that signed the callback payload and return the callback data.
+func verifyWebPaymentCallback(payload []byte) (cb webPaymentCallback, gatewayID byte, err *er.Error) {
+	err = ErrWebPaymentBadSignature
+	for id, gw := range webPaymentGateways {
+		cb, err = gw.VerifyCallback(payload)
+		if err == nil {
+			return cb, id, nil
+		}
+	}
+	return
+}
+
+// decideFinancialWebPayment return status of the payment by verified callback of given gateway.
+// Just pending payments can decide, so gateway can call again for same payment without any change.
+func decideFinancialWebPayment(fwp *datastore.FinancialWebPayment, gatewayID byte, cb *webPaymentCallback) (status datastore.FinancialWebPaymentStatus, err *er.Error) {
+	if fwp.GatewayID != gatewayID || fwp.ID != cb.PaymentID {
+		return fwp.Status, ErrWebPaymentBadSignature
+	}
+	if fwp.Status != datastore.FinancialWebPaymentPending {
+		return fwp.Status, nil
+	}
+	if cb.Succeed && price.Amount(cb.Amount) == fwp.Amount {
+		return datastore.FinancialWebPaymentVerified, nil
+	}
+	return datastore.FinancialWebPaymentFailed, nil
+}
+
+// hmacWebPaymentGateway is a generic gateway that sign requests and callbacks by HMAC-SHA256 with a shared key.
+type hmacWebPaymentGateway struct {
+	GatewayURL string
+	MerchantID string
+	SecretKey  string // Base64 encoded shared key
+	key        []byte
+}
+
+// Init parse gateway config from given json e.g. secret/web-payment-gateway.json
+func (gw *hmacWebPaymentGateway) Init(configJSON []byte) (err *er.Error) {
+	err = json.UnMarshal(configJSON, gw)
+	if err != nil {
+		return
+	}
+
+	var goErr error
+	gw.key, goErr = base64.StdEncoding.DecodeString(gw.SecretKey)
+	if goErr != nil || len(gw.key) == 0 {
+		err = ErrWebPaymentGatewayConfig
+	}
+	return
+}
+
+// RequestPayment make signed redirect URL. The gateway register the payment when user open the URL.
+func (gw *hmacWebPaymentGateway) RequestPayment(fwp *datastore.FinancialWebPayment, callbackURL string) (redirectURL string, err *er.Error) {
+	var query = url.Values{}
+	query.Set("MerchantID", gw.MerchantID)
+	query.Set("PaymentID", base64.RawURLEncoding.EncodeToString(fwp.ID[:]))
+	query.Set("Amount", strconv.FormatInt(int64(fwp.Amount), 10))
+	query.Set("CallbackURL", callbackURL)
+	query.Set("Signature", hex.EncodeToString(gw.sign(webPaymentSignRequest, fwp.ID, [32]byte{}, int64(fwp.Amount), false)))
+	redirectURL = gw.GatewayURL + "?" + query.Encode()
+	return
+}
+
+// VerifyCallback decode callback payload as json and check its signature.
+func (gw *hmacWebPaymentGateway) VerifyCallback(payload []byte) (cb webPaymentCallback, err *er.Error) {
+	err = json.UnMarshal(payload, &cb)
+	if err != nil {
+		return
+	}
+
+	var signature, goErr = hex.DecodeString(cb.Signature)
+	if goErr != nil || !hmac.Equal(signature, gw.sign(webPaymentSignCallback, cb.PaymentID, cb.Reference, cb.Amount, cb.Succeed)) {
+		err = ErrWebPaymentBadSignature
+	}
+	return
+}
+
+// Signed message kinds. Each kind prefix its message, so a request signature can't replay as a callback signature.
+const (
+	webPaymentSignRequest  = "Request"
+	webPaymentSignCallback = "Callback"
+)
+
+func (gw *hmacWebPaymentGateway) sign(kind string, paymentID, reference [32]byte, amount int64, succeed bool) []byte {
+	var ln = uint32(len(kind))
+	var buf = make([]byte, ln+73) // kind+32+32+8+1
+	copy(buf[0:], kind)
+	copy(buf[ln:], paymentID[:])
+	copy(buf[ln+32:], reference[:])
+	syllab.SetInt64(buf, ln+64, amount)
+	if succeed {
+		buf[ln+72] = 1
+	}
+
+	var mac = hmac.New(sha256.New, gw.key)
+	mac.Write(buf)
+	return mac.Sum(nil)
+}
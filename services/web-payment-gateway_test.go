@@ -0,0 +1,139 @@
+/* For license and copyright information please see LEGAL file in repository */
+
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"../datastore"
+)
+
+// fakeWebPaymentGateway act as gateway side of hmacWebPaymentGateway to make signed callbacks.
+type fakeWebPaymentGateway struct {
+	hmacWebPaymentGateway
+}
+
+func (fake *fakeWebPaymentGateway) callback(paymentID, reference [32]byte, amount int64, succeed bool) []byte {
+	var signature = hex.EncodeToString(fake.sign(webPaymentSignCallback, paymentID, reference, amount, succeed))
+	return []byte(`{"PaymentID":"` + base64.RawURLEncoding.EncodeToString(paymentID[:]) +
+		`","Reference":"` + base64.RawURLEncoding.EncodeToString(reference[:]) +
+		`","Amount":` + strconv.FormatInt(amount, 10) +
+		`,"Succeed":` + strconv.FormatBool(succeed) +
+		`,"Signature":"` + signature + `"}`)
+}
+
+func TestHMACWebPaymentGateway_VerifyCallback(t *testing.T) {
+	var key = base64.StdEncoding.EncodeToString([]byte("test-shared-key"))
+	var gw hmacWebPaymentGateway
+	var err = gw.Init([]byte(`{"GatewayURL":"https://pay.example","MerchantID":"m1","SecretKey":"` + key + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fake = fakeWebPaymentGateway{gw}
+	var otherGW = hmacWebPaymentGateway{key: []byte("other-key")}
+
+	var paymentID = [32]byte{1, 2, 3}
+	var reference = [32]byte{9}
+
+	cb, err := gw.VerifyCallback(fake.callback(paymentID, reference, 8099, true))
+	if err != nil {
+		t.Fatal("valid callback rejected:", err)
+	}
+	if cb.PaymentID != paymentID || cb.Reference != reference || cb.Amount != 8099 || !cb.Succeed {
+		t.Errorf("callback decoded wrong: %+v", cb)
+	}
+
+	var tampered = bytes.Replace(fake.callback(paymentID, reference, 8099, true), []byte("8099"), []byte("9999"), 1)
+	_, err = gw.VerifyCallback(tampered)
+	if err == nil {
+		t.Error("tampered callback accepted")
+	}
+
+	_, err = otherGW.VerifyCallback(fake.callback(paymentID, reference, 8099, true))
+	if err == nil {
+		t.Error("callback signed by other key accepted")
+	}
+}
+
+func TestWebPaymentCallbackFlow(t *testing.T) {
+	var key = base64.StdEncoding.EncodeToString([]byte("test-shared-key"))
+	var gw hmacWebPaymentGateway
+	var err = gw.Init([]byte(`{"GatewayURL":"https://pay.example","MerchantID":"m1","SecretKey":"` + key + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fake = fakeWebPaymentGateway{gw}
+
+	var savedGateways = webPaymentGateways
+	webPaymentGateways = map[byte]webPaymentGateway{'0': &gw}
+	defer func() { webPaymentGateways = savedGateways }()
+
+	var pending = datastore.FinancialWebPayment{
+		ID:        [32]byte{7},
+		Amount:    8099,
+		GatewayID: '0',
+		Status:    datastore.FinancialWebPaymentPending,
+	}
+
+	// Gateway know the payment just by the redirect URL, so make callbacks by what the URL carry.
+	redirectURL, err := gw.RequestPayment(&pending, "https://callback.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed, goErr = url.Parse(redirectURL)
+	if goErr != nil {
+		t.Fatal(goErr)
+	}
+	var paymentID [32]byte
+	var decodedID, _ = base64.RawURLEncoding.DecodeString(parsed.Query().Get("PaymentID"))
+	copy(paymentID[:], decodedID)
+	var amount, _ = strconv.ParseInt(parsed.Query().Get("Amount"), 10, 64)
+	if paymentID != pending.ID || amount != int64(pending.Amount) {
+		t.Fatalf("redirect URL carry wrong payment: %s", redirectURL)
+	}
+
+	var verified = pending
+	verified.Status = datastore.FinancialWebPaymentVerified
+	var otherGateway = pending
+	otherGateway.GatewayID = '1'
+
+	var tests = []struct {
+		name       string
+		payment    datastore.FinancialWebPayment
+		callback   []byte
+		wantStatus datastore.FinancialWebPaymentStatus
+		wantErr    bool
+	}{
+		{"paid", pending, fake.callback(paymentID, [32]byte{9}, amount, true), datastore.FinancialWebPaymentVerified, false},
+		{"canceled", pending, fake.callback(paymentID, [32]byte{9}, amount, false), datastore.FinancialWebPaymentFailed, false},
+		{"paid less", pending, fake.callback(paymentID, [32]byte{9}, amount-1, true), datastore.FinancialWebPaymentFailed, false},
+		{"other payment", pending, fake.callback([32]byte{8}, [32]byte{9}, amount, true), datastore.FinancialWebPaymentPending, true},
+		{"other gateway", otherGateway, fake.callback(paymentID, [32]byte{9}, amount, true), datastore.FinancialWebPaymentPending, true},
+		{"called again", verified, fake.callback(paymentID, [32]byte{9}, amount, false), datastore.FinancialWebPaymentVerified, false},
+		{"replayed request signature", pending, requestSignatureCallback(fake.callback(paymentID, [32]byte{}, amount, false), parsed.Query().Get("Signature")), datastore.FinancialWebPaymentPending, true},
+		{"tampered", pending, bytes.Replace(fake.callback(paymentID, [32]byte{9}, amount, false), []byte("false"), []byte("true"), 1), datastore.FinancialWebPaymentPending, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status = tt.payment.Status
+			var cb, gatewayID, err = verifyWebPaymentCallback(tt.callback)
+			if err == nil {
+				status, err = decideFinancialWebPayment(&tt.payment, gatewayID, &cb)
+			}
+			if (err != nil) != tt.wantErr || status != tt.wantStatus {
+				t.Errorf("callback decide = %v, %v, want %v, error %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+		})
+	}
+}
+
+// requestSignatureCallback replace signature of given callback with given redirect URL signature.
+func requestSignatureCallback(callback []byte, signature string) []byte {
+	var i = bytes.Index(callback, []byte(`"Signature":"`)) + len(`"Signature":"`)
+	return append(append(callback[:i:i], signature...), `"}`...)
+}